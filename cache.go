@@ -0,0 +1,318 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/michael-go/go-jsn/jsn"
+	"github.com/spf13/pflag"
+)
+
+// responseCache keeps Snyk responses on disk between runs.
+// Entries tied to a project snapshot (paths, code issue details) are only
+// reused while the project has not been retested, the entries of a project
+// without a last tested date expire after the project TTL.
+type responseCache struct {
+	dir             string
+	ttl             time.Duration
+	projectTTL      time.Duration
+	projectVersions sync.Map
+}
+
+// cacheEntry is the content of a cache file
+type cacheEntry struct {
+	Endpoint string    `json:"endpoint"`
+	Version  string    `json:"version,omitempty"`
+	StoredAt time.Time `json:"storedAt"`
+	Body     []byte    `json:"body"`
+}
+
+const cacheFileSuffix = ".cache.json"
+
+/*
+**
+function newResponseCache
+input dir string, directory of the cache, created if missing
+input ttl time.Duration, how long entries tied to a project snapshot are kept
+input projectTTL time.Duration, how long the entries of a project without a last tested date are kept
+return *responseCache
+return error, when the directory can't be created
+**
+*/
+func newResponseCache(dir string, ttl time.Duration, projectTTL time.Duration) (*responseCache, error) {
+
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	return &responseCache{
+		dir:        dir,
+		ttl:        ttl,
+		projectTTL: projectTTL,
+	}, nil
+}
+
+/*
+**
+function newCache
+input Of *optionalFlags, cacheDir, cacheTTL and projectCacheTTL are used
+input customDebug debug
+return *responseCache, nil when the cache can't be used, the run goes on without it
+**
+*/
+func (Of *optionalFlags) newCache(customDebug debug) *responseCache {
+
+	ttl, _ := parseDuration(Of.cacheTTL)
+	projectTTL, _ := parseDuration(Of.projectCacheTTL)
+
+	cache, err := newResponseCache(Of.cacheDir, ttl, projectTTL)
+	if err != nil {
+		customDebug.Warn("Could not use the cache directory, running without cache", "cacheDir", Of.cacheDir, "error", err)
+		return nil
+	}
+
+	customDebug.Debug("Using cache directory", "cacheDir", Of.cacheDir)
+	return cache
+}
+
+/*
+**
+function setProjectVersion
+input projectID string
+input projectInfo jsn.Json, project details from the v1 API
+Remember the last tested date of the project so the entries of an older snapshot are not used
+**
+*/
+func (c *responseCache) setProjectVersion(projectID string, projectInfo jsn.Json) {
+
+	if c == nil {
+		return
+	}
+
+	version := projectInfo.K("lastTestedDate").String().Value
+	if len(version) == 0 {
+		return
+	}
+
+	c.projectVersions.Store(projectID, version)
+}
+
+func (c *responseCache) projectVersion(projectID string) string {
+
+	if c == nil || len(projectID) == 0 {
+		return ""
+	}
+
+	version, found := c.projectVersions.Load(projectID)
+	if !found {
+		return ""
+	}
+
+	return version.(string)
+}
+
+func (c *responseCache) path(endpointURL string, version string) string {
+	hash := sha256.Sum256([]byte(endpointURL + "\n" + version))
+	return filepath.Join(c.dir, hex.EncodeToString(hash[:])+cacheFileSuffix)
+}
+
+func (c *responseCache) ttlFor(version string) time.Duration {
+	if len(version) > 0 {
+		return c.ttl
+	}
+	return c.projectTTL
+}
+
+/*
+**
+function get
+input endpointURL string, version string, project snapshot or empty
+input now time.Time
+return []byte, cached body
+return bool, false when there is no valid entry
+**
+*/
+func (c *responseCache) get(endpointURL string, version string, now time.Time) ([]byte, bool) {
+
+	if c == nil {
+		return nil, false
+	}
+
+	data, err := ioutil.ReadFile(c.path(endpointURL, version))
+	if err != nil {
+		return nil, false
+	}
+
+	entry := cacheEntry{}
+	err = json.Unmarshal(data, &entry)
+	if err != nil || entry.Endpoint != endpointURL || entry.Version != version {
+		return nil, false
+	}
+
+	if now.Sub(entry.StoredAt) > c.ttlFor(version) {
+		return nil, false
+	}
+
+	return entry.Body, true
+}
+
+/*
+**
+function put
+input endpointURL string, version string, project snapshot or empty
+input body []byte, response to keep
+input now time.Time
+return error
+The entry is written in a temporary file and renamed so a crash never leaves a half written entry
+**
+*/
+func (c *responseCache) put(endpointURL string, version string, body []byte, now time.Time) error {
+
+	if c == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(cacheEntry{Endpoint: endpointURL, Version: version, StoredAt: now, Body: body})
+	if err != nil {
+		return err
+	}
+
+	tmpFile, err := ioutil.TempFile(c.dir, "tmp-*")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmpFile.Write(data)
+	closeErr := tmpFile.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		return err
+	}
+
+	return os.Rename(tmpFile.Name(), c.path(endpointURL, version))
+}
+
+/*
+**
+function prune
+input now time.Time
+input all bool, remove every entry and not only the expired ones
+return int, number of entries removed
+return error
+Expired, unreadable and leftover temporary files are removed
+**
+*/
+func (c *responseCache) prune(now time.Time, all bool) (int, error) {
+
+	files, err := ioutil.ReadDir(c.dir)
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+
+		name := file.Name()
+		path := filepath.Join(c.dir, name)
+
+		if strings.HasPrefix(name, "tmp-") {
+			if os.Remove(path) == nil {
+				removed++
+			}
+			continue
+		}
+
+		if !strings.HasSuffix(name, cacheFileSuffix) {
+			continue
+		}
+
+		expired := all
+		if !expired {
+			entry := cacheEntry{}
+			data, err := ioutil.ReadFile(path)
+			if err != nil || json.Unmarshal(data, &entry) != nil {
+				expired = true
+			} else {
+				expired = now.Sub(entry.StoredAt) > c.ttlFor(entry.Version)
+			}
+		}
+
+		if expired {
+			if os.Remove(path) == nil {
+				removed++
+			}
+		}
+	}
+
+	return removed, nil
+}
+
+/*
+**
+function runCacheCommand
+input args []string, arguments after "cache"
+return int, exit code
+Handle the cache subcommands, only prune for now:
+
+	cache prune --cacheDir=<dir> [--cacheTTL=24h] [--projectCacheTTL=1h] [--all]
+
+**
+*/
+func runCacheCommand(args []string) int {
+
+	if len(args) == 0 || args[0] != "prune" {
+		logger.Error("Unknown cache command", "usage", "cache prune --cacheDir=<dir> [--cacheTTL=24h] [--projectCacheTTL=1h] [--all]")
+		return 1
+	}
+
+	fs := pflag.NewFlagSet("cache prune", pflag.ContinueOnError)
+	cacheDir := fs.String("cacheDir", "", "Cache directory to prune")
+	cacheTTL := fs.String("cacheTTL", "24h", "Entries tied to a project snapshot older than this are removed")
+	projectCacheTTL := fs.String("projectCacheTTL", "1h", "Entries of projects without a last tested date older than this are removed")
+	all := fs.Bool("all", false, "Remove all the entries")
+	if err := fs.Parse(args[1:]); err != nil {
+		logger.Error("Error parsing command line arguments", "error", err)
+		return 1
+	}
+
+	if len(*cacheDir) == 0 {
+		logger.Error("--cacheDir is required")
+		return 1
+	}
+
+	ttl, err := parseDuration(*cacheTTL)
+	if err != nil {
+		logger.Error("Not a valid cacheTTL", "cacheTTL", *cacheTTL, "error", err)
+		return 1
+	}
+
+	projectTTL, err := parseDuration(*projectCacheTTL)
+	if err != nil {
+		logger.Error("Not a valid projectCacheTTL", "projectCacheTTL", *projectCacheTTL, "error", err)
+		return 1
+	}
+
+	cache := &responseCache{dir: *cacheDir, ttl: ttl, projectTTL: projectTTL}
+	removed, err := cache.prune(time.Now(), *all)
+	if err != nil {
+		logger.Error("Could not prune the cache", "cacheDir", *cacheDir, "error", err)
+		return 1
+	}
+
+	logger.Info("Cache entries removed", "removed", removed, "cacheDir", *cacheDir)
+	return 0
+}
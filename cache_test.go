@@ -0,0 +1,129 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/michael-go/go-jsn/jsn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseCacheFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	dir, _ := ioutil.TempDir("", "snyk-cache")
+	defer os.RemoveAll(dir)
+
+	cache, err := newResponseCache(filepath.Join(dir, "cache"), 24*time.Hour, time.Hour)
+	assert.Nil(err)
+
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	endpoint := "https://api.snyk.io/v1/org/123/project/456/issue/789/paths"
+
+	_, found := cache.get(endpoint, "v1", now)
+	assert.False(found)
+
+	assert.Nil(cache.put(endpoint, "v1", []byte(`{"paths": []}`), now))
+
+	body, found := cache.get(endpoint, "v1", now.Add(time.Hour))
+	assert.True(found)
+	assert.Equal(`{"paths": []}`, string(body))
+
+	// a new snapshot of the project does not use the old entries
+	_, found = cache.get(endpoint, "v2", now)
+	assert.False(found)
+
+	// expired
+	_, found = cache.get(endpoint, "v1", now.Add(25*time.Hour))
+	assert.False(found)
+
+	// entries without snapshot use the project TTL
+	assert.Nil(cache.put("https://api.snyk.io/v1/org/123/project/456", "", []byte(`{}`), now))
+	_, found = cache.get("https://api.snyk.io/v1/org/123/project/456", "", now.Add(2*time.Hour))
+	assert.False(found)
+
+	removed, err := cache.prune(now.Add(2*time.Hour), false)
+	assert.Nil(err)
+	assert.Equal(1, removed)
+
+	removed, err = cache.prune(now.Add(2*time.Hour), true)
+	assert.Nil(err)
+	assert.Equal(1, removed)
+
+	// a nil cache never finds anything
+	var noCache *responseCache
+	_, found = noCache.get(endpoint, "", now)
+	assert.False(found)
+	assert.Nil(noCache.put(endpoint, "", []byte(`{}`), now))
+}
+
+func TestMakeCachedSnykAPIRequestFunc(t *testing.T) {
+
+	assert := assert.New(t)
+	cD := debug{}
+	cD.setDebug(false)
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte(`{"paths": [["a@1.0.0"]]}`))
+	}))
+	defer server.Close()
+
+	dir, _ := ioutil.TempDir("", "snyk-cache")
+	defer os.RemoveAll(dir)
+
+	cache, _ := newResponseCache(dir, 24*time.Hour, time.Hour)
+	defaultSnykClient.cache = cache
+	defer func() { defaultSnykClient.cache = nil }()
+
+	project, _ := jsn.NewJson([]byte(`{"id": "456", "lastTestedDate": "2026-01-01T10:00:00Z"}`))
+	cache.setProjectVersion("456", project)
+
+	url := server.URL + "/v1/org/123/project/456/issue/789/paths"
+
+	for i := 0; i < 3; i++ {
+		body, err := makeCachedSnykAPIRequest(url, "123", "456", cD)
+		assert.Nil(err)
+		assert.Equal(`{"paths": [["a@1.0.0"]]}`, string(body))
+	}
+	assert.Equal(1, calls)
+
+	// the project has been retested, the paths are fetched again
+	project, _ = jsn.NewJson([]byte(`{"id": "456", "lastTestedDate": "2026-01-02T10:00:00Z"}`))
+	cache.setProjectVersion("456", project)
+
+	makeCachedSnykAPIRequest(url, "123", "456", cD)
+	assert.Equal(2, calls)
+}
+
+func TestProjectDetailsNotCachedFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	lastTestedDate := "2026-01-01T10:00:00Z"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id": "456", "lastTestedDate": "` + lastTestedDate + `"}`))
+	}))
+	defer server.Close()
+
+	cache, _ := newResponseCache(t.TempDir(), 24*time.Hour, time.Hour)
+	defaultSnykClient.cache = cache
+	defer func() { defaultSnykClient.cache = nil }()
+
+	Mf := MandatoryFlags{orgID: "123", endpointAPI: server.URL, apiToken: "123"}
+
+	getProjectDetails(Mf, "456", debug{})
+	assert.Equal("2026-01-01T10:00:00Z", cache.projectVersion("456"))
+
+	// retested within the project TTL, the new snapshot keys the cache
+	lastTestedDate = "2026-01-01T10:30:00Z"
+	getProjectDetails(Mf, "456", debug{})
+	assert.Equal("2026-01-01T10:30:00Z", cache.projectVersion("456"))
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// CheckpointFilePrefix is the prefix of the checkpoint of an org, it is shared
+// by the runs so a run can be resumed by the next one
+const CheckpointFilePrefix = "checkpoint_"
+
+// kinds of checkpoint lines
+const (
+	checkpointStart         = "start"
+	checkpointProjectDone   = "project-done"
+	checkpointTicketPending = "ticket-pending"
+	checkpointTicketCreated = "ticket-created"
+	checkpointTicketFailed  = "ticket-failed"
+)
+
+// checkpointEntry is one line of the checkpoint
+type checkpointEntry struct {
+	Kind      string    `json:"kind"`
+	Time      time.Time `json:"time"`
+	RunID     string    `json:"runId"`
+	ProjectID string    `json:"project,omitempty"`
+	IssueID   string    `json:"issue,omitempty"`
+	JiraKey   string    `json:"jiraKey,omitempty"`
+}
+
+// checkpoint records the projects done and the tickets created by a run in a
+// JSON Lines file. A ticket is written as pending before it is posted, so a
+// ticket posted just before a crash is never posted again by the resumed run
+type checkpoint struct {
+	mu       sync.Mutex
+	filename string
+	runID    string
+	now      func() time.Time
+	// start of the run that wrote the checkpoint, before the interruption when resumed
+	startedAt time.Time
+	// read from the file when the run is resumed
+	projectsDone map[string]bool
+	created      map[string]map[string]string
+	pending      map[string]map[string]bool
+}
+
+// defaultCheckpoint is nil for dry runs, plans and applies, every method is a no-op then
+var defaultCheckpoint *checkpoint
+
+// checkpointPath is the checkpoint of the org
+func checkpointPath(orgID string) string {
+	return defaultRunOutput.resolve(CheckpointFilePrefix + orgID + ".jsonl")
+}
+
+// planCheckpointPath is the checkpoint of the applies of a plan, apart from the one of the org
+func planCheckpointPath(orgID string, planRunID string) string {
+	return defaultRunOutput.resolve(CheckpointFilePrefix + orgID + "_plan_" + planRunID + ".jsonl")
+}
+
+// pendingLookup lists the tickets of a project again, once, when one of its tickets is pending
+type pendingLookup struct {
+	tracker   tracker
+	projectID string
+	listed    bool
+	tickets   map[string]string
+	err       error
+}
+
+/*
+**
+function find
+input issueID string, its ticket is pending
+input customDebug debug
+return string, the key of the ticket, empty when the tracker doesn't have it
+return error, when the tickets could not be listed, the ticket stays pending
+The ticket found is recorded as created in the checkpoint
+**
+*/
+func (p *pendingLookup) find(issueID string, customDebug debug) (string, error) {
+
+	if !p.listed {
+		p.tickets, p.err = p.tracker.existingTickets(p.projectID, customDebug)
+		p.listed = true
+	}
+	if p.err != nil {
+		return "", p.err
+	}
+
+	jiraKey := p.tickets[issueID]
+	if jiraKey != "" {
+		defaultCheckpoint.ticketCreated(p.projectID, issueID, jiraKey)
+	}
+
+	return jiraKey, nil
+}
+
+/*
+**
+function openCheckpoint
+input filename string
+input resume bool, continue the run recorded in the file, otherwise it is replaced
+input startedAt time.Time, start of the run, kept when the run is resumed
+return *checkpoint
+return bool, true when a checkpoint was resumed
+return error, when the file can't be read or written
+**
+*/
+func openCheckpoint(filename string, resume bool, startedAt time.Time) (*checkpoint, bool, error) {
+
+	c := &checkpoint{
+		filename:     filename,
+		runID:        defaultRunOutput.runID,
+		now:          time.Now,
+		projectsDone: make(map[string]bool),
+		created:      make(map[string]map[string]string),
+		pending:      make(map[string]map[string]bool),
+	}
+
+	resumed := false
+	if resume {
+		entries, err := readCheckpoint(filename)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, false, err
+		}
+		for _, entry := range entries {
+			c.replay(entry)
+		}
+		resumed = len(entries) > 0
+	}
+
+	if !resumed {
+		if err := writeFileAtomic(filename, nil, 0644); err != nil {
+			return nil, false, err
+		}
+		c.startedAt = startedAt.UTC()
+		if err := c.append(checkpointEntry{Kind: checkpointStart, Time: c.startedAt}); err != nil {
+			return nil, false, err
+		}
+	}
+
+	return c, resumed, nil
+}
+
+/*
+**
+function readCheckpoint
+input filename string
+return []checkpointEntry
+return error
+A line cut by a crash is ignored, the lines before it are kept
+**
+*/
+func readCheckpoint(filename string) ([]checkpointEntry, error) {
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	entries := []checkpointEntry{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		entry := checkpointEntry{}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, scanner.Err()
+}
+
+// replay applies a line of the checkpoint
+func (c *checkpoint) replay(entry checkpointEntry) {
+
+	switch entry.Kind {
+	case checkpointStart:
+		c.startedAt = entry.Time
+	case checkpointProjectDone:
+		c.projectsDone[entry.ProjectID] = true
+	case checkpointTicketPending:
+		if c.pending[entry.ProjectID] == nil {
+			c.pending[entry.ProjectID] = make(map[string]bool)
+		}
+		c.pending[entry.ProjectID][entry.IssueID] = true
+	case checkpointTicketCreated:
+		if c.created[entry.ProjectID] == nil {
+			c.created[entry.ProjectID] = make(map[string]string)
+		}
+		c.created[entry.ProjectID][entry.IssueID] = entry.JiraKey
+		delete(c.pending[entry.ProjectID], entry.IssueID)
+	case checkpointTicketFailed:
+		delete(c.pending[entry.ProjectID], entry.IssueID)
+	}
+}
+
+/*
+**
+function append
+input entry checkpointEntry, the time is set when empty
+return error
+The line is synced to the disk before the function returns
+**
+*/
+func (c *checkpoint) append(entry checkpointEntry) error {
+
+	if entry.Time.IsZero() {
+		entry.Time = c.now().UTC()
+	}
+	entry.RunID = c.runID
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	f, err := os.OpenFile(c.filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(append(data, '\n'))
+	if err == nil {
+		err = f.Sync()
+	}
+	closeErr := f.Close()
+	if err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// write appends a line, a checkpoint that can't be written is logged and doesn't stop the run.
+// The line is applied to the checkpoint too, so the pending tickets of this run are known
+func (c *checkpoint) write(entry checkpointEntry) {
+
+	if c == nil {
+		return
+	}
+
+	if err := c.append(entry); err != nil {
+		logger.Error("Could not write the checkpoint", "file", c.filename, "kind", entry.Kind, "project", entry.ProjectID, "issue", entry.IssueID, "error", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.replay(entry)
+}
+
+func (c *checkpoint) projectDone(projectID string) {
+	c.write(checkpointEntry{Kind: checkpointProjectDone, ProjectID: projectID})
+}
+
+func (c *checkpoint) ticketPending(projectID string, issueID string) {
+	c.write(checkpointEntry{Kind: checkpointTicketPending, ProjectID: projectID, IssueID: issueID})
+}
+
+func (c *checkpoint) ticketCreated(projectID string, issueID string, jiraKey string) {
+	c.write(checkpointEntry{Kind: checkpointTicketCreated, ProjectID: projectID, IssueID: issueID, JiraKey: jiraKey})
+}
+
+func (c *checkpoint) ticketFailed(projectID string, issueID string) {
+	c.write(checkpointEntry{Kind: checkpointTicketFailed, ProjectID: projectID, IssueID: issueID})
+}
+
+/*
+**
+function remainingProjects
+input projectIDs []string
+return []string, the projects not done by the resumed run, in the same order
+**
+*/
+func (c *checkpoint) remainingProjects(projectIDs []string) []string {
+
+	if c == nil {
+		return projectIDs
+	}
+
+	remaining := []string{}
+	for _, projectID := range projectIDs {
+		if !c.projectsDone[projectID] {
+			remaining = append(remaining, projectID)
+		}
+	}
+
+	return remaining
+}
+
+/*
+**
+function mergeTickets
+input projectID string
+input tickets map[string]string, issue ID to Jira key returned by getJiraTickets
+input customDebug debug
+return map[string]string, the tickets with the ones created by the resumed run added
+Snyk may not list the tickets created just before the interruption yet
+**
+*/
+func (c *checkpoint) mergeTickets(projectID string, tickets map[string]string, customDebug debug) map[string]string {
+
+	if c == nil {
+		return tickets
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.created[projectID]) == 0 {
+		return tickets
+	}
+
+	merged := make(map[string]string, len(tickets))
+	for issueID, jiraKey := range tickets {
+		merged[issueID] = jiraKey
+	}
+	for issueID, jiraKey := range c.created[projectID] {
+		if _, found := merged[issueID]; !found {
+			customDebug.Debug("Ticket created before the interruption", "issue", issueID, "jiraKey", jiraKey)
+			merged[issueID] = jiraKey
+		}
+	}
+
+	return merged
+}
+
+// isPending is true when the ticket was posted without knowing the result, before an interruption
+// or by a request that failed after it may have reached the tracker
+func (c *checkpoint) isPending(projectID string, issueID string) bool {
+
+	if c == nil {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.pending[projectID][issueID]
+}
+
+// hasPendingIn is true when a ticket of the project is pending, the project is processed again by --resume
+func (c *checkpoint) hasPendingIn(projectID string) bool {
+
+	if c == nil {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.pending[projectID]) > 0
+}
+
+// hasPending is true while a ticket posted without knowing the result was not found or created since
+func (c *checkpoint) hasPending() bool {
+
+	if c == nil {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, issues := range c.pending {
+		if len(issues) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+**
+function remove
+Delete the checkpoint once every project is done and no ticket is pending, the next run starts from scratch
+**
+*/
+func (c *checkpoint) remove() error {
+
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return os.Remove(c.filename)
+}
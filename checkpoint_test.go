@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/michael-go/go-jsn/jsn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckpointFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	filename := filepath.Join(t.TempDir(), "checkpoint_123.jsonl")
+	startedAt := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	c, resumed, err := openCheckpoint(filename, true, startedAt)
+	assert.Nil(err)
+	assert.False(resumed)
+
+	c.projectDone("project-a")
+	c.ticketPending("project-b", "SNYK-JS-1")
+	c.ticketCreated("project-b", "SNYK-JS-1", "FPI-1")
+	c.ticketPending("project-b", "SNYK-JS-2")
+	c.ticketFailed("project-b", "SNYK-JS-2")
+	c.ticketPending("project-b", "SNYK-JS-3")
+
+	// the run is killed while writing a line
+	f, _ := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY, 0644)
+	f.Write([]byte(`{"kind":"ticket-crea`))
+	f.Close()
+
+	resumedCheckpoint, resumed, err := openCheckpoint(filename, true, startedAt.Add(time.Hour))
+	assert.Nil(err)
+	assert.True(resumed)
+	assert.Equal(startedAt, resumedCheckpoint.startedAt)
+	assert.Equal([]string{"project-b", "project-c"}, resumedCheckpoint.remainingProjects([]string{"project-a", "project-b", "project-c"}))
+
+	// the tickets created before the interruption are not created again
+	tickets := resumedCheckpoint.mergeTickets("project-b", map[string]string{"SNYK-JS-4": "FPI-4"}, debug{})
+	assert.Equal(map[string]string{"SNYK-JS-1": "FPI-1", "SNYK-JS-4": "FPI-4"}, tickets)
+	assert.False(resumedCheckpoint.isPending("project-b", "SNYK-JS-1"))
+	assert.False(resumedCheckpoint.isPending("project-b", "SNYK-JS-2"))
+	assert.True(resumedCheckpoint.isPending("project-b", "SNYK-JS-3"))
+
+	assert.Nil(resumedCheckpoint.remove())
+	assert.NoFileExists(filename)
+
+	// without --resume the checkpoint is replaced
+	c, _, _ = openCheckpoint(filename, false, startedAt)
+	c.projectDone("project-a")
+	c, resumed, _ = openCheckpoint(filename, false, startedAt)
+	assert.False(resumed)
+	assert.Equal(0, len(c.projectsDone))
+	entries, _ := readCheckpoint(filename)
+	assert.Equal(1, len(entries))
+	assert.Equal(checkpointStart, entries[0].Kind)
+
+	// without checkpoint nothing is recorded
+	var noCheckpoint *checkpoint
+	noCheckpoint.ticketPending("project-b", "SNYK-JS-1")
+	assert.False(noCheckpoint.isPending("project-b", "SNYK-JS-1"))
+	assert.Equal([]string{"project-a"}, noCheckpoint.remainingProjects([]string{"project-a"}))
+	assert.Nil(noCheckpoint.remove())
+}
+
+func TestCheckpointResumeProjectFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	server := HTTPResponseEndToEnd()
+	defer server.Close()
+
+	cD := debug{}
+	CreateLogFile(cD, ErrorsFilePrefix)
+	defer removeLogFile()
+
+	// the interrupted run created a ticket and was posting another one
+	filename := filepath.Join(t.TempDir(), "checkpoint_123.jsonl")
+	c, _, _ := openCheckpoint(filename, false, time.Now())
+	c.ticketPending("123", "SNYK-JS-ACORN-559469")
+	c.ticketCreated("123", "SNYK-JS-ACORN-559469", "FPI-9")
+	c.ticketPending("123", "SNYK-JS-DOTPROP-543489")
+
+	resumedCheckpoint, resumed, err := openCheckpoint(filename, true, time.Now())
+	assert.Nil(err)
+	assert.True(resumed)
+	defaultCheckpoint = resumedCheckpoint
+	defer func() { defaultCheckpoint = nil }()
+
+	options := flags{}
+	options.mandatoryFlags = MandatoryFlags{orgID: "123", endpointAPI: server.URL, apiToken: "123", jiraProjectID: "123"}
+	options.optionalFlags = optionalFlags{severity: "low", issueType: "all", jiraTicketType: "Bug", concurrency: 1, issueConcurrency: 1}
+
+	defaultRunStatus = &runStatus{}
+	result := processProject(options, "123", nil, cD)
+
+	assert.False(result.failed)
+	// the pending ticket is not in the tracker, it is created
+	assert.Equal(2, defaultRunStatus.ticketsCreated)
+	assert.True(strings.Contains(result.summary, "Number of tickets created: 2"))
+	assert.False(resumedCheckpoint.isPending("123", "SNYK-JS-DOTPROP-543489"))
+	assert.False(resumedCheckpoint.hasPending())
+
+	// the ticket created by the resumed run is checkpointed
+	entries, _ := readCheckpoint(filename)
+	last := entries[len(entries)-1]
+	assert.Equal(checkpointTicketCreated, last.Kind)
+	assert.Equal("SNYK-JS-PACRESOLVER-1564857", last.IssueID)
+	assert.Equal(resumedCheckpoint.runID, last.RunID)
+}
+
+func TestPendingTicketFoundFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	cD := debug{}
+	CreateLogFile(cD, ErrorsFilePrefix)
+	defer removeLogFile()
+
+	posts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			posts++
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"number": 8}`))
+			return
+		}
+		// the issue posted before the interruption was created
+		w.Write([]byte(`[{"number": 7, "body": "<!-- snyk-issue: 12345678-1234-1234-1234-123456789012/SNYK-JS-MINIMIST-559764 -->"}]`))
+	}))
+	defer server.Close()
+
+	c, _, _ := openCheckpoint(filepath.Join(t.TempDir(), "checkpoint_123.jsonl"), false, time.Now())
+	c.ticketPending("12345678-1234-1234-1234-123456789012", "SNYK-JS-MINIMIST-559764")
+	defaultCheckpoint = c
+	defer func() { defaultCheckpoint = nil }()
+	// the checkpoint is kept and the project processed again until the ticket is found or created
+	assert.True(c.hasPending())
+	assert.True(c.hasPendingIn("12345678-1234-1234-1234-123456789012"))
+
+	projectInfo, _ := jsn.NewJson(readFixture("./fixtures/project.json"))
+	vulnsForJira := make(map[string]interface{})
+	if err := json.Unmarshal(readFixture("./fixtures/vulnForJiraAggregatedWithPath.json"), &vulnsForJira); err != nil {
+		panic(err)
+	}
+	pending := map[string]interface{}{"SNYK-JS-MINIMIST-559764": vulnsForJira["SNYK-JS-MINIMIST-559764"]}
+
+	options := flags{}
+	options.mandatoryFlags = MandatoryFlags{orgID: "123", endpointAPI: server.URL, apiToken: "123", tracker: TrackerGitHub, githubAPI: server.URL, githubRepo: "acme/typescript", githubToken: "gh-token"}
+	options.optionalFlags = optionalFlags{jiraTicketType: "Bug"}
+
+	defaultRunStatus = &runStatus{}
+	created, _, _, _ := openJiraTickets(options, projectInfo, pending, cD)
+
+	assert.Equal(0, created)
+	assert.Equal(0, posts)
+	assert.False(c.hasPending())
+	assert.Equal(map[string]string{"SNYK-JS-MINIMIST-559764": "acme/typescript#7"}, c.mergeTickets("12345678-1234-1234-1234-123456789012", map[string]string{}, cD))
+}
@@ -0,0 +1,525 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/spf13/pflag"
+)
+
+// stopRequested is set by serve and daemon on SIGINT or SIGTERM, the run
+// finishes the projects in flight and leaves the others to --resume
+var stopRequested int32
+
+func requestStop() {
+	atomic.StoreInt32(&stopRequested, 1)
+}
+
+func isStopRequested() bool {
+	return atomic.LoadInt32(&stopRequested) == 1
+}
+
+// states of the daemon shown by /status
+const (
+	daemonStarting = "starting"
+	daemonIdle     = "idle"
+	daemonRunning  = "running"
+	daemonStopping = "stopping"
+)
+
+// configPollInterval is how often the daemon checks if jira.yaml changed
+const configPollInterval = 10 * time.Second
+
+// DaemonRun is a run of the daemon, the last one is shown by /status
+type DaemonRun struct {
+	RunID      string    `json:"runId,omitempty"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+	ExitCode   int       `json:"exitCode"`
+	Outcome    string    `json:"outcome"`
+}
+
+// DaemonStatus is returned by /status
+type DaemonStatus struct {
+	State          string     `json:"state"`
+	Schedule       string     `json:"schedule"`
+	NextRun        *time.Time `json:"nextRun,omitempty"`
+	Runs           int        `json:"runs"`
+	LastRun        *DaemonRun `json:"lastRun,omitempty"`
+	ConfigFile     string     `json:"configFile,omitempty"`
+	ConfigLoadedAt *time.Time `json:"configLoadedAt,omitempty"`
+	ConfigError    string     `json:"configError,omitempty"`
+}
+
+// daemon runs the syncs on a schedule, one at a time
+type daemon struct {
+	schedule cron.Schedule
+	jitter   time.Duration
+	syncArgs []string
+	// jira.yaml is watched, the syncs read a copy of its last valid version from configDir
+	configSource string
+	configDir    string
+	configHash   string
+	reloadMu     sync.Mutex
+	// replaced by the tests
+	sync  func(args []string) int
+	check func(args []string) (flags, error)
+	now   func() time.Time
+
+	mu     sync.Mutex
+	status DaemonStatus
+}
+
+/*
+**
+function newDaemon
+input schedule cron.Schedule
+input description string, the schedule shown by /status
+input jitter time.Duration, every run is delayed by a random duration up to it
+input syncArgs []string, options of the syncs
+return *daemon
+**
+*/
+func newDaemon(schedule cron.Schedule, description string, jitter time.Duration, syncArgs []string) *daemon {
+
+	return &daemon{
+		schedule: schedule,
+		jitter:   jitter,
+		syncArgs: syncArgs,
+		sync:     runSync,
+		check:    checkSyncOptions,
+		now:      time.Now,
+		status:   DaemonStatus{State: daemonStarting, Schedule: description},
+	}
+}
+
+/*
+**
+function parseSchedule
+input schedule string, a cron expression like "0 * * * *"
+input interval string, a duration like 1h or 1d
+return cron.Schedule
+return string, the description of the schedule
+return error, when both or none are set or the value is not valid
+**
+*/
+func parseSchedule(schedule string, interval string) (cron.Schedule, string, error) {
+
+	if (schedule == "") == (interval == "") {
+		return nil, "", errors.New("set either --schedule or --interval")
+	}
+
+	if schedule != "" {
+		parsed, err := cron.ParseStandard(schedule)
+		if err != nil {
+			return nil, "", err
+		}
+		return parsed, schedule, nil
+	}
+
+	every, err := parseDuration(interval)
+	if err != nil {
+		return nil, "", err
+	}
+	if every < time.Minute {
+		return nil, "", errors.New("the interval must be at least 1m")
+	}
+
+	return cron.Every(every), "every " + interval, nil
+}
+
+/*
+**
+function checkSyncOptions
+input args []string, options of a sync
+return flags, the options read
+return error, when the options or the config file are not valid
+The checks of setOption exit on an error, the exit is turned into an error
+**
+*/
+func checkSyncOptions(args []string) (options flags, err error) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			fatal, ok := r.(fatalExit)
+			if !ok {
+				panic(r)
+			}
+			err = fmt.Errorf("the options or the config file are not valid (%s)", exitCodeOutcome(fatal.code))
+		}
+	}()
+
+	// serve and daemon set it before their goroutines start
+	if !recoverFatal {
+		recoverFatal = true
+		defer func() { recoverFatal = false }()
+	}
+
+	options.setOption(args)
+	options.mandatoryFlags.checkMandatoryAreSet()
+
+	return options, nil
+}
+
+// configFileOf is the jira.yaml read by the syncs, the working directory is used without --configFile
+func configFileOf(args []string) string {
+
+	dir := "."
+	for index, arg := range args {
+		if strings.HasPrefix(arg, "--configFile=") {
+			dir = strings.TrimPrefix(arg, "--configFile=")
+		} else if arg == "--configFile" && index+1 < len(args) {
+			dir = args[index+1]
+		}
+	}
+
+	return filepath.Join(dir, "jira.yaml")
+}
+
+// args are the options of the next sync, reading the last valid config
+func (d *daemon) args() []string {
+
+	args := append([]string{}, d.syncArgs...)
+	if d.configSource != "" {
+		args = append(args, "--configFile="+d.configDir)
+	}
+
+	return args
+}
+
+/*
+**
+function reloadConfig
+return error, when the config can't be read or is not valid, the previous one is kept
+The config is checked before it is copied for the syncs, a config being edited
+never reaches a run half written or with a typo.
+**
+*/
+func (d *daemon) reloadConfig() error {
+
+	if d.configSource == "" {
+		return nil
+	}
+
+	d.reloadMu.Lock()
+	defer d.reloadMu.Unlock()
+
+	data, err := ioutil.ReadFile(d.configSource)
+	if err != nil {
+		return d.configFailed(fmt.Errorf("could not read the config file: %w", err))
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	if hash == d.configHash {
+		return nil
+	}
+	d.configHash = hash
+
+	candidate := filepath.Join(d.configDir, "candidate")
+	if err := os.MkdirAll(candidate, 0700); err != nil {
+		return d.configFailed(err)
+	}
+	if err := writeFileAtomic(filepath.Join(candidate, "jira.yaml"), data, 0600); err != nil {
+		return d.configFailed(err)
+	}
+	if _, err := d.check(append(append([]string{}, d.syncArgs...), "--configFile="+candidate)); err != nil {
+		return d.configFailed(err)
+	}
+
+	if err := writeFileAtomic(filepath.Join(d.configDir, "jira.yaml"), data, 0600); err != nil {
+		return d.configFailed(err)
+	}
+
+	d.mu.Lock()
+	loadedAt := d.now()
+	first := d.status.ConfigLoadedAt == nil
+	d.status.ConfigLoadedAt = &loadedAt
+	d.status.ConfigError = ""
+	d.mu.Unlock()
+
+	if !first {
+		logger.Info("Config reloaded, it is used from the next run", "configFile", d.configSource)
+	}
+
+	return nil
+}
+
+// configFailed keeps the error for /status, it is logged once
+func (d *daemon) configFailed(err error) error {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.status.ConfigError != err.Error() {
+		logger.Error("Config not loaded, the previous one is kept", "configFile", d.configSource, "error", err)
+	}
+	d.status.ConfigError = err.Error()
+
+	return err
+}
+
+func (d *daemon) setState(state string, nextRun *time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// a daemon stopping doesn't go back to idle
+	if d.status.State != daemonStopping {
+		d.status.State = state
+	}
+	d.status.NextRun = nextRun
+}
+
+// snapshot is a copy of the status, safe to read while a run goes on
+func (d *daemon) snapshot() DaemonStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.status
+}
+
+/*
+**
+function runOnce
+Run a sync with the last valid config and keep its outcome for /status
+**
+*/
+func (d *daemon) runOnce() {
+
+	d.reloadConfig()
+	d.setState(daemonRunning, nil)
+
+	previousOutput := defaultRunOutput
+	run := DaemonRun{StartedAt: d.now()}
+	logger.Info("Scheduled run started")
+
+	run.ExitCode = d.sync(d.args())
+	run.FinishedAt = d.now()
+	run.Outcome = exitCodeOutcome(run.ExitCode)
+	// a run stopped before its output was set has no run ID
+	if defaultRunOutput != previousOutput {
+		run.RunID = defaultRunOutput.runID
+	}
+	logger.Info("Scheduled run done", "runId", run.RunID, "exitCode", run.ExitCode, "outcome", run.Outcome, "duration", run.FinishedAt.Sub(run.StartedAt).String())
+
+	d.mu.Lock()
+	d.status.Runs++
+	d.status.LastRun = &run
+	d.mu.Unlock()
+	d.setState(daemonIdle, nil)
+}
+
+/*
+**
+function loop
+input stop <-chan struct{}, closed on SIGINT or SIGTERM
+input runOnStart bool, run a sync before waiting for the schedule
+Wait for the next run, run it and start again. The next run is computed once the
+previous one is done, so runs never overlap: the ones missed are skipped.
+**
+*/
+func (d *daemon) loop(stop <-chan struct{}, runOnStart bool) {
+
+	if runOnStart {
+		d.runOnce()
+	}
+
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		now := d.now()
+		scheduled := d.schedule.Next(now)
+		nextRun := scheduled
+		if d.jitter > 0 {
+			nextRun = nextRun.Add(time.Duration(rand.Int63n(int64(d.jitter))))
+		}
+		d.setState(daemonIdle, &nextRun)
+		logger.Info("Next run scheduled", "nextRun", nextRun)
+
+		timer := time.NewTimer(nextRun.Sub(now))
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		d.runOnce()
+
+		if missed := d.schedule.Next(scheduled); missed.Before(d.now()) {
+			logger.Warn("The run lasted longer than the schedule, the runs missed are skipped", "scheduled", scheduled, "missed", missed)
+		}
+	}
+}
+
+// watchConfig reloads jira.yaml when it changes until the daemon stops
+func (d *daemon) watchConfig(stop <-chan struct{}) {
+
+	ticker := time.NewTicker(configPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			d.reloadConfig()
+		}
+	}
+}
+
+/*
+**
+function handler
+return http.Handler
+/healthz answers while the process runs, /readyz while the daemon is scheduling
+runs and /status returns the DaemonStatus
+**
+*/
+func (d *daemon) handler() http.Handler {
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSONResponse(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		state := d.snapshot().State
+		if state == daemonIdle || state == daemonRunning {
+			writeJSONResponse(w, http.StatusOK, map[string]string{"status": state})
+			return
+		}
+		writeJSONResponse(w, http.StatusServiceUnavailable, map[string]string{"status": state})
+	})
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		writeJSONResponse(w, http.StatusOK, d.snapshot())
+	})
+
+	return mux
+}
+
+/*
+**
+function runDaemonCommand
+input args []string, the daemon options then, after --, the options of the syncs
+return int, the exit code
+Run the syncs on a schedule until SIGINT or SIGTERM. The run going on finishes
+the projects in flight before the daemon stops, the others are left to --resume.
+**
+*/
+func runDaemonCommand(args []string) int {
+
+	fs := pflag.NewFlagSet("daemon", pflag.ContinueOnError)
+	schedule := fs.String("schedule", "", "Cron expression of the runs (e.g. \"0 * * * *\")")
+	interval := fs.String("interval", "", "Time between the runs (e.g. 1h, 1d), instead of --schedule")
+	jitter := fs.String("jitter", "", "Every run is delayed by a random duration up to this one (e.g. 5m)")
+	listen := fs.String("listen", ":8080", "Address of /healthz, /readyz and /status")
+	runOnStart := fs.Bool("runOnStart", false, "Run a sync when the daemon starts")
+	if err := fs.Parse(args); err != nil {
+		logger.Error("Error parsing command line arguments", "usage", "daemon --schedule=<cron>|--interval=<duration> [--jitter=5m] [--listen=:8080] [--runOnStart] -- [sync options]", "error", err)
+		return exitConfigError
+	}
+
+	parsedSchedule, description, err := parseSchedule(*schedule, *interval)
+	if err != nil {
+		logger.Error("Not a valid schedule", "schedule", *schedule, "interval", *interval, "error", err)
+		return exitConfigError
+	}
+
+	jitterDuration := time.Duration(0)
+	if *jitter != "" {
+		if jitterDuration, err = parseDuration(*jitter); err != nil {
+			logger.Error("Not a valid jitter", "jitter", *jitter, "error", err)
+			return exitConfigError
+		}
+	}
+
+	// a fatal error ends the run, not the daemon
+	recoverFatal = true
+	defer func() { recoverFatal = false }()
+
+	d := newDaemon(parsedSchedule, description, jitterDuration, fs.Args())
+
+	// the syncs read a copy of the config, a config being edited is checked first
+	configDir, err := ioutil.TempDir("", "snyk-jira-daemon-")
+	if err != nil {
+		logger.Error("Could not create the config directory", "error", err)
+		return exitError
+	}
+	defer os.RemoveAll(configDir)
+	d.configDir = configDir
+	configSource := configFileOf(d.syncArgs)
+	if _, err := os.Stat(configSource); err == nil {
+		d.configSource = configSource
+		d.status.ConfigFile = configSource
+	}
+	if err := d.reloadConfig(); err != nil {
+		return exitConfigError
+	}
+	options, err := d.check(d.args())
+	if err != nil {
+		logger.Error("The options of the syncs are not valid", "error", err)
+		return exitConfigError
+	}
+	configureLogging(options.optionalFlags.logFormat, options.optionalFlags.logLevel, options.optionalFlags.debug)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	httpServer := &http.Server{Addr: *listen, Handler: d.handler(), ReadHeaderTimeout: 10 * time.Second}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- httpServer.ListenAndServe()
+	}()
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	logger.Info("Daemon started", "schedule", description, "jitter", jitterDuration.String(), "listen", *listen, "configFile", d.configSource)
+	go func() {
+		d.loop(stop, *runOnStart)
+		close(done)
+	}()
+	go d.watchConfig(stop)
+
+	exitCode := exitSuccess
+	select {
+	case err := <-serveErr:
+		logger.Error("The status server stopped", "listen", *listen, "error", err)
+		exitCode = exitError
+	case sig := <-signals:
+		logger.Info("Stopping the daemon, the projects in flight are finished first", "signal", sig.String())
+	}
+
+	requestStop()
+	d.setState(daemonStopping, nil)
+	close(stop)
+	<-done
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := httpServer.Shutdown(ctx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Warn("The status server did not stop cleanly", "error", err)
+	}
+	logger.Info("Daemon stopped")
+
+	return exitCode
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// everySchedule runs as soon as possible, the tests don't wait for a minute
+type everySchedule struct {
+	delay time.Duration
+}
+
+func (s everySchedule) Next(t time.Time) time.Time {
+	return t.Add(s.delay)
+}
+
+func TestParseScheduleFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	from := time.Date(2024, 6, 1, 12, 10, 0, 0, time.UTC)
+
+	schedule, description, err := parseSchedule("0 * * * *", "")
+	assert.Nil(err)
+	assert.Equal("0 * * * *", description)
+	assert.Equal(time.Date(2024, 6, 1, 13, 0, 0, 0, time.UTC), schedule.Next(from))
+
+	schedule, description, err = parseSchedule("", "1d")
+	assert.Nil(err)
+	assert.Equal("every 1d", description)
+	assert.Equal(from.Add(24*time.Hour), schedule.Next(from))
+
+	_, _, err = parseSchedule("", "")
+	assert.NotNil(err)
+	_, _, err = parseSchedule("0 * * * *", "1h")
+	assert.NotNil(err)
+	_, _, err = parseSchedule("every hour", "")
+	assert.NotNil(err)
+	_, _, err = parseSchedule("", "10s")
+	assert.NotNil(err)
+}
+
+func TestDaemonStatusFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	d := newDaemon(everySchedule{time.Hour}, "every 1h", 0, []string{"--orgID=123"})
+	d.sync = func(args []string) int {
+		assert.Equal([]string{"--orgID=123"}, args)
+		assert.Equal(daemonRunning, d.snapshot().State)
+		return exitNothingToDo
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		response := httptest.NewRecorder()
+		d.handler().ServeHTTP(response, httptest.NewRequest(http.MethodGet, path, nil))
+		return response
+	}
+
+	assert.Equal(http.StatusOK, get("/healthz").Code)
+	assert.Equal(http.StatusServiceUnavailable, get("/readyz").Code)
+
+	d.runOnce()
+	assert.Equal(http.StatusOK, get("/readyz").Code)
+
+	status := DaemonStatus{}
+	assert.Nil(json.Unmarshal(get("/status").Body.Bytes(), &status))
+	assert.Equal(daemonIdle, status.State)
+	assert.Equal("every 1h", status.Schedule)
+	assert.Equal(1, status.Runs)
+	assert.Equal(exitNothingToDo, status.LastRun.ExitCode)
+	assert.Equal("nothing-to-do", status.LastRun.Outcome)
+
+	d.setState(daemonStopping, nil)
+	d.setState(daemonIdle, nil)
+	assert.Equal(http.StatusServiceUnavailable, get("/readyz").Code)
+}
+
+func TestDaemonLoopFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	d := newDaemon(everySchedule{5 * time.Millisecond}, "test", time.Millisecond, nil)
+
+	stop := make(chan struct{})
+	var running, overlaps, runs int32
+	d.sync = func(args []string) int {
+		if !atomic.CompareAndSwapInt32(&running, 0, 1) {
+			atomic.AddInt32(&overlaps, 1)
+		}
+		// the run lasts longer than the schedule
+		time.Sleep(20 * time.Millisecond)
+		if atomic.AddInt32(&runs, 1) == 3 {
+			close(stop)
+		}
+		atomic.StoreInt32(&running, 0)
+		return exitSuccess
+	}
+
+	done := make(chan struct{})
+	go func() {
+		d.loop(stop, true)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the daemon did not stop")
+	}
+
+	assert.Equal(int32(3), atomic.LoadInt32(&runs))
+	assert.Equal(int32(0), atomic.LoadInt32(&overlaps))
+	assert.Equal(3, d.snapshot().Runs)
+}
+
+func TestDaemonConfigReloadFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	source := t.TempDir()
+	configFile := filepath.Join(source, "jira.yaml")
+	ioutil.WriteFile(configFile, []byte("snyk:\n  orgID: \"123\"\njira:\n  jiraProjectKey: FPI\n"), 0644)
+
+	d := newDaemon(everySchedule{time.Hour}, "test", 0, []string{"--token=123", "--configFile=" + source})
+	d.configDir = t.TempDir()
+	d.configSource = configFileOf(d.syncArgs)
+	assert.Equal(configFile, d.configSource)
+
+	assert.Nil(d.reloadConfig())
+	assert.Equal("--configFile="+d.configDir, d.args()[len(d.args())-1])
+	copied, _ := ioutil.ReadFile(filepath.Join(d.configDir, "jira.yaml"))
+	assert.Contains(string(copied), "orgID: \"123\"")
+
+	// a config that is not valid is not used, the previous one is kept
+	ioutil.WriteFile(configFile, []byte("snyk:\n  orgID: \"456\"\n  unknownKey: true\njira:\n  jiraProjectKey: FPI\n"), 0644)
+	assert.NotNil(d.reloadConfig())
+	copied, _ = ioutil.ReadFile(filepath.Join(d.configDir, "jira.yaml"))
+	assert.Contains(string(copied), "orgID: \"123\"")
+	assert.NotEqual("", d.snapshot().ConfigError)
+
+	// the config is fixed
+	ioutil.WriteFile(configFile, []byte("snyk:\n  orgID: \"456\"\njira:\n  jiraProjectKey: FPI\n"), 0644)
+	assert.Nil(d.reloadConfig())
+	copied, _ = ioutil.ReadFile(filepath.Join(d.configDir, "jira.yaml"))
+	assert.Contains(string(copied), "orgID: \"456\"")
+	assert.Equal("", d.snapshot().ConfigError)
+
+	options, err := checkSyncOptions(d.args())
+	assert.Nil(err)
+	assert.Equal("456", options.mandatoryFlags.orgID)
+	_, err = checkSyncOptions([]string{"--orgID=123", "--token=123", "--jiraProjectID=123", "--priorityScoreThreshold=2000", "--configFile=" + t.TempDir()})
+	assert.NotNil(err)
+	// a typo in the severity is refused before a project worker sees it
+	_, err = checkSyncOptions([]string{"--orgID=123", "--token=123", "--jiraProjectID=123", "--severity=hihg", "--configFile=" + t.TempDir()})
+	assert.NotNil(err)
+	assert.False(recoverFatal)
+}
+
+func TestStoppedRunFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	os.Setenv("EXECUTION_ENVIRONMENT", "test")
+
+	server := HTTPResponseEndToEnd()
+	defer server.Close()
+
+	oldOutput := defaultRunOutput
+	defer func() { defaultRunOutput = oldOutput }()
+
+	// the stop is requested before the first project starts
+	requestStop()
+	defer atomic.StoreInt32(&stopRequested, 0)
+
+	dir := t.TempDir()
+	reportFile := filepath.Join(dir, "report.json")
+	exitCode := runSync([]string{"--orgID=123", "--token=123", "--jiraProjectID=123", "--api=" + server.URL, "--output-dir=" + dir, "--reportFile=" + reportFile})
+
+	assert.Equal(exitPartialFailure, exitCode)
+	assert.Equal(0, defaultRunStatus.ticketsCreated)
+	report, _ := ioutil.ReadFile(reportFile)
+	assert.True(strings.Contains(string(report), "not processed, the run was stopped"))
+
+	// the checkpoint is kept for --resume
+	_, err := os.Stat(filepath.Join(dir, CheckpointFilePrefix+"123.jsonl"))
+	assert.Nil(err)
+}
+
+func TestLastSuccessfulRunOfProjectRunFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	os.Setenv("EXECUTION_ENVIRONMENT", "test")
+
+	server := HTTPResponseEndToEnd()
+	defer server.Close()
+
+	oldOutput := defaultRunOutput
+	defer func() { defaultRunOutput = oldOutput }()
+
+	// a single project doesn't move the threshold of the org
+	dir := t.TempDir()
+	runSync([]string{"--orgID=123", "--token=123", "--jiraProjectID=123", "--api=" + server.URL, "--output-dir=" + dir, "--projectID=123"})
+	_, err := os.Stat(filepath.Join(dir, LastRunFilePrefix+"123.json"))
+	assert.True(os.IsNotExist(err))
+
+	runSync([]string{"--orgID=123", "--token=123", "--jiraProjectID=123", "--api=" + server.URL, "--output-dir=" + dir})
+	_, err = os.Stat(filepath.Join(dir, LastRunFilePrefix+"123.json"))
+	assert.Nil(err)
+}
+
+func TestProjectRunKeepsCheckpointFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	os.Setenv("EXECUTION_ENVIRONMENT", "test")
+
+	server := HTTPResponseEndToEnd()
+	defer server.Close()
+
+	oldOutput := defaultRunOutput
+	defer func() { defaultRunOutput = oldOutput }()
+
+	// the checkpoint of an interrupted run of the org
+	dir := t.TempDir()
+	checkpointFile := filepath.Join(dir, CheckpointFilePrefix+"123.jsonl")
+	interrupted := `{"kind":"start","time":"2024-06-01T12:00:00Z","runId":"20240601T120000Z-1a2b3c"}` + "\n"
+	assert.Nil(ioutil.WriteFile(checkpointFile, []byte(interrupted), 0644))
+
+	runSync([]string{"--orgID=123", "--token=123", "--jiraProjectID=123", "--api=" + server.URL, "--output-dir=" + dir, "--projectID=123"})
+
+	data, err := ioutil.ReadFile(checkpointFile)
+	assert.Nil(err)
+	assert.Equal(interrupted, string(data))
+}
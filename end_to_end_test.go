@@ -34,7 +34,7 @@ func TestEndToEndFunc(t *testing.T) {
 	r, w, _ := os.Pipe()
 	os.Stdout = w
 
-	main()
+	exitCode := run()
 
 	// Test finished, read the output and compare with expectation
 	w.Close()
@@ -64,6 +64,7 @@ func TestEndToEndFunc(t *testing.T) {
 	compare := strings.Contains(string(out), "Number of tickets created: 3")
 
 	assert.Equal(t, compare, true)
+	assert.Equal(t, exitSuccess, exitCode)
 }
 
 // comment for now, error with the arguments that are redefined
@@ -87,7 +88,7 @@ func TestEndToEndFunc(t *testing.T) {
 // 	r, w, _ := os.Pipe()
 // 	os.Stdout = w
 
-// 	main()
+// 	exitCode := run()
 
 // 	os.Args = []string{}
 
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/michael-go/go-jsn/jsn"
+)
+
+// eventTypePrefix is followed by the outcome of the issue, like io.snyk.jira-tickets.issue.created
+// the existing tickets are never updated, so no updated event is emitted
+const eventTypePrefix = "io.snyk.jira-tickets.issue."
+
+// CloudEvent is an event in the CloudEvents 1.0 structured JSON format
+type CloudEvent struct {
+	SpecVersion     string    `json:"specversion"`
+	ID              string    `json:"id"`
+	Source          string    `json:"source"`
+	Type            string    `json:"type"`
+	Subject         string    `json:"subject"`
+	Time            time.Time `json:"time"`
+	DataContentType string    `json:"datacontenttype"`
+	Data            EventData `json:"data"`
+}
+
+// EventData is the outcome of an issue
+type EventData struct {
+	RunID     string `json:"runId"`
+	OrgID     string `json:"orgId"`
+	ProjectID string `json:"projectId"`
+	IssueID   string `json:"issueId"`
+	IssueType string `json:"issueType"`
+	Title     string `json:"title"`
+	Severity  string `json:"severity"`
+	Outcome   string `json:"outcome"`
+	Reason    string `json:"reason,omitempty"`
+	JiraKey   string `json:"jiraKey,omitempty"`
+	JiraURL   string `json:"jiraUrl,omitempty"`
+	DryRun    bool   `json:"dryRun"`
+}
+
+// eventSink writes an event per issue outcome in a JSON Lines file and/or posts it
+// to an HTTP endpoint. The posts are sent in the background so the tickets are not delayed
+type eventSink struct {
+	mu         sync.Mutex
+	runID      string
+	orgID      string
+	dryRun     bool
+	jiraURL    string
+	file       string
+	url        string
+	client     *http.Client
+	maxRetries int
+	backoff    time.Duration
+	sequence   int
+	queue      chan CloudEvent
+	done       chan struct{}
+	now        func() time.Time
+}
+
+// defaultEventSink is nil unless events are requested, every method is a no-op then
+var defaultEventSink *eventSink
+
+/*
+**
+function newEventSink
+input Of optionalFlags, dryRun and jiraURL are used
+input orgID string
+input file string, JSON Lines file, empty to skip
+input url string, endpoint receiving the events, empty to skip
+input client *http.Client
+return *eventSink
+**
+*/
+func newEventSink(Of optionalFlags, orgID string, file string, url string, client *http.Client) *eventSink {
+
+	sink := &eventSink{
+		runID:      defaultRunOutput.runID,
+		orgID:      orgID,
+		dryRun:     Of.dryRun,
+		jiraURL:    strings.TrimSuffix(Of.jiraURL, "/"),
+		file:       file,
+		url:        url,
+		client:     client,
+		maxRetries: 3,
+		backoff:    500 * time.Millisecond,
+		now:        time.Now,
+	}
+
+	if url != "" {
+		sink.queue = make(chan CloudEvent, 100)
+		sink.done = make(chan struct{})
+		go sink.post()
+	}
+
+	return sink
+}
+
+/*
+**
+function emit
+input projectID string
+input issue jsn.Json, open source issue, code issue from the list or code issue details
+input outcome string, one of the outcome constants
+input reason string, why the issue was not ticketed
+input jiraKey string, key of the created or existing ticket
+**
+*/
+func (s *eventSink) emit(projectID string, issue jsn.Json, outcome string, reason string, jiraKey string) {
+
+	if s == nil {
+		return
+	}
+
+	described := describeIssue(issue)
+	data := EventData{
+		RunID:     s.runID,
+		OrgID:     s.orgID,
+		ProjectID: projectID,
+		IssueID:   described.IssueID,
+		IssueType: described.IssueType,
+		Title:     described.Title,
+		Severity:  described.Severity,
+		Outcome:   outcome,
+		Reason:    reason,
+		JiraKey:   jiraKey,
+		DryRun:    s.dryRun,
+	}
+	data.JiraURL = ticketURL(s.jiraURL, jiraKey)
+
+	s.mu.Lock()
+	s.sequence++
+	event := CloudEvent{
+		SpecVersion:     "1.0",
+		ID:              fmt.Sprintf("%s-%d", s.runID, s.sequence),
+		Source:          "/snyk/org/" + s.orgID + "/project/" + projectID,
+		Type:            eventTypePrefix + outcome,
+		Subject:         described.IssueID,
+		Time:            s.now().UTC(),
+		DataContentType: "application/json",
+		Data:            data,
+	}
+
+	// the lines are written under the lock so they keep the order of the IDs
+	if s.file != "" {
+		if err := appendEvent(s.file, event); err != nil {
+			logger.Error("Could not write the event", "file", s.file, "event", event.ID, "error", err)
+		}
+	}
+	s.mu.Unlock()
+
+	if s.queue != nil {
+		s.queue <- event
+	}
+}
+
+func appendEvent(filename string, event CloudEvent) error {
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(append(data, '\n'))
+	closeErr := f.Close()
+	if err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// post sends the queued events one after the other until the queue is closed
+func (s *eventSink) post() {
+
+	defer close(s.done)
+
+	for event := range s.queue {
+		if err := s.send(event); err != nil {
+			logger.Error("Could not send the event", "url", s.url, "event", event.ID, "error", err)
+			writeErrorFile("sendEvent", fmt.Sprintf("*** ERROR *** Could not send the event %s: %s", event.ID, err), logger.With("project", event.Data.ProjectID, "issue", event.Data.IssueID))
+		}
+	}
+}
+
+/*
+**
+function send
+input event CloudEvent
+return error, the last error once the retries are exhausted
+Connection errors, 429 and 5xx are retried with an exponential backoff
+**
+*/
+func (s *eventSink) send(event CloudEvent) error {
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= s.maxRetries; attempt++ {
+
+		if attempt > 0 {
+			time.Sleep(s.backoff << uint(attempt-1))
+		}
+
+		request, err := http.NewRequest("POST", s.url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		request.Header.Set("Content-Type", "application/cloudevents+json; charset=utf-8")
+
+		response, err := s.client.Do(request)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		responseBody, _ := ioutil.ReadAll(response.Body)
+		response.Body.Close()
+
+		if response.StatusCode < 300 {
+			return nil
+		}
+
+		lastErr = fmt.Errorf("request failed with %s: %s", response.Status, bodyExcerpt(responseBody))
+		if !isRetryableStatus(response.StatusCode) {
+			return lastErr
+		}
+	}
+
+	return lastErr
+}
+
+/*
+**
+function close
+Wait for the queued events to be sent, the sink can't be used afterwards
+**
+*/
+func (s *eventSink) close() {
+
+	if s == nil || s.queue == nil {
+		return
+	}
+
+	close(s.queue)
+	<-s.done
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/michael-go/go-jsn/jsn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventSinkFileFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	filename := filepath.Join(t.TempDir(), "events.jsonl")
+	sink := newEventSink(optionalFlags{jiraURL: "https://example.atlassian.net/"}, "123", filename, "", nil)
+	sink.runID = "run"
+	sink.now = func() time.Time {
+		return time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	}
+
+	vuln, _ := jsn.NewJson(map[string]interface{}{
+		"id":        "SNYK-JS-MINIMIST-559764",
+		"issueType": "vuln",
+		"issueData": map[string]interface{}{"title": "Prototype Pollution", "severity": "high"},
+	})
+
+	sink.emit("project-a", vuln, outcomeCreated, "", "FPI-1")
+	sink.emit("project-a", vuln, outcomeFiltered, "no upgrade available", "")
+	sink.close()
+
+	f, err := os.Open(filename)
+	assert.Nil(err)
+	defer f.Close()
+
+	events := []CloudEvent{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		event := CloudEvent{}
+		assert.Nil(json.Unmarshal(scanner.Bytes(), &event))
+		events = append(events, event)
+	}
+	assert.Equal(2, len(events))
+
+	assert.Equal(CloudEvent{
+		SpecVersion:     "1.0",
+		ID:              "run-1",
+		Source:          "/snyk/org/123/project/project-a",
+		Type:            "io.snyk.jira-tickets.issue.created",
+		Subject:         "SNYK-JS-MINIMIST-559764",
+		Time:            time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC),
+		DataContentType: "application/json",
+		Data: EventData{
+			RunID:     "run",
+			OrgID:     "123",
+			ProjectID: "project-a",
+			IssueID:   "SNYK-JS-MINIMIST-559764",
+			IssueType: "vuln",
+			Title:     "Prototype Pollution",
+			Severity:  "high",
+			Outcome:   outcomeCreated,
+			JiraKey:   "FPI-1",
+			JiraURL:   "https://example.atlassian.net/browse/FPI-1",
+		},
+	}, events[0])
+
+	assert.Equal("run-2", events[1].ID)
+	assert.Equal("io.snyk.jira-tickets.issue.filtered", events[1].Type)
+	assert.Equal("no upgrade available", events[1].Data.Reason)
+
+	// without sink nothing is emitted
+	var noSink *eventSink
+	noSink.emit("project-a", vuln, outcomeCreated, "", "")
+	noSink.close()
+}
+
+func TestEventSinkHTTPFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	var mu sync.Mutex
+	attempts := 0
+	received := []CloudEvent{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		attempts++
+		// the first attempt fails and is retried
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		assert.Equal("application/cloudevents+json; charset=utf-8", r.Header.Get("Content-Type"))
+		data, _ := ioutil.ReadAll(r.Body)
+		event := CloudEvent{}
+		assert.Nil(json.Unmarshal(data, &event))
+		received = append(received, event)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	sink := newEventSink(optionalFlags{dryRun: true}, "123", "", server.URL, &http.Client{Timeout: 5 * time.Second})
+	sink.backoff = time.Millisecond
+
+	codeIssue, _ := jsn.NewJson(map[string]interface{}{
+		"id":         "code-1",
+		"attributes": map[string]interface{}{"title": "SQL Injection", "severity": "high"},
+	})
+	sink.emit("project-b", codeIssue, outcomeDryRun, "", "")
+	sink.emit("project-b", codeIssue, outcomeFailed, "Request failed", "")
+	sink.close()
+
+	assert.Equal(3, attempts)
+	assert.Equal(2, len(received))
+	assert.Equal("io.snyk.jira-tickets.issue.dry-run", received[0].Type)
+	assert.True(received[0].Data.DryRun)
+	assert.Equal("code", received[0].Data.IssueType)
+	assert.Equal("io.snyk.jira-tickets.issue.failed", received[1].Type)
+
+	// the errors that are not retried
+	sink = newEventSink(optionalFlags{}, "123", "", server.URL+"/missing", &http.Client{Timeout: 5 * time.Second})
+	server.Config.Handler = http.NotFoundHandler()
+	err := sink.send(received[0])
+	assert.NotNil(err)
+	sink.close()
+}
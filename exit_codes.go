@@ -0,0 +1,169 @@
+package main
+
+import (
+	"sync"
+)
+
+// exit codes of a run, they are documented in the README
+const (
+	exitSuccess        = 0 // tickets were created, or listed in dry run
+	exitError          = 1 // the run stopped on an unexpected error
+	exitConfigError    = 2 // the options or the config file are not valid
+	exitAuthError      = 3 // Snyk rejected the token or the OAuth client
+	exitPartialFailure = 4 // some projects or tickets failed, see --fail-on
+	exitNothingToDo    = 5 // the run succeeded, no new ticket was needed
+)
+
+// values of --fail-on
+const (
+	failOnAnyError       = "any-error"
+	failOnTicketFailure  = "ticket-failure"
+	failOnProjectFailure = "project-failure"
+	failOnNever          = "never"
+)
+
+var failOnValues = []string{failOnAnyError, failOnTicketFailure, failOnProjectFailure, failOnNever}
+
+/*
+**
+function isFailOn
+input value string
+return bool, true when the value is one of any-error, ticket-failure, project-failure or never
+**
+*/
+func isFailOn(value string) bool {
+	for _, failOn := range failOnValues {
+		if value == failOn {
+			return true
+		}
+	}
+	return false
+}
+
+// runStatus counts what decides the exit code, the projects and issues are processed in parallel
+type runStatus struct {
+	mu             sync.Mutex
+	ticketsCreated int
+	ticketsFailed  int
+	projectsFailed int
+	authFailed     bool
+	// projects with an issue skipped or a ticket not created, they keep the lastRun threshold
+	incompleteProjects map[string]bool
+}
+
+var defaultRunStatus = &runStatus{}
+
+// ticketCreated counts a ticket created, or that would be created in dry run
+func (s *runStatus) ticketCreated() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ticketsCreated++
+}
+
+// ticketFailed counts a ticket that could not be created
+func (s *runStatus) ticketFailed() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ticketsFailed++
+}
+
+// projectFailed counts a project skipped because its details, tickets or issues could not be retrieved
+func (s *runStatus) projectFailed() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.projectsFailed++
+}
+
+// issueNotTicketed records a project with an issue skipped or a ticket that could not be created
+func (s *runStatus) issueNotTicketed(projectID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.incompleteProjects == nil {
+		s.incompleteProjects = make(map[string]bool)
+	}
+	s.incompleteProjects[projectID] = true
+}
+
+// isIncomplete is true when an issue of the project was skipped or its ticket could not be created
+func (s *runStatus) isIncomplete(projectID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.incompleteProjects[projectID]
+}
+
+// authenticationFailed records that Snyk rejected the credentials
+func (s *runStatus) authenticationFailed() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.authFailed = true
+}
+
+// hasAuthFailed is true when Snyk rejected the credentials
+func (s *runStatus) hasAuthFailed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.authFailed
+}
+
+/*
+**
+function exitCode
+input failOn string, which failures make the run fail: any-error, ticket-failure, project-failure or never
+return int, the exit code of the run
+An authentication error is always reported, whatever the value of failOn
+**
+*/
+func (s *runStatus) exitCode(failOn string) int {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.authFailed {
+		return exitAuthError
+	}
+
+	failed := false
+	switch failOn {
+	case failOnAnyError:
+		failed = s.ticketsFailed > 0 || s.projectsFailed > 0
+	case failOnTicketFailure:
+		failed = s.ticketsFailed > 0
+	case failOnProjectFailure:
+		failed = s.projectsFailed > 0
+	}
+	if failed {
+		return exitPartialFailure
+	}
+
+	// the failures ignored by failOn are not reported as nothing to do
+	if s.ticketsCreated == 0 && s.ticketsFailed == 0 && s.projectsFailed == 0 {
+		return exitNothingToDo
+	}
+
+	return exitSuccess
+}
+
+/*
+**
+function exitCodeOutcome
+input code int, exit code of a run
+return string, the name of the exit code, shown by the status of the daemon
+**
+*/
+func exitCodeOutcome(code int) string {
+	switch code {
+	case exitSuccess:
+		return "success"
+	case exitError:
+		return "error"
+	case exitConfigError:
+		return "config-error"
+	case exitAuthError:
+		return "auth-error"
+	case exitPartialFailure:
+		return "partial-failure"
+	case exitNothingToDo:
+		return "nothing-to-do"
+	}
+	return "unknown"
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/michael-go/go-jsn/jsn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunStatusExitCodeFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	// nothing created, nothing failed
+	status := &runStatus{}
+	assert.Equal(exitNothingToDo, status.exitCode(failOnAnyError))
+
+	status.ticketCreated()
+	assert.Equal(exitSuccess, status.exitCode(failOnAnyError))
+
+	// a ticket failed
+	status.ticketFailed()
+	assert.Equal(exitPartialFailure, status.exitCode(failOnAnyError))
+	assert.Equal(exitPartialFailure, status.exitCode(failOnTicketFailure))
+	assert.Equal(exitSuccess, status.exitCode(failOnProjectFailure))
+	assert.Equal(exitSuccess, status.exitCode(failOnNever))
+
+	// a project failed and nothing was created
+	status = &runStatus{}
+	status.projectFailed()
+	assert.Equal(exitPartialFailure, status.exitCode(failOnAnyError))
+	assert.Equal(exitSuccess, status.exitCode(failOnTicketFailure))
+	assert.Equal(exitPartialFailure, status.exitCode(failOnProjectFailure))
+
+	// every ticket failed and the failures are ignored
+	status = &runStatus{}
+	status.ticketFailed()
+	assert.Equal(exitSuccess, status.exitCode(failOnNever))
+	assert.Equal(exitSuccess, status.exitCode(failOnProjectFailure))
+
+	// the authentication errors are always reported
+	status.authenticationFailed()
+	assert.True(status.hasAuthFailed())
+	assert.Equal(exitAuthError, status.exitCode(failOnNever))
+
+	assert.True(isFailOn("project-failure"))
+	assert.False(isFailOn("all"))
+}
+
+func TestIncompleteProjectFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	defaultRunStatus = &runStatus{}
+	issue, _ := jsn.NewJson(map[string]interface{}{"id": "SNYK-JS-1", "issueData": map[string]interface{}{"title": "ReDoS"}})
+
+	recordIssue("project-a", issue, outcomeCreated, "", "FPI-1")
+	recordIssue("project-a", issue, outcomeFiltered, "ignored in Snyk", "")
+	assert.False(defaultRunStatus.isIncomplete("project-a"))
+
+	// the skipped issues and the failed tickets keep the lastRun threshold
+	recordIssue("project-a", issue, outcomeSkipped, "could not retrieve the paths from Snyk", "")
+	recordIssue("project-b", issue, outcomeFailed, "500", "")
+	assert.True(defaultRunStatus.isIncomplete("project-a"))
+	assert.True(defaultRunStatus.isIncomplete("project-b"))
+	assert.False(defaultRunStatus.isIncomplete("project-c"))
+}
+
+func TestUnauthorizedSetsAuthErrorFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	oldStatus := defaultRunStatus
+	defaultRunStatus = &runStatus{}
+	defer func() { defaultRunStatus = oldStatus }()
+
+	cD := debug{}
+	CreateLogFile(cD, ErrorsFilePrefix)
+	defer removeLogFile()
+
+	reportSnykAPIError(&SnykAPIError{Kind: ErrNotFound, StatusCode: 404, Endpoint: "/v1/org/123"}, cD)
+	assert.False(defaultRunStatus.hasAuthFailed())
+
+	reportSnykAPIError(&SnykAPIError{Kind: ErrUnauthorized, StatusCode: 401, Endpoint: "/v1/org/123"}, cD)
+	assert.Equal(exitAuthError, defaultRunStatus.exitCode(failOnAnyError))
+}
@@ -42,3 +42,59 @@ func TestFormatJiraTicketFunc(t *testing.T) {
 		log.Fatal(err)
 	}
 }
+
+func TestFormatRemediationFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	issue, _ := jsn.NewJson([]byte(`{
+		"pkgName": "minimist",
+		"fixInfo": {
+			"isUpgradable": true,
+			"isPatchable": true,
+			"fixedIn": ["0.2.1", "1.2.3"],
+			"upgradePaths": [
+				{"path": [{"name": "goof", "version": "1.0.0"}, {"name": "mkdirp", "version": "0.5.1", "newVersion": "0.5.2"}, {"name": "minimist", "version": "0.0.8", "newVersion": "1.2.3"}]},
+				{"path": [{"name": "goof", "version": "1.0.0"}, {"name": "mkdirp", "version": "0.5.1", "newVersion": "0.5.2"}, {"name": "minimist", "version": "0.0.8", "newVersion": "1.2.3"}]},
+				{"path": [{"name": "goof", "version": "1.0.0"}, {"name": "optimist", "version": "0.6.1", "isDropped": true}]}
+			]
+		}
+	}`))
+
+	howToFix := formatRemediation(issue)
+
+	assert.Contains(howToFix, "**How to fix:**")
+	assert.Contains(howToFix, "- Fixed in: minimist@0.2.1, 1.2.3")
+	assert.Equal(1, strings.Count(howToFix, "- Upgrade mkdirp@0.5.1 to mkdirp@0.5.2"))
+	assert.Contains(howToFix, "- Remove optimist@0.6.1, it is dropped by the fix")
+	assert.Contains(howToFix, "- Snyk patch available")
+
+	noFix, _ := jsn.NewJson([]byte(`{"pkgName": "minimist", "fixInfo": {"isUpgradable": false, "isPatchable": false, "fixedIn": []}}`))
+
+	howToFix = formatRemediation(noFix)
+
+	assert.Contains(howToFix, "- No fixed version available")
+	assert.Contains(howToFix, "- Upgradable: no")
+	assert.Contains(howToFix, "- No Snyk patch available")
+}
+
+func TestFormatLicenseJiraTicketFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	projectInfo, _ := jsn.NewJson(readFixture("./fixtures/project.json"))
+	issueData, _ := jsn.NewJson(readFixture("./fixtures/licenseForJiraAggregatedWithPath.json"))
+
+	assert.True(isLicenseIssue(issueData))
+
+	jiraTicket := formatLicenseJiraTicket(issueData, projectInfo, flags{})
+
+	assert.Equal("snyk-playground/typescript:package.json - GPL-2.0 license", jiraTicket.Fields.Summary)
+	assert.Contains(jiraTicket.Fields.Description, "license:  GPL\\-2.0")
+	assert.Contains(jiraTicket.Fields.Description, "policy severity:  high")
+	assert.Contains(jiraTicket.Fields.Description, "GPL\\-2.0: Contact the legal team before shipping this dependency.")
+	assert.Contains(jiraTicket.Fields.Description, "*Dependent Paths:*")
+	assert.Contains(jiraTicket.Fields.Description, "\"snyk\"@\"1.228.3\" => \"goof\"@\"0.0.3\"")
+	assert.NotContains(jiraTicket.Fields.Description, "How to fix")
+	assert.Contains(jiraTicket.Fields.Description, "[More About this issue|https://security.snyk.io/vuln/snyk:lic:npm:goof:GPL-2.0]")
+}
@@ -0,0 +1,347 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// GitHubAPI is the default GitHub REST API, set --githubAPI for GitHub Enterprise Server
+const GitHubAPI = "https://api.github.com"
+
+// githubLabel is added to every issue created, the existing issues are listed with it
+const githubLabel = "snyk"
+
+// githubPageSize is the number of issues listed per request, the maximum of the API
+const githubPageSize = 100
+
+// githubMarker links a GitHub issue to the Snyk project and issue, it is hidden in the rendered body
+var githubMarker = regexp.MustCompile(`<!-- snyk-issue: ([^/\s]+)/(\S+) -->`)
+
+// defaultGitHubClient sends the GitHub requests, it shares the transport of the Snyk client
+// but not its OAuth token
+var defaultGitHubClient = newGitHubClient()
+
+// defaultGitHubIssues keeps the issues of the repositories listed by the run, the projects
+// sharing a repository don't page through it again
+var defaultGitHubIssues = newGitHubIssueCache()
+
+// githubIssueCache is the issues of every repository listed, keyed by API and repository
+type githubIssueCache struct {
+	mu    sync.Mutex
+	repos map[string]*githubRepoIssues
+}
+
+// githubRepoIssues is the issues of a repository, project ID to issue ID to owner/repository#number
+type githubRepoIssues struct {
+	mu      sync.Mutex
+	listed  bool
+	tickets map[string]map[string]string
+}
+
+func newGitHubIssueCache() *githubIssueCache {
+	return &githubIssueCache{repos: make(map[string]*githubRepoIssues)}
+}
+
+// repo is the issues of a repository, without a cache they are listed for every project
+func (c *githubIssueCache) repo(key string) *githubRepoIssues {
+
+	if c == nil {
+		return &githubRepoIssues{}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.repos[key] == nil {
+		c.repos[key] = &githubRepoIssues{}
+	}
+
+	return c.repos[key]
+}
+
+// newGitHubClient is a client with the retries of the Snyk client, the GitHub rate limits are
+// retried too and its errors don't count as Snyk authentication errors
+func newGitHubClient() *snykClient {
+
+	client := newSnykClient()
+	client.report = reportGitHubAPIError
+	client.retryable = isRetryableGitHubResponse
+
+	return client
+}
+
+// isRetryableGitHubResponse retries the 429 and 5xx, and the 403 GitHub answers when a rate limit is reached
+func isRetryableGitHubResponse(response *http.Response) bool {
+
+	if response.StatusCode == http.StatusForbidden {
+		return response.Header.Get("Retry-After") != "" || response.Header.Get("X-RateLimit-Remaining") == "0"
+	}
+
+	return isRetryableStatus(response.StatusCode)
+}
+
+/*
+**
+function reportGitHubAPIError
+input apiErr *SnykAPIError
+input customDebug debug
+Print the hints matching the error and write it in the error file, a rejected
+GitHub token doesn't make the run an authentication failure of Snyk
+**
+*/
+func reportGitHubAPIError(apiErr *SnykAPIError, customDebug debug) {
+
+	if apiErr == nil {
+		return
+	}
+
+	status := apiErr.Status
+	if status == "" && apiErr.Cause != nil {
+		status = apiErr.Cause.Error()
+	}
+
+	fields := []interface{}{"endpoint", apiErr.Endpoint, "status", status}
+
+	switch {
+	case errors.Is(apiErr, ErrUnauthorized):
+		customDebug.Error("GitHub request failed", append(fields, "hint", "Please check the GitHub token")...)
+	case errors.Is(apiErr, ErrForbidden), errors.Is(apiErr, ErrNotFound):
+		customDebug.Error("GitHub request failed", append(fields, "hint", "Please check the repository and that the GitHub token can write its issues")...)
+	default:
+		customDebug.Error("GitHub request failed", fields...)
+	}
+
+	if len(apiErr.Body) > 0 {
+		customDebug.Debug("GitHub request failure details", "endpoint", apiErr.Endpoint, "details", string(apiErr.Body))
+	}
+
+	entry := newJournalEntry("githubAPIRequest", fmt.Sprintf("*** INFO *** GitHub request failed with error %s", status), customDebug)
+	entry.Endpoint = apiErr.Endpoint
+	entry.Status = apiErr.StatusCode
+	entry.Body = bodyExcerpt(apiErr.Body)
+	writeJournalEntry(entry, customDebug)
+}
+
+// githubTracker creates the tickets as issues of a GitHub repository
+type githubTracker struct {
+	api    string
+	repo   string
+	token  string
+	labels []string
+}
+
+// githubIssue is the part of a GitHub issue read by the tracker
+type githubIssue struct {
+	Number      int       `json:"number"`
+	Title       string    `json:"title"`
+	Body        string    `json:"body"`
+	State       string    `json:"state"`
+	HTMLURL     string    `json:"html_url"`
+	PullRequest *struct{} `json:"pull_request,omitempty"`
+}
+
+/*
+**
+function newGitHubTracker
+input api string, the GitHub REST API URL
+input repo string, owner/repository
+input token string
+input labels string, comma separated labels added to the issues with the snyk label
+return *githubTracker
+**
+*/
+func newGitHubTracker(api string, repo string, token string, labels string) *githubTracker {
+
+	if api == "" {
+		api = GitHubAPI
+	}
+
+	issueLabels := []string{githubLabel}
+	for _, label := range strings.Split(labels, ",") {
+		if label = strings.TrimSpace(label); label != "" && label != githubLabel {
+			issueLabels = append(issueLabels, label)
+		}
+	}
+
+	return &githubTracker{
+		api:    strings.TrimSuffix(api, "/"),
+		repo:   repo,
+		token:  token,
+		labels: issueLabels,
+	}
+}
+
+func (t *githubTracker) name() string {
+	return TrackerGitHub
+}
+
+func (t *githubTracker) target() string {
+	return t.repo
+}
+
+// request sends a request to the GitHub API with the retries of the Snyk client
+func (t *githubTracker) request(verb string, endpointURL string, body []byte, customDebug debug) ([]byte, error) {
+
+	headers := map[string]string{
+		"Accept":               "application/vnd.github+json",
+		"Authorization":        "Bearer " + t.token,
+		"Content-Type":         "application/json",
+		"X-GitHub-Api-Version": "2022-11-28",
+	}
+
+	return defaultGitHubClient.do(verb, endpointURL, headers, body, customDebug)
+}
+
+// key is the reference of an issue, owner/repository#number
+func (t *githubTracker) key(number int) string {
+	return fmt.Sprintf("%s#%d", t.repo, number)
+}
+
+// body is the markdown of the content followed by the marker of the Snyk issue
+func (t *githubTracker) body(content ticketContent) string {
+	return fmt.Sprintf("%s\n\n<!-- snyk-issue: %s/%s -->\n", content.Body, content.ProjectID, content.IssueID)
+}
+
+/*
+**
+function existingTickets
+input projectID string
+input customDebug debug
+return map[string]string, issue ID to owner/repository#number
+return error, when the issues cannot be listed
+The open and closed issues with the snyk label are listed, an issue closed
+in GitHub is not created again. The repository is listed once per run, a
+listing that failed is tried again by the next project.
+**
+*/
+func (t *githubTracker) existingTickets(projectID string, customDebug debug) (map[string]string, error) {
+
+	repo := defaultGitHubIssues.repo(t.api + "/repos/" + t.repo)
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if !repo.listed {
+		tickets, err := t.listTickets(customDebug)
+		if err != nil {
+			customDebug.Error("Could not list the GitHub issues of the project", "repo", t.repo, "project", projectID)
+			return nil, err
+		}
+		repo.tickets = tickets
+		repo.listed = true
+	}
+
+	// a copy, the tickets of the project are merged with the ones of the state and the checkpoint
+	tickets := make(map[string]string, len(repo.tickets[projectID]))
+	for issueID, key := range repo.tickets[projectID] {
+		tickets[issueID] = key
+	}
+
+	return tickets, nil
+}
+
+/*
+**
+function listTickets
+input customDebug debug
+return map[string]map[string]string, project ID to issue ID to owner/repository#number
+return error, when the issues cannot be listed
+Page through the issues of the repository with the snyk label
+**
+*/
+func (t *githubTracker) listTickets(customDebug debug) (map[string]map[string]string, error) {
+
+	tickets := make(map[string]map[string]string)
+
+	for page := 1; ; page++ {
+		endpoint := fmt.Sprintf("%s/repos/%s/issues?state=all&labels=%s&per_page=%d&page=%d", t.api, t.repo, githubLabel, githubPageSize, page)
+		responseData, err := t.request("GET", endpoint, nil, customDebug)
+		if err != nil {
+			customDebug.Error("Could not get the GitHub issues", "repo", t.repo, "error", err)
+			writeErrorFile("existingTickets", fmt.Sprintf("Could not get the GitHub issues of %s %s\n", t.repo, err.Error()), customDebug)
+			return nil, errors.New("Could not get the tickets")
+		}
+
+		var issues []githubIssue
+		if err := json.Unmarshal(responseData, &issues); err != nil {
+			customDebug.Error("Could not read the GitHub issues", "repo", t.repo, "error", err)
+			writeErrorFile("existingTickets", fmt.Sprintf("Could not read the GitHub issues of %s %s\n", t.repo, err.Error()), customDebug)
+			return nil, errors.New("Could not read the GitHub issues")
+		}
+
+		for _, issue := range issues {
+			// the issues endpoint lists the pull requests too
+			if issue.PullRequest != nil {
+				continue
+			}
+			marker := githubMarker.FindStringSubmatch(issue.Body)
+			if marker == nil {
+				continue
+			}
+			if tickets[marker[1]] == nil {
+				tickets[marker[1]] = make(map[string]string)
+			}
+			tickets[marker[1]][marker[2]] = t.key(issue.Number)
+		}
+
+		if len(issues) < githubPageSize {
+			break
+		}
+	}
+
+	return tickets, nil
+}
+
+/*
+**
+function newRequest
+input content ticketContent
+input customDebug debug
+return trackerRequest, the issue with the markdown body and the endpoint creating it
+return error, when the issue cannot be marshalled
+**
+*/
+func (t *githubTracker) newRequest(content ticketContent, customDebug debug) (trackerRequest, error) {
+
+	request := trackerRequest{
+		IssueID:     content.IssueID,
+		Endpoint:    fmt.Sprintf("%s/repos/%s/issues", t.api, t.repo),
+		Summary:     content.Title,
+		Description: t.body(content),
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"title":  request.Summary,
+		"body":   request.Description,
+		"labels": t.labels,
+	})
+	if err != nil {
+		return request, err
+	}
+	request.Payload = payload
+
+	return request, nil
+}
+
+func (t *githubTracker) create(request trackerRequest, customDebug debug) ([]byte, *JiraDetailForTicket, error) {
+
+	responseData, err := t.request("POST", request.Endpoint, request.Payload, customDebug)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	issue := githubIssue{}
+	if err := json.Unmarshal(responseData, &issue); err != nil || issue.Number == 0 {
+		customDebug.Error("Could not read the created GitHub issue", "endpoint", request.Endpoint, "error", err)
+		return nil, nil, errors.New("Received an invalid response from the GitHub issues API")
+	}
+
+	return responseData, &JiraDetailForTicket{
+		JiraIssue: &JiraIssueForTicket{Id: strconv.Itoa(issue.Number), Key: t.key(issue.Number)},
+		IssueId:   request.IssueID,
+	}, nil
+}
@@ -6,9 +6,11 @@ require (
 	github.com/kentaro-m/blackfriday-confluence v0.0.0-20191222131424-8d627b5b68dc
 	github.com/michael-go/go-jsn v0.0.0-20171026145752-f106662b224a
 	github.com/nsf/jsondiff v0.0.0-20190712045011-8443391ee9b6
+	github.com/robfig/cron/v3 v3.0.1
 	github.com/spf13/pflag v1.0.5
 	github.com/spf13/viper v1.10.1
 	github.com/stretchr/testify v1.7.0
+	go.etcd.io/bbolt v1.3.6
 	gopkg.in/russross/blackfriday.v2 v2.0.0
 	gopkg.in/yaml.v2 v2.4.0
 )
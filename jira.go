@@ -5,8 +5,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/michael-go/go-jsn/jsn"
@@ -54,7 +54,7 @@ type IssueType struct {
 func getJiraTickets(Mf MandatoryFlags, projectID string, customDebug debug) (map[string]string, error) {
 	responseData, err := makeSnykAPIRequest("GET", Mf.endpointAPI+"/v1/org/"+Mf.orgID+"/project/"+projectID+"/jira-issues", Mf.apiToken, nil, customDebug)
 	if err != nil {
-		customDebug.Debug("*** ERROR *** Could not get the Jira issues via Snyk API")
+		customDebug.Error("Could not get the Jira issues via Snyk API", "project", projectID, "error", err)
 		message := fmt.Sprintf("Could not get the tickets %s\n", err.Error())
 		writeErrorFile("getJiraTickets", message, customDebug)
 		return nil, errors.New("Could not get the tickets")
@@ -62,7 +62,7 @@ func getJiraTickets(Mf MandatoryFlags, projectID string, customDebug debug) (map
 
 	tickets, err := jsn.NewJson(responseData)
 	if err != nil {
-		customDebug.Debug("*** ERROR *** Could not read Jira issues via Snyk API")
+		customDebug.Error("Could not read Jira issues via Snyk API", "project", projectID, "error", err)
 		message := fmt.Sprintf("Could not read Jira issues via Snyk API %s\n", err.Error())
 		writeErrorFile("getJiraTickets", message, customDebug)
 		return nil, errors.New("Could not read Jira issues via Snyk API")
@@ -77,148 +77,218 @@ func getJiraTickets(Mf MandatoryFlags, projectID string, customDebug debug) (map
 	return tickRefs, err
 }
 
-/*
-**
-function openJiraTicket
-argument lots
-return responseData: request response from snyk API
-return error: if request or ticket creation failure
-create a ticket for a specific vuln
+// snykJiraTracker creates the Jira tickets through the Jira integration of Snyk
+type snykJiraTracker struct {
+	options flags
+}
 
-	ticket is created and send to snyk jira ticket creation API endpoint
+func (t snykJiraTracker) name() string {
+	return TrackerJira
+}
+
+func (t snykJiraTracker) target() string {
+	if t.options.mandatoryFlags.jiraProjectKey != "" {
+		return t.options.mandatoryFlags.jiraProjectKey
+	}
+	return t.options.mandatoryFlags.jiraProjectID
+}
+
+func (t snykJiraTracker) existingTickets(projectID string, customDebug debug) (map[string]string, error) {
+	return getJiraTickets(t.options.mandatoryFlags, projectID, customDebug)
+}
 
+/*
+**
+function newRequest
+input content ticketContent
+input customDebug debug
+return trackerRequest, the Jira issue in Confluence wiki markup and the Snyk endpoint creating it
+return error, when the ticket cannot be marshalled
+The Jira project, issue type, labels, due date, assignee, priority and the
+custom mandatory fields of the config file are added.
 **
 */
-func openJiraTicket(flags flags, projectInfo jsn.Json, vulnForJira interface{}, customDebug debug) ([]byte, *Tickets, error, string) {
+func (t snykJiraTracker) newRequest(content ticketContent, customDebug debug) (trackerRequest, error) {
 
-	jsonVuln, _ := jsn.NewJson(vulnForJira)
-	issueType := jsonVuln.K("data").K("attributes").K("issueType").String().Value
-	vulnID := jsonVuln.K("id").String().Value
+	Mf := t.options.mandatoryFlags
+	Of := t.options.optionalFlags
 
-	var ticketFile *Tickets
-	var jiraTicket *JiraIssue
+	endpoint := fmt.Sprintf("/v1/org/%s/project/%s/issue/%s/jira-issue", Mf.orgID, content.ProjectID, content.IssueID)
+	request := trackerRequest{IssueID: content.IssueID, Endpoint: Mf.endpointAPI + endpoint}
 
-	if issueType == "code" {
-		jiraTicket = formatCodeJiraTicket(jsonVuln, projectInfo, flags)
-		vulnID = jsonVuln.K("data").K("id").String().Value
-	} else {
-		jiraTicket = formatJiraTicket(jsonVuln, projectInfo, flags)
+	jiraTicket := renderJiraIssue(content)
+
+	if Mf.jiraProjectKey != "" {
+		jiraTicket.Fields.Projects.Key = Mf.jiraProjectKey
+	} else if Mf.jiraProjectID != "" {
+		jiraTicket.Fields.Projects.ID = Mf.jiraProjectID
 	}
 
-	if len(vulnID) == 0 {
-		writeErrorFile("openJiraTicket", "*** ERROR *** Failed to create ticket, vuln ID is empty\n", customDebug)
-		return nil, nil, errors.New("*** ERROR *** Failed to create ticket, vuln ID is empty"), ""
+	jiraTicket.Fields.IssueTypes.Name = Of.jiraTicketType
+
+	if Of.labels != "" {
+		jiraTicket.Fields.Labels = strings.Split(Of.labels, ",")
 	}
 
-	if flags.mandatoryFlags.jiraProjectKey != "" {
-		jiraTicket.Fields.Projects.Key = flags.mandatoryFlags.jiraProjectKey
-	} else if flags.mandatoryFlags.jiraProjectID != "" {
-		jiraTicket.Fields.Projects.ID = flags.mandatoryFlags.jiraProjectID
+	if Of.dueDate != "" {
+		jiraTicket.Fields.DueDate = Of.dueDate
 	}
 
-	jiraTicket.Fields.IssueTypes.Name = flags.optionalFlags.jiraTicketType
+	if Of.assigneeID != "" {
+		var assignee Assignee
+		assignee.AccountId = Of.assigneeID
+		jiraTicket.Fields.Assignees = &assignee
+	}
 
-	projectInfoId := projectInfo.K("id").String().Value
-	endpoint := fmt.Sprintf("/v1/org/%s/project/%s/issue/%s/jira-issue", flags.mandatoryFlags.orgID, projectInfoId, vulnID)
-	var jiraApiUrl = flags.mandatoryFlags.endpointAPI + endpoint
+	if Of.priorityIsSeverity {
+		jiraTicket.Fields.Priority = jiraPriority(content.Severity)
+	}
 
-	if projectInfoId == "" {
-		writeErrorFile("openJiraTicket", "Failure, Could not retrieve project ID \n", customDebug)
-		return nil, nil, errors.New("Failure, Could not retrieve project ID"), endpoint
+	ticket, err := json.Marshal(jiraTicket)
+	if err != nil {
+		return request, err
 	}
 
-	if flags.optionalFlags.labels != "" {
-		jiraTicket.Fields.Labels = strings.Split(flags.optionalFlags.labels, ",")
+	// Add Mandatory filed to the ticket
+	if len(t.options.customMandatoryJiraFields) > 0 {
+		ticket = addMandatoryFieldToTicket(ticket, t.options.customMandatoryJiraFields, customDebug)
 	}
 
-	if flags.optionalFlags.dueDate != "" {
-		jiraTicket.Fields.DueDate = flags.optionalFlags.dueDate
+	request.Payload = ticket
+	request.Summary = jiraTicket.Fields.Summary
+	request.Description = jiraTicket.Fields.Description
+
+	return request, nil
+}
+
+func (t snykJiraTracker) create(request trackerRequest, customDebug debug) ([]byte, *JiraDetailForTicket, error) {
+
+	responseData, err := makeSnykAPIRequest("POST", request.Endpoint, t.options.mandatoryFlags.apiToken, request.Payload, customDebug)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	if flags.optionalFlags.assigneeID != "" {
-		var assignee Assignee
-		assignee.AccountId = flags.optionalFlags.assigneeID
-		jiraTicket.Fields.Assignees = &assignee
+	if bytes.Equal(responseData, nil) {
+		customDebug.Error("Request response is empty", "endpoint", request.Endpoint)
+		return nil, nil, errors.New("Received empty response from /jira-issues API")
 	}
 
-	if flags.optionalFlags.priorityIsSeverity {
-		var priority PriorityType
+	return responseData, getJiraTicketId(responseData), nil
+}
 
-		severity := jsonVuln.K("issueData").K("severity").String().Value
-		if issueType == "code" {
-			severity = jsonVuln.K("data").K("attributes").K("severity").String().Value
-		}
+/*
+**
+function jiraPriority
+input severity string
+return *PriorityType, the priority of SNYK_JIRA_PRIORITY_FOR_<SEVERITY>_VULN when set,
+Highest for critical and the severity otherwise
+**
+*/
+func jiraPriority(severity string) *PriorityType {
 
-		jiraMappingEnvVarName := fmt.Sprintf("SNYK_JIRA_PRIORITY_FOR_%s_VULN", strings.ToUpper(severity))
-		val, present := os.LookupEnv(jiraMappingEnvVarName)
-		if present {
-			priority.Name = val
-		} else {
-			if severity == "critical" {
-				priority.Name = "Highest"
-			} else {
+	var priority PriorityType
 
-				priority.Name = strings.Title(severity)
+	jiraMappingEnvVarName := fmt.Sprintf("SNYK_JIRA_PRIORITY_FOR_%s_VULN", strings.ToUpper(severity))
+	val, present := os.LookupEnv(jiraMappingEnvVarName)
+	if present {
+		priority.Name = val
+	} else if severity == "critical" {
+		priority.Name = "Highest"
+	} else {
+		priority.Name = strings.Title(severity)
+	}
 
-			}
+	return &priority
+}
 
-		}
-		jiraTicket.Fields.Priority = &priority
+/*
+**
+function openJiraTicket
+argument lots
+return responseData: request response from snyk API
+return error: if request or ticket creation failure
+create a ticket for a specific vuln
+
+	ticket is created and send to snyk jira ticket creation API endpoint
+
+**
+*/
+func openJiraTicket(flags flags, projectInfo jsn.Json, vulnForJira interface{}, customDebug debug) ([]byte, *Tickets, error, string) {
+
+	jsonVuln, _ := jsn.NewJson(vulnForJira)
+	content := formatTicketContent(jsonVuln, projectInfo, flags)
+	vulnID := content.IssueID
+
+	if len(vulnID) == 0 {
+		writeErrorFile("openJiraTicket", "*** ERROR *** Failed to create ticket, vuln ID is empty\n", customDebug)
+		return nil, nil, errors.New("*** ERROR *** Failed to create ticket, vuln ID is empty"), ""
+	}
+
+	projectInfoId := projectInfo.K("id").String().Value
+	content.ProjectID = projectInfoId
+
+	tracker := flags.trackerFor(projectInfoId, projectInfo.K("name").String().Value)
+	request, err := tracker.newRequest(content, customDebug)
+	endpoint := strings.TrimPrefix(request.Endpoint, flags.mandatoryFlags.endpointAPI)
+
+	if projectInfoId == "" {
+		writeErrorFile("openJiraTicket", "Failure, Could not retrieve project ID \n", customDebug)
+		return nil, nil, errors.New("Failure, Could not retrieve project ID"), endpoint
 	}
 
-	ticket, err := json.Marshal(jiraTicket)
 	if err != nil {
-		customDebug.Debug("*** ERROR *** Error while creating the ticket")
+		customDebug.Error("Error while creating the ticket", "error", err)
 		writeErrorFile("openJiraTicket", "*** ERROR *** Error while creating the ticket\n", customDebug)
 		return nil, nil, errors.New("Failure, Failure to create ticket(s)"), endpoint
 	}
 
-	// Add Mandatory filed to the ticket
-	if len(flags.customMandatoryJiraFields) > 0 {
-		ticket = addMandatoryFieldToTicket(ticket, flags.customMandatoryJiraFields, customDebug)
-	}
-
-	customDebug.Debugf("*** INFO *** Ticket data to be sent %s", string(ticket))
+	customDebug.Trace("Ticket data to be sent", "tracker", tracker.name(), "ticket", string(request.Payload))
 
 	// create ticket struct to add in the logfile
 	// test is dryRun, if not log only what's have been created
 	if flags.optionalFlags.dryRun == true {
-		ticketFile = &Tickets{
-			Summary:     jiraTicket.Fields.Summary,
-			Description: jiraTicket.Fields.Description,
+		ticketFile := &Tickets{
+			Summary:     request.Summary,
+			Description: request.Description,
+			IssueID:     vulnID,
+			JiraProject: tracker.target(),
+			Endpoint:    request.Endpoint,
+			Payload:     request.Payload,
+			Tracker:     tracker.name(),
 		}
 		return nil, ticketFile, errors.New("*** WARN *** Skipping opening a ticket in --dryRun mode"), endpoint
 	}
 
-	// check that vulnId exist and dryRun is off
+	// written before the request, a crash before the result is known must not lead to a second ticket
+	defaultCheckpoint.ticketPending(projectInfoId, vulnID)
 
-	var er error
-	responseData, er := makeSnykAPIRequest("POST", jiraApiUrl, flags.mandatoryFlags.apiToken, ticket, customDebug)
+	responseData, issueDetail, er := tracker.create(request, customDebug)
 
 	if er != nil {
-		if er.Error() == "Failed too many times with 50x errors" {
-			message := fmt.Sprintf("*** ERROR *** Failed too many times with 50x errors %s\n", err.Error())
+		// after a 5xx or a connection error the ticket may exist, it stays pending until it is found
+		if !errors.Is(er, ErrServer) && !errors.Is(er, ErrConnection) {
+			defaultCheckpoint.ticketFailed(projectInfoId, vulnID)
+		}
+		if errors.Is(er, ErrServer) {
+			message := fmt.Sprintf("*** ERROR *** Failed too many times with 50x errors %s\n", request.Endpoint)
 			writeErrorFile("openJiraTicket", message, customDebug)
-			return nil, nil, errors.New("Failed too many times with 50x errors"), jiraApiUrl
+			return nil, nil, er, request.Endpoint
 		}
 		message := fmt.Sprintf("*** ERROR *** Request failed\n")
 		writeErrorFile("openJiraTicket", message, customDebug)
-		customDebug.Debug("*** ERROR *** Request failed")
-		return nil, nil, errors.New(er.Error()), endpoint
-	}
-
-	if bytes.Equal(responseData, nil) {
-		customDebug.Debugf("*** ERROR *** Request response from %s is empty\n", jiraApiUrl)
-		return nil, nil, errors.New("Received empty response from /jira-issues API"), jiraApiUrl
+		customDebug.Error("Request failed", "endpoint", request.Endpoint, "error", er)
+		return nil, nil, er, endpoint
 	}
 
 	// create ticket struct to add in the json logfile
 	// log only what's have been created
-	ticketFile = &Tickets{
-		Summary:         jiraTicket.Fields.Summary,
-		Description:     jiraTicket.Fields.Description,
-		JiraIssueDetail: getJiraTicketId(responseData),
+	ticketFile := &Tickets{
+		Summary:         request.Summary,
+		Description:     request.Description,
+		JiraIssueDetail: issueDetail,
+		contentHash:     payloadHash(request.Payload),
 	}
+	defaultCheckpoint.ticketCreated(projectInfoId, vulnID, ticketKey(ticketFile))
 
 	return responseData, ticketFile, nil, endpoint
 
@@ -232,7 +302,7 @@ func displayErrorForIssue(vulnForJira interface{}, reason string, error error, e
 	if reason == "ifUpgradeAvailableOnly" || reason == "ifAutoFixableOnly" {
 		message = fmt.Sprintf("VulnID %s ticket not created : %s", vulnID, error)
 	}
-	log.Printf("*** ERROR *** " + message)
+	customDebug.Error("Ticket not created", "reason", reason, "error", error)
 	writeErrorFile("openJiraTickets", message, customDebug)
 
 	return message
@@ -245,10 +315,25 @@ func openJiraTickets(flags flags, projectInfo jsn.Json, vulnsForJira map[string]
 	issueCreated := 0
 	MaxNumberOfRetry := 1
 	var ticketArray []Tickets
+	projectID := projectInfo.K("id").String().Value
+	tracker := flags.trackerFor(projectID, projectInfo.K("name").String().Value)
+	// the tickets are listed again when a ticket is pending, its result is unknown
+	pending := &pendingLookup{tracker: tracker, projectID: projectID}
+
+	// sorted so the tickets are opened in the same order on every run
+	issueIDs := make([]string, 0, len(vulnsForJira))
+	for issueID := range vulnsForJira {
+		issueIDs = append(issueIDs, issueID)
+	}
+	sort.Strings(issueIDs)
 
-	for _, vulnForJira := range vulnsForJira {
+	for _, issueID := range issueIDs {
+		vulnForJira := vulnsForJira[issueID]
 		jsonVuln, _ := jsn.NewJson(vulnForJira)
 
+		// the entries and the journal lines of this issue carry its ID
+		customDebug := customDebug.With("issue", issueID)
+
 		// determine if is code issue
 		issueType := jsonVuln.K("data").K("attributes").K("issueType").String().Value
 		isCodeIssue := strings.Contains(issueType, "code")
@@ -257,6 +342,7 @@ func openJiraTickets(flags flags, projectInfo jsn.Json, vulnsForJira map[string]
 			if jsonVuln.K("fixInfo").K("isUpgradable").Bool().Value == false {
 				message := fmt.Sprintf("Skipping creating ticket for %s because no upgrade is available.", jsonVuln.K("issueData").K("title").String().Value)
 				fullListNotCreatedIssue += displayErrorForIssue(vulnForJira, "ifUpgradeAvailableOnly", errors.New(message), "", customDebug)
+				recordIssue(projectID, jsonVuln, outcomeFiltered, "no upgrade available", "")
 				continue
 			}
 		} else if flags.optionalFlags.ifAutoFixableOnly && isCodeIssue == false {
@@ -264,49 +350,92 @@ func openJiraTickets(flags flags, projectInfo jsn.Json, vulnsForJira map[string]
 			if jsonVuln.K("fixInfo").K("isFixable").Bool().Value == false {
 				message := fmt.Sprintf("Skipping creating ticket for %s because no fix is available.", jsonVuln.K("issueData").K("title").String().Value)
 				fullListNotCreatedIssue += displayErrorForIssue(vulnForJira, "ifAutoFixableOnly", errors.New(message), "", customDebug)
+				recordIssue(projectID, jsonVuln, outcomeFiltered, "no fix available", "")
 				continue
 			}
 		}
 
+		// the result of the request sent before is unknown, the ticket is only created when the tracker doesn't have it
+		if defaultCheckpoint.isPending(projectID, issueID) {
+			jiraKey, err := pending.find(issueID, customDebug)
+			if err != nil {
+				message := fmt.Sprintf("*** WARN *** Ticket for %s may have been created before the run was interrupted and the tickets could not be listed, not created again\n", issueID)
+				writeErrorFile("openJiraTickets", message, customDebug)
+				customDebug.Warn("Ticket may have been created before the run was interrupted, not created again", "error", err)
+				fullListNotCreatedIssue += message
+				recordIssue(projectID, jsonVuln, outcomeSkipped, "ticket may have been created before the run was interrupted", "")
+				continue
+			}
+			if jiraKey != "" {
+				customDebug.Info("Ticket created before the run was interrupted", "jiraKey", jiraKey)
+				recordIssue(projectID, jsonVuln, outcomeAlreadyTicketed, "created before the run was interrupted", jiraKey)
+				continue
+			}
+			customDebug.Info("Pending ticket not found in the tracker, creating it", "tracker", tracker.name())
+		}
+
 		RequestFailed = false
 
-		customDebug.Debug("*** INFO *** Trying to open ticket for vuln:", jsonVuln.K("issueData").K("title").String().Value)
+		customDebug.Debug("Trying to open ticket", "title", jsonVuln.K("issueData").K("title").String().Value)
 		responseDataAggregatedByte, ticket, err, jiraApiUrl := openJiraTicket(flags, projectInfo, vulnForJira, customDebug)
 		if err != nil {
 			message := fmt.Sprintf("*** ERROR *** Failed to open a Jira ticket via Snyk API: %s\nERROR:%s", jiraApiUrl, err)
 			writeErrorFile("openJiraTickets", message, customDebug)
-			customDebug.Debugf("*** ERROR *** Failed to open a Jira ticket via Snyk API: %s\nERROR:%s", jiraApiUrl, err)
+			customDebug.Debug("Failed to open a Jira ticket via Snyk API", "endpoint", jiraApiUrl, "error", err)
 			RequestFailed = true
 		}
 
 		// Don't need to do all that on dryRun
 		if !flags.optionalFlags.dryRun {
 
-			if RequestFailed == true {
+			// only a payload refused by Jira is retried without the priority, after a 5xx or
+			// a connection error the ticket may exist and GitHub issues have no priority field
+			retried := false
+			if RequestFailed == true && tracker.name() == TrackerJira && isPayloadRefused(err) {
+				retried = true
 				for numberOfRetries := 0; numberOfRetries < MaxNumberOfRetry; numberOfRetries++ {
 
-					customDebug.Debug("*** INFO *** Retrying with priorityIsSeverity set to false, max retries=", MaxNumberOfRetry)
+					customDebug.Info("Retrying with priorityIsSeverity set to false", "maxRetries", MaxNumberOfRetry)
 
 					flags.optionalFlags.priorityIsSeverity = false
 					responseDataAggregatedByte, ticket, err, jiraApiUrl = openJiraTicket(flags, projectInfo, vulnForJira, customDebug)
-					if err != nil {
-						fullListNotCreatedIssue += displayErrorForIssue(vulnForJira, "api", err, jiraApiUrl, customDebug)
-					} else {
+					if err == nil {
 						RequestFailed = false
 						break
 					}
+					fullListNotCreatedIssue += displayErrorForIssue(vulnForJira, "api", err, jiraApiUrl, customDebug)
+					if !isPayloadRefused(err) {
+						break
+					}
 				}
 			}
 			if RequestFailed == true && strings.Contains(strings.ToLower(string(responseDataAggregatedByte)), "error") {
 				fullListNotCreatedIssue += displayErrorForIssue(vulnForJira, "api", err, jiraApiUrl, customDebug)
+				recordIssue(projectID, jsonVuln, outcomeFailed, fmt.Sprint(err), "")
+				defaultRunStatus.ticketFailed()
 				continue
 			}
 
+			if RequestFailed == true {
+				// the failures retried are already listed
+				if !retried {
+					fullListNotCreatedIssue += displayErrorForIssue(vulnForJira, "api", err, jiraApiUrl, customDebug)
+				}
+				recordIssue(projectID, jsonVuln, outcomeFailed, fmt.Sprint(err), "")
+				defaultRunStatus.ticketFailed()
+			}
+
 			if responseDataAggregatedByte != nil {
 				// increment the number of ticket created adn response
 				fullResponseDataAggregated += "\n" + string(responseDataAggregatedByte) + "\n"
 				issueCreated += 1
+				recordIssue(projectID, jsonVuln, outcomeCreated, "", ticketKey(ticket))
+				defaultStateStore.setContentHash(projectID, issueID, ticket.contentHash)
+				defaultRunStatus.ticketCreated()
 			}
+		} else {
+			recordIssue(projectID, jsonVuln, outcomeDryRun, "", "")
+			defaultRunStatus.ticketCreated()
 		}
 
 		// add only existing ticket to the array
@@ -315,19 +444,29 @@ func openJiraTickets(flags flags, projectInfo jsn.Json, vulnsForJira map[string]
 		}
 	}
 
-	// getting project ID and associate the ticket list to it
-	projectIdString := projectInfo.K("id").String().Value
-
+	// associate the ticket list to the project ID
 	project := make(map[string]interface{})
-	project[projectIdString] = ticketArray
+	project[projectID] = ticketArray
 
 	if fullResponseDataAggregated == "" && !flags.optionalFlags.dryRun {
 		message := fmt.Sprintf("*** ERROR *** Request response from %s is empty\n", flags.mandatoryFlags.endpointAPI)
 		writeErrorFile("openJiraTickets", message, customDebug)
-		customDebug.Debugf("*** ERROR *** Request response from %s is empty\n", flags.mandatoryFlags.endpointAPI)
+		customDebug.Error("Request response is empty", "endpoint", flags.mandatoryFlags.endpointAPI)
 	}
 
-	customDebug.Debugf("*** INFO *** %d issueCreated, fullResponseDataAggregated: %s", issueCreated, fullResponseDataAggregated)
+	customDebug.Debug("Tickets opened", "issueCreated", issueCreated)
+	customDebug.Trace("Jira responses", "responses", fullResponseDataAggregated)
 
 	return issueCreated, fullResponseDataAggregated, fullListNotCreatedIssue, project
 }
+
+/*
+**
+function isPayloadRefused
+input err error, the error of a ticket creation
+return bool, true when the payload was refused with a 400 or a 422, the request can be changed and sent again
+**
+*/
+func isPayloadRefused(err error) bool {
+	return errors.Is(err, ErrBadRequest) || errors.Is(err, ErrUnprocessable)
+}
@@ -558,3 +558,41 @@ func TestGetSnykCodeIssueWithoutTicketsWithPagination(t *testing.T) {
 	return
 
 }
+
+func TestGetSnykCodeIssueWithoutTicketsConcurrent(t *testing.T) {
+
+	os.Setenv("EXECUTION_ENVIRONMENT", "test")
+
+	assert := assert.New(t)
+
+	server := HTTPResponseCodeIssueStubAndMirrorRequest()
+
+	defer server.Close()
+
+	flags := flags{}
+	flags.mandatoryFlags.orgID = "xxx99a85-c519-xxxx-ae55-xxx9b9bfaxxx"
+	flags.mandatoryFlags.endpointAPI = server.URL
+	flags.mandatoryFlags.apiToken = "123"
+	flags.mandatoryFlags.jiraProjectID = "123"
+	flags.optionalFlags.severity = "low"
+	flags.optionalFlags.issueType = "all"
+	flags.optionalFlags.issueConcurrency = 4
+
+	cD := debug{}
+	cD.setDebug(false)
+
+	tickets := map[string]string{"xxbac5ed-83dd-xx65-8730-2xxx4467e0xx": "FPI-454"}
+
+	CreateLogFile(cD, "ErrorsFile_")
+
+	// same result as the sequential run
+	response, _ := getSnykCodeIssueWithoutTickets(flags, "1234", tickets, cD)
+
+	assert.Equal(4, len(response))
+	assert.NotEmpty(response["bbbbbbb-83dd-xx65-8730-2xxx4467e00q"])
+	assert.NotEmpty(response["xxbac5ed-83dd-xx65-8730-2xxx4467e00q"])
+	assert.NotEmpty(response["xxbac5ed-critical2-xx65-8730-2xxx4467e00q"])
+	assert.NotEmpty(response["xxbac5ed-critical1-xx65-8730-2xxx4467e00q"])
+
+	removeLogFile()
+}
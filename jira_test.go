@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/michael-go/go-jsn/jsn"
@@ -989,8 +990,10 @@ func TestAddMandatoryFieldToTicketCustomFieldSimpleField(t *testing.T) {
 	removeLogFile()
 }
 
-func TestOpenJiraTicketError50xAndRetryFunc(t *testing.T) {
+// after a 5xx the ticket may exist, it is not posted again without the priority
+func TestOpenJiraTicketError50xNotRetriedFunc(t *testing.T) {
 	assert := assert.New(t)
+	count = 0
 	server := HTTPResponseCheckOpenJiraTicketsWithError50x("/v1/org/123/project/12345678-1234-1234-1234-123456789012/issue/SNYK-JS-MINIMIST-559764/jira-issue")
 
 	defer server.Close()
@@ -1039,9 +1042,9 @@ func TestOpenJiraTicketError50xAndRetryFunc(t *testing.T) {
 	numberIssueCreated, jiraResponse, NotCreatedIssueId, tickets := openJiraTickets(flags, projectInfo, vulnsForJira, cD)
 
 	assert.NotNil(tickets)
-	assert.Equal("", NotCreatedIssueId)
-	assert.Equal(numberIssueCreated, 1)
-	assert.Equal(string(readFixture("./fixtures/results/jiraTicketsOpeningResults")), jiraResponse)
+	assert.Contains(NotCreatedIssueId, "SNYK-JS-MINIMIST-559764")
+	assert.Equal(0, numberIssueCreated)
+	assert.Equal("", jiraResponse)
 
 	removeLogFile()
 
@@ -1103,3 +1106,61 @@ func TestOpenJiraTicketWithDueDateFunc(t *testing.T) {
 
 	return
 }
+
+func TestOpenJiraTicketDryRunPayloadFunc(t *testing.T) {
+
+	assert := assert.New(t)
+	server := HTTPResponseStubAndMirrorRequest("/v1/org/123/project/12345678-1234-1234-1234-123456789012/issue/SNYK-JS-MINIMIST-559764/jira-issue", "", "")
+
+	defer server.Close()
+
+	projectInfo, _ := jsn.NewJson(readFixture("./fixtures/project.json"))
+	vulnsForJira := make(map[string]interface{})
+	err := json.Unmarshal(readFixture("./fixtures/vulnForJiraAggregatedWithPath.json"), &vulnsForJira)
+	if err != nil {
+		panic(err)
+	}
+
+	flags := flags{}
+	flags.mandatoryFlags = MandatoryFlags{orgID: "123", endpointAPI: server.URL, apiToken: "123", jiraProjectKey: "FPI"}
+	flags.optionalFlags = optionalFlags{jiraTicketType: "Bug", assigneeID: "12345", labels: "snyk,security", dueDate: "2024-07-01", priorityIsSeverity: true, dryRun: true}
+	flags.customMandatoryJiraFields = map[string]interface{}{"customfield_10601": "some value"}
+
+	cD := debug{}
+	CreateLogFile(cD, "ErrorsFile_")
+	defer removeLogFile()
+
+	_, _, _, projectsTickets := openJiraTickets(flags, projectInfo, vulnsForJira, cD)
+
+	tickets := projectsTickets[projectInfo.K("id").String().Value].([]Tickets)
+	assert.Equal(1, len(tickets))
+
+	ticket := tickets[0]
+	assert.Equal("SNYK-JS-MINIMIST-559764", ticket.IssueID)
+	assert.Equal("FPI", ticket.JiraProject)
+	assert.Equal(server.URL+"/v1/org/123/project/12345678-1234-1234-1234-123456789012/issue/SNYK-JS-MINIMIST-559764/jira-issue", ticket.Endpoint)
+
+	// the payload is the one that would be sent, with the mandatory fields
+	payload, _ := jsn.NewJson(ticket.Payload)
+	assert.Equal("FPI", payload.K("fields").K("project").K("key").String().Value)
+	assert.Equal("12345", payload.K("fields").K("assignee").K("accountId").String().Value)
+	assert.Equal("2024-07-01", payload.K("fields").K("duedate").String().Value)
+	assert.Equal("snyk", payload.K("fields").K("labels").I(0).String().Value)
+	assert.Equal("some value", payload.K("fields").K("customfield_10601").String().Value)
+	assert.NotEqual("", payload.K("fields").K("priority").K("name").String().Value)
+
+	preview := formatTicketPreview(ticket)
+	lines := strings.Split(strings.TrimSpace(preview), "\n")
+	assert.Equal("+ SNYK-JS-MINIMIST-559764 => Jira project FPI", lines[0])
+	assert.Equal("+ POST "+ticket.Endpoint, lines[1])
+	assert.Contains(preview, "+   fields.assignee.accountId: \"12345\"\n")
+	assert.Contains(preview, "+   fields.labels[1]: \"security\"\n")
+	assert.Contains(preview, "+   fields.description: |\n")
+	assert.Contains(preview, "+   fields.customfield_10601: \"some value\"\n")
+	for _, line := range lines {
+		assert.True(strings.HasPrefix(line, "+ "), line)
+	}
+
+	assert.Equal("\n"+preview, formatProjectPreview(tickets))
+	assert.Equal("", formatProjectPreview(nil))
+}
@@ -32,6 +32,14 @@ type Tickets struct {
 	Summary         string               `json:Summary`
 	Description     string               `json:Description`
 	JiraIssueDetail *JiraDetailForTicket `json:JiraIssueDetail,omitempty"`
+	// set in dry run, what would be sent to the Snyk API
+	IssueID     string          `json:"IssueId,omitempty"`
+	JiraProject string          `json:"JiraProject,omitempty"`
+	Endpoint    string          `json:"Endpoint,omitempty"`
+	Payload     json.RawMessage `json:"Payload,omitempty"`
+	Tracker     string          `json:"Tracker,omitempty"`
+	// hash of the payload kept in the state store, not logged
+	contentHash string
 }
 
 type LogFile struct {
@@ -63,29 +71,52 @@ func getJiraTicketId(responseData []byte) *JiraDetailForTicket {
 	return jiraIssueDetails
 }
 
-func formatJiraTicket(jsonVuln jsn.Json, projectInfo jsn.Json, flags flags) *JiraIssue {
+// ticketKey is the Jira key of a created ticket, empty when unknown
+func ticketKey(ticket *Tickets) string {
+	if ticket == nil || ticket.JiraIssueDetail == nil || ticket.JiraIssueDetail.JiraIssue == nil {
+		return ""
+	}
+	return ticket.JiraIssueDetail.JiraIssue.Key
+}
 
-	issueData := jsonVuln.K("issueData")
+/*
+**
+function formatTicketContent
+input jsonVuln jsn.Json, vulnerability, license or code issue
+input projectInfo jsn.Json
+input flags
+return ticketContent, tracker-neutral title and markdown body
+**
+*/
+func formatTicketContent(jsonVuln jsn.Json, projectInfo jsn.Json, flags flags) ticketContent {
 
-	paths := "\n**Impacted Paths:**\n"
+	if jsonVuln.K("data").K("attributes").K("issueType").String().Value == "code" {
+		return formatCodeContent(jsonVuln, projectInfo, flags)
+	} else if isLicenseIssue(jsonVuln) {
+		return formatLicenseContent(jsonVuln, projectInfo, flags)
+	}
 
-	for count, e := range jsonVuln.K("from").Array().Elements() {
+	return formatVulnContent(jsonVuln, projectInfo, flags)
+}
 
-		newPathArray := make([]string, len(e.Array().Elements()))
+func formatJiraTicket(jsonVuln jsn.Json, projectInfo jsn.Json, flags flags) *JiraIssue {
+	return renderJiraIssue(formatVulnContent(jsonVuln, projectInfo, flags))
+}
 
-		for count_, j := range e.Array().Elements() {
-			name := fmt.Sprintf("%s@%s", j.K("name").Stringify(), j.K("version").Stringify())
-			newPathArray[count_] = name
-		}
+/*
+**
+function formatVulnContent
+input jsonVuln jsn.Json, vulnerability with its paths
+input projectInfo jsn.Json
+input flags
+return ticketContent, title and markdown body of the ticket
+**
+*/
+func formatVulnContent(jsonVuln jsn.Json, projectInfo jsn.Json, flags flags) ticketContent {
 
-		paths += "- " + strings.Join(newPathArray, " => ") + "\n"
+	issueData := jsonVuln.K("issueData")
 
-		if count > 10 {
-			paths += "- ... [" + fmt.Sprintf("%d", len(jsonVuln.K("from").Array().Elements())-count) + " more paths](" + projectInfo.K("browseUrl").String().Value + ")"
-			break
-		}
-		paths += "\r"
-	}
+	paths := formatPaths(jsonVuln, projectInfo, "\n**Impacted Paths:**\n")
 
 	var pkgVersionsArray []string
 	// jsonVuln.K("pkgVersions").Array().Elements() is []jsn.json
@@ -101,12 +132,7 @@ func formatJiraTicket(jsonVuln jsn.Json, projectInfo jsn.Json, flags flags) *Jir
 	pkgVersions += "[" + strings.Join(pkgVersionsArray, ", ")
 	pkgVersions += "]\n\r"
 
-	descriptionFromIssue := ""
-
-	if issueData.K("type").String().Value == "license" {
-		descriptionFromIssue = `This dependency is infringing your organization license policy.
-								Refer to the Reporting tab for possible instructions from your legal team.`
-	}
+	howToFix := formatRemediation(jsonVuln)
 
 	var identifiers []string
 	var cveIdentifiers []string
@@ -133,36 +159,297 @@ func formatJiraTicket(jsonVuln jsn.Json, projectInfo jsn.Json, flags flags) *Jir
 		"\n severity: ", issueData.K("severity").String().Value,
 		pkgVersions,
 		paths,
+		howToFix,
 		snykBreadcrumbs,
-		descriptionFromIssue,
 		moreAboutThisIssue,
 	}
 
-	descriptionBody := markdownToConfluenceWiki(strings.Join(issueDetails, " "))
-	descriptionBody = strings.ReplaceAll(descriptionBody, "{{", "{code}")
-	descriptionBody = strings.ReplaceAll(descriptionBody, "}}", "{code}")
-
-	// Sanitizing known issue where JIRA FW doesn't like this string....
-	descriptionBody = strings.ReplaceAll(descriptionBody, "/etc/passwd", "")
+	body := strings.Join(issueDetails, " ")
 
 	// Build Summary
 	summary := projectInfo.K("name").String().Value + " - " + issueData.K("title").String().Value
 
-	// Sanitizing subject to prevent Path Traversal protection failure in Web Application Firewall
-	summary = strings.ReplaceAll(summary, "/bin/", "_bin_")
-
 	if flags.optionalFlags.cveInTitle == true && len(cveIdentifiers) > 0 {
 		summary = fmt.Sprintf("%s - %s", summary, strings.Join(cveIdentifiers, ", "))
 	}
 
-	jiraTicket := &JiraIssue{
-		Field{
-			Summary:     summary,
-			Description: descriptionBody,
-		},
+	return ticketContent{
+		IssueID:   jsonVuln.K("id").String().Value,
+		IssueType: "vuln",
+		Severity:  issueData.K("severity").String().Value,
+		Title:     summary,
+		Body:      body,
+	}
+}
+
+/*
+**
+function formatPaths
+input jsonVuln jsn.Json, open source issue with the paths added under "from"
+input projectInfo jsn.Json, used to link to the remaining paths
+input header string, title of the section
+return string, markdown list of the paths, truncated after a dozen
+**
+*/
+func formatPaths(jsonVuln jsn.Json, projectInfo jsn.Json, header string) string {
+
+	paths := header
+
+	for count, e := range jsonVuln.K("from").Array().Elements() {
+
+		newPathArray := make([]string, len(e.Array().Elements()))
+
+		for count_, j := range e.Array().Elements() {
+			name := fmt.Sprintf("%s@%s", j.K("name").Stringify(), j.K("version").Stringify())
+			newPathArray[count_] = name
+		}
+
+		paths += "- " + strings.Join(newPathArray, " => ") + "\n"
+
+		if count > 10 {
+			paths += "- ... [" + fmt.Sprintf("%d", len(jsonVuln.K("from").Array().Elements())-count) + " more paths](" + projectInfo.K("browseUrl").String().Value + ")"
+			break
+		}
+		paths += "\r"
+	}
+
+	return paths
+}
+
+/*
+**
+function isLicenseIssue
+input jsonVuln jsn.Json, open source issue from aggregated-issues
+return bool, true for license policy issues
+**
+*/
+func isLicenseIssue(jsonVuln jsn.Json) bool {
+	return jsonVuln.K("issueType").String().Value == "license" || jsonVuln.K("issueData").K("type").String().Value == "license"
+}
+
+/*
+**
+function getLicenses
+input jsonVuln jsn.Json, license issue from aggregated-issues
+return []string, license identifiers of the issue
+The identifier is taken from the issue data when present, otherwise from
+the issue ID which ends with it (snyk:lic:npm:goof:GPL-2.0)
+**
+*/
+func getLicenses(jsonVuln jsn.Json) []string {
+
+	var licenses []string
+
+	issueData := jsonVuln.K("issueData")
+	for _, e := range issueData.K("licenses").Array().Elements() {
+		licenses = append(licenses, e.String().Value)
+	}
+	if len(licenses) > 0 {
+		return licenses
+	}
+
+	license := issueData.K("license").String().Value
+	if len(license) == 0 {
+		issueID := jsonVuln.K("id").String().Value
+		license = issueID[strings.LastIndex(issueID, ":")+1:]
+	}
+	if len(license) > 0 {
+		licenses = append(licenses, license)
+	}
+
+	return licenses
+}
+
+/*
+**
+function getLegalInstructions
+input jsonVuln jsn.Json, license issue from aggregated-issues
+return string, instructions configured in the Snyk license policy
+**
+*/
+func getLegalInstructions(jsonVuln jsn.Json) string {
+
+	issueData := jsonVuln.K("issueData")
+
+	var instructions []string
+	for _, e := range issueData.K("legalInstructionsArray").Array().Elements() {
+		content := e.K("legalContent").String().Value
+		if len(content) == 0 {
+			continue
+		}
+		if licenseName := e.K("licenseName").String().Value; len(licenseName) > 0 {
+			content = licenseName + ": " + content
+		}
+		instructions = append(instructions, content)
 	}
+	if len(instructions) > 0 {
+		return strings.Join(instructions, "\n\n")
+	}
+
+	return issueData.K("legalInstructions").String().Value
+}
 
-	return jiraTicket
+/*
+**
+function formatLicenseJiraTicket
+input jsonVuln jsn.Json, license issue with its paths
+input projectInfo jsn.Json
+input flags
+return *JiraIssue, ticket with summary and description
+License tickets list the license identifiers, the policy severity, the legal
+instructions from the license policy and the dependent paths
+**
+*/
+func formatLicenseJiraTicket(jsonVuln jsn.Json, projectInfo jsn.Json, flags flags) *JiraIssue {
+	return renderJiraIssue(formatLicenseContent(jsonVuln, projectInfo, flags))
+}
+
+/*
+**
+function formatLicenseContent
+input jsonVuln jsn.Json, license issue with its paths
+input projectInfo jsn.Json
+input flags
+return ticketContent, title and markdown body of the ticket
+**
+*/
+func formatLicenseContent(jsonVuln jsn.Json, projectInfo jsn.Json, flags flags) ticketContent {
+
+	issueData := jsonVuln.K("issueData")
+
+	licenses := getLicenses(jsonVuln)
+	if len(licenses) == 0 {
+		licenses = append(licenses, "N/A")
+	}
+
+	var pkgVersionsArray []string
+	for _, e := range jsonVuln.K("pkgVersions").Array().Elements() {
+		pkgVersionsArray = append(pkgVersionsArray, e.String().Value)
+	}
+
+	legalInstructions := getLegalInstructions(jsonVuln)
+	if len(legalInstructions) == 0 {
+		legalInstructions = "No instructions configured in the license policy."
+	}
+
+	paths := formatPaths(jsonVuln, projectInfo, "\n**Dependent Paths:**\n")
+
+	snykBreadcrumbs := "\n\n[See this issue on Snyk](" + projectInfo.K("browseUrl").String().Value + ")\n"
+	moreAboutThisIssue := "\n\n[More About this issue](" + issueData.K("url").String().Value + ")\n"
+
+	issueDetails := []string{"\r\n** License issue details: **\n\r",
+		"\n license: ", strings.Join(licenses, ", "),
+		"\n policy severity: ", issueData.K("severity").String().Value,
+		"\n package: ", jsonVuln.K("pkgName").String().Value,
+		"\n pkgVersions: [" + strings.Join(pkgVersionsArray, ", ") + "]\n\r",
+		"\n**Legal instructions:**\n\n", legalInstructions, "\n",
+		paths,
+		snykBreadcrumbs,
+		moreAboutThisIssue,
+	}
+
+	body := strings.Join(issueDetails, " ")
+
+	summary := projectInfo.K("name").String().Value + " - " + issueData.K("title").String().Value
+
+	return ticketContent{
+		IssueID:   jsonVuln.K("id").String().Value,
+		IssueType: "license",
+		Severity:  issueData.K("severity").String().Value,
+		Title:     summary,
+		Body:      body,
+	}
+}
+
+/*
+**
+function formatRemediation
+input jsonVuln jsn.Json, open source issue with its fixInfo
+return string, markdown "How to fix" section
+List the minimum fixed versions, the direct dependency upgrade needed for each
+upgrade path and whether a Snyk patch exists
+**
+*/
+func formatRemediation(jsonVuln jsn.Json) string {
+
+	fixInfo := jsonVuln.K("fixInfo")
+
+	howToFix := "\n**How to fix:**\n"
+
+	var fixedInArray []string
+	for _, e := range fixInfo.K("fixedIn").Array().Elements() {
+		fixedInArray = append(fixedInArray, e.String().Value)
+	}
+
+	if len(fixedInArray) > 0 {
+		howToFix += "- Fixed in: " + jsonVuln.K("pkgName").String().Value + "@" + strings.Join(fixedInArray, ", ") + "\n"
+	} else {
+		howToFix += "- No fixed version available\n"
+	}
+
+	if fixInfo.K("isUpgradable").Bool().Value {
+		upgrades := []string{}
+		for _, upgradePath := range fixInfo.K("upgradePaths").Array().Elements() {
+			upgrade := directDependencyUpgrade(upgradePath.K("path"))
+			if len(upgrade) > 0 && !containsString(upgrades, upgrade) {
+				upgrades = append(upgrades, upgrade)
+			}
+		}
+
+		if len(upgrades) == 0 {
+			howToFix += "- Upgradable: yes\n"
+		}
+		for _, upgrade := range upgrades {
+			howToFix += "- " + upgrade + "\n"
+		}
+	} else {
+		howToFix += "- Upgradable: no\n"
+	}
+
+	if fixInfo.K("isPatchable").Bool().Value {
+		howToFix += "- Snyk patch available\n"
+	} else {
+		howToFix += "- No Snyk patch available\n"
+	}
+
+	return howToFix
+}
+
+/*
+**
+function directDependencyUpgrade
+input path jsn.Json, upgrade path from fixInfo, the first element is the project itself
+return string, the change to apply on the direct dependency, empty if none is needed
+**
+*/
+func directDependencyUpgrade(path jsn.Json) string {
+
+	for count, e := range path.Array().Elements() {
+		// skipping the project itself
+		if count == 0 {
+			continue
+		}
+
+		if e.K("isDropped").Bool().Value {
+			return fmt.Sprintf("Remove %s@%s, it is dropped by the fix", e.K("name").String().Value, e.K("version").String().Value)
+		}
+
+		newVersion := e.K("newVersion").String().Value
+		if len(newVersion) > 0 {
+			return fmt.Sprintf("Upgrade %s@%s to %s@%s", e.K("name").String().Value, e.K("version").String().Value, e.K("name").String().Value, newVersion)
+		}
+	}
+
+	return ""
+}
+
+func containsString(list []string, value string) bool {
+	for _, e := range list {
+		if e == value {
+			return true
+		}
+	}
+	return false
 }
 
 func markdownToConfluenceWiki(textToConvert string) string {
@@ -174,7 +461,48 @@ func markdownToConfluenceWiki(textToConvert string) string {
 	return string(output)
 }
 
+/*
+**
+function renderJiraIssue
+input content ticketContent
+return *JiraIssue, ticket with summary and a description in Confluence wiki markup
+The markdown is converted and the strings refused by the Jira firewall are removed
+**
+*/
+func renderJiraIssue(content ticketContent) *JiraIssue {
+
+	descriptionBody := markdownToConfluenceWiki(content.Body)
+	descriptionBody = strings.ReplaceAll(descriptionBody, "{{", "{code}")
+	descriptionBody = strings.ReplaceAll(descriptionBody, "}}", "{code}")
+
+	// Sanitizing known issue where JIRA FW doesn't like this string....
+	descriptionBody = strings.ReplaceAll(descriptionBody, "/etc/passwd", "")
+
+	// Sanitizing subject to prevent Path Traversal protection failure in Web Application Firewall
+	summary := strings.ReplaceAll(content.Title, "/bin/", "_bin_")
+
+	return &JiraIssue{
+		Field{
+			Summary:     summary,
+			Description: descriptionBody,
+		},
+	}
+}
+
 func formatCodeJiraTicket(jsonVuln jsn.Json, projectInfo jsn.Json, flags flags) *JiraIssue {
+	return renderJiraIssue(formatCodeContent(jsonVuln, projectInfo, flags))
+}
+
+/*
+**
+function formatCodeContent
+input jsonVuln jsn.Json, code issue
+input projectInfo jsn.Json
+input flags
+return ticketContent, title and markdown body of the ticket
+**
+*/
+func formatCodeContent(jsonVuln jsn.Json, projectInfo jsn.Json, flags flags) ticketContent {
 
 	issueData := jsonVuln.K("data")
 
@@ -205,22 +533,16 @@ func formatCodeJiraTicket(jsonVuln jsn.Json, projectInfo jsn.Json, flags flags)
 		snykBreadcrumbs,
 	}
 
-	descriptionBody := markdownToConfluenceWiki(strings.Join(issueDetails, " "))
-	descriptionBody = strings.ReplaceAll(descriptionBody, "{{", "{code}")
-	descriptionBody = strings.ReplaceAll(descriptionBody, "}}", "{code}")
-
-	// Sanitizing known issue where JIRA FW doesn't like this string....
-	descriptionBody = strings.ReplaceAll(descriptionBody, "/etc/passwd", "")
+	body := strings.Join(issueDetails, " ")
 	summary := projectInfo.K("name").String().Value + " - " + jsonVuln.K("title").String().Value
 	// TODO: add CVE in title once API sends it
-	jiraTicket := &JiraIssue{
-		Field{
-			Summary:     summary,
-			Description: descriptionBody,
-		},
+	return ticketContent{
+		IssueID:   issueData.K("id").String().Value,
+		IssueType: "code",
+		Severity:  issueData.K("attributes").K("severity").String().Value,
+		Title:     summary,
+		Body:      body,
 	}
-
-	return jiraTicket
 }
 
 /*
@@ -243,7 +565,7 @@ func addMandatoryFieldToTicket(ticket []byte, customMandatoryField map[string]in
 	if err != nil {
 		message := fmt.Sprintf("*** ERROR *** Could not unMarshalled ticket, mandatory fields will no the added %s", err.Error())
 		writeErrorFile("addMandatoryFieldToTicket", message, customDebug)
-		customDebug.Debug("*** ERROR *** Could not unMarshalled ticket, mandatory fields will no the added ", err)
+		customDebug.Error("Could not unMarshalled ticket, mandatory fields will not be added", "error", err)
 	}
 
 	fieldFromTicket := unmarshalledTicket["fields"]
@@ -252,12 +574,12 @@ func addMandatoryFieldToTicket(ticket []byte, customMandatoryField map[string]in
 	if err != nil {
 		message := fmt.Sprintf("*** ERROR *** Could not parse Jira fields config, mandatory fields will no the added %s", err.Error())
 		writeErrorFile("addMandatoryFieldToTicket", message, customDebug)
-		customDebug.Debug(message, err)
+		customDebug.Error("Could not parse Jira fields config, mandatory fields will not be added", "error", err)
 	}
 
 	err = json.Unmarshal(marshalledFieldFromTicket, &fields)
 	if err != nil {
-		customDebug.Debug("*** ERROR *** Could not Jira fields config, mandatory fields will no the added ", err)
+		customDebug.Error("Could not read Jira fields config, mandatory fields will not be added", "error", err)
 	}
 
 	for i, s := range customMandatoryField {
@@ -271,7 +593,7 @@ func addMandatoryFieldToTicket(ticket []byte, customMandatoryField map[string]in
 				}
 			}
 		} else {
-			customDebug.Debug(fmt.Sprintf("*** ERROR *** Expected mandatory Jira fields configuration to be in format map[string]interface{}, received type: %T for field %s ", s, i))
+			customDebug.Error("Expected mandatory Jira fields configuration to be in format map[string]interface{}", "field", i, "type", fmt.Sprintf("%T", s))
 			message := fmt.Sprintf("*** ERROR *** Expected mandatory Jira fields configuration to be in format map[string]interface{}, received type: %T for field %s ", s, i)
 			writeErrorFile("addMandatoryFieldToTicket", message, customDebug)
 		}
@@ -283,7 +605,7 @@ func addMandatoryFieldToTicket(ticket []byte, customMandatoryField map[string]in
 
 	newMarshalledTicket, err := json.Marshal(newTicket)
 	if err != nil {
-		customDebug.Debug("*** ERROR *** Invalid JSON, mandatory Jira fields will be skipped. ERROR:", err)
+		customDebug.Error("Invalid JSON, mandatory Jira fields will be skipped", "error", err)
 		message := fmt.Sprintf("*** ERROR *** Invalid JSON, mandatory Jira fields will be skipped. ERROR: %s", err.Error())
 		writeErrorFile("addMandatoryFieldToTicket", message, customDebug)
 	}
@@ -364,9 +686,93 @@ func supportJiraFormats(v string, customDebug debug) (result interface{}, err er
 		return nil, errors.New("Custom field format not recognized, please check the config file.")
 	}
 
-	if customDebug.PrintDebug {
-		customDebug.Debug(fmt.Sprintf("*** INFO *** Custom field value '%s' replaced with '%s' ", v, result))
-	}
+	customDebug.Debug("Custom field value replaced", "value", v, "result", result)
 
 	return result, nil
 }
+
+/*
+**
+function formatTicketPreview
+input ticket Tickets, ticket of the dry run with its payload
+return string, the request that would be sent, one line per field of the payload prefixed with +
+**
+*/
+func formatTicketPreview(ticket Tickets) string {
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "+ %s => Jira project %s\n", ticket.IssueID, ticket.JiraProject)
+	fmt.Fprintf(&builder, "+ POST %s\n", ticket.Endpoint)
+
+	var payload interface{}
+	if err := json.Unmarshal(ticket.Payload, &payload); err != nil {
+		fmt.Fprintf(&builder, "+   %s\n", string(ticket.Payload))
+		return builder.String()
+	}
+
+	fields := make(map[string]interface{})
+	flattenPayload("", payload, fields)
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		text, isText := fields[key].(string)
+		if isText && strings.Contains(text, "\n") {
+			fmt.Fprintf(&builder, "+   %s: |\n", key)
+			for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+				fmt.Fprintf(&builder, "+       %s\n", strings.TrimRight(line, "\r"))
+			}
+			continue
+		}
+		value, _ := json.Marshal(fields[key])
+		fmt.Fprintf(&builder, "+   %s: %s\n", key, value)
+	}
+
+	return builder.String()
+}
+
+// flattenPayload lists the leaves of the payload with their path, like fields.priority.name
+func flattenPayload(prefix string, value interface{}, fields map[string]interface{}) {
+
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		for key, child := range typedValue {
+			path := key
+			if prefix != "" {
+				path = prefix + "." + key
+			}
+			flattenPayload(path, child, fields)
+		}
+	case []interface{}:
+		for index, child := range typedValue {
+			flattenPayload(fmt.Sprintf("%s[%d]", prefix, index), child, fields)
+		}
+	default:
+		fields[prefix] = value
+	}
+}
+
+/*
+**
+function formatProjectPreview
+input tickets interface{}, the []Tickets of a project returned by openJiraTickets
+return string, the preview of every ticket of the project, empty when there is none
+**
+*/
+func formatProjectPreview(tickets interface{}) string {
+
+	ticketList, _ := tickets.([]Tickets)
+
+	preview := ""
+	for _, ticket := range ticketList {
+		if len(ticket.Payload) > 0 {
+			preview += "\n" + formatTicketPreview(ticket)
+		}
+	}
+
+	return preview
+}
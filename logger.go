@@ -0,0 +1,349 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type logLevel int
+
+// levels from the most to the least important
+const (
+	levelError logLevel = iota
+	levelWarn
+	levelInfo
+	levelDebug
+	levelTrace
+)
+
+var logLevelNames = []string{"error", "warn", "info", "debug", "trace"}
+
+func (l logLevel) String() string {
+	if l < levelError || l > levelTrace {
+		return "unknown"
+	}
+	return logLevelNames[l]
+}
+
+/*
+**
+function parseLogLevel
+input value string, error|warn|info|debug|trace
+return logLevel
+return error, when the level is unknown
+**
+*/
+func parseLogLevel(value string) (logLevel, error) {
+
+	for index, name := range logLevelNames {
+		if strings.EqualFold(value, name) {
+			return logLevel(index), nil
+		}
+	}
+
+	return levelInfo, fmt.Errorf("%s is not a valid log level. Must be one of %s", value, strings.Join(logLevelNames, ", "))
+}
+
+// logSink is where the entries of every logger end up
+type logSink struct {
+	mu     sync.Mutex
+	writer io.Writer
+	format string
+	level  logLevel
+	now    func() time.Time
+}
+
+var defaultLogSink = &logSink{
+	writer: os.Stderr,
+	format: "text",
+	level:  levelInfo,
+	now:    time.Now,
+}
+
+/*
+**
+function checkLogOptions
+input format string, text or json
+input level string, error|warn|info|debug|trace
+return error, when the format or the level is not valid
+**
+*/
+func checkLogOptions(format string, level string) error {
+
+	if format != "text" && format != "json" {
+		return fmt.Errorf("%s is not a valid log format. Must be text or json", format)
+	}
+
+	_, err := parseLogLevel(level)
+	return err
+}
+
+/*
+**
+function configureLogging
+input format string, text or json
+input level string, error|warn|info|debug|trace
+input debugMode bool, --debug enables at least the debug level
+return error, when the format or the level is not valid
+**
+*/
+func configureLogging(format string, level string, debugMode bool) error {
+
+	if err := checkLogOptions(format, level); err != nil {
+		return err
+	}
+
+	parsedLevel, _ := parseLogLevel(level)
+	if debugMode && parsedLevel < levelDebug {
+		parsedLevel = levelDebug
+	}
+
+	defaultLogSink.mu.Lock()
+	defer defaultLogSink.mu.Unlock()
+	defaultLogSink.format = format
+	defaultLogSink.level = parsedLevel
+
+	return nil
+}
+
+// logEntry is one line of log
+type logEntry struct {
+	time   time.Time
+	level  logLevel
+	msg    string
+	fields []interface{}
+}
+
+// logBuffer keeps the entries of a task running in parallel so they can be
+// written in a deterministic order
+type logBuffer struct {
+	mu      sync.Mutex
+	entries []logEntry
+}
+
+func (b *logBuffer) add(entry logEntry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.entries = append(b.entries, entry)
+}
+
+// Flush writes the buffered entries to the sink and empties the buffer
+func (b *logBuffer) Flush() {
+
+	b.mu.Lock()
+	entries := b.entries
+	b.entries = nil
+	b.mu.Unlock()
+
+	for _, entry := range entries {
+		defaultLogSink.write(entry)
+	}
+}
+
+func (s *logSink) write(entry logEntry) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.format == "json" {
+		line := map[string]interface{}{}
+		for index := 0; index+1 < len(entry.fields); index += 2 {
+			line[fmt.Sprint(entry.fields[index])] = jsonValue(entry.fields[index+1])
+		}
+		line["time"] = entry.time.UTC().Format(time.RFC3339)
+		line["level"] = entry.level.String()
+		line["msg"] = entry.msg
+
+		data, err := json.Marshal(line)
+		if err != nil {
+			data, _ = json.Marshal(map[string]string{"time": line["time"].(string), "level": entry.level.String(), "msg": entry.msg})
+		}
+		s.writer.Write(append(data, '\n'))
+		return
+	}
+
+	var builder strings.Builder
+	builder.WriteString(entry.time.Format("2006/01/02 15:04:05"))
+	builder.WriteString(" *** ")
+	builder.WriteString(strings.ToUpper(entry.level.String()))
+	builder.WriteString(" *** ")
+	builder.WriteString(entry.msg)
+	for index := 0; index+1 < len(entry.fields); index += 2 {
+		builder.WriteString(" ")
+		builder.WriteString(fmt.Sprint(entry.fields[index]))
+		builder.WriteString("=")
+		builder.WriteString(textValue(entry.fields[index+1]))
+	}
+	builder.WriteString("\n")
+
+	io.WriteString(s.writer, builder.String())
+}
+
+// errors are not marshalled by encoding/json
+func jsonValue(value interface{}) interface{} {
+	switch typedValue := value.(type) {
+	case error:
+		return typedValue.Error()
+	case fmt.Stringer:
+		return typedValue.String()
+	}
+	return value
+}
+
+func textValue(value interface{}) string {
+
+	var text string
+	switch typedValue := value.(type) {
+	case map[string]string:
+		// sorted so the lines are stable
+		keys := make([]string, 0, len(typedValue))
+		for key := range typedValue {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		pairs := make([]string, 0, len(keys))
+		for _, key := range keys {
+			pairs = append(pairs, key+":"+typedValue[key])
+		}
+		text = strings.Join(pairs, ",")
+	default:
+		text = fmt.Sprint(value)
+	}
+
+	if text == "" || strings.ContainsAny(text, " =\"\n\t") {
+		return strconv.Quote(text)
+	}
+	return text
+}
+
+/*
+**
+Function With
+input keysAndValues ...interface{}, fields added to every entry of the returned logger
+return debug, a copy of the logger with the fields
+**
+*/
+func (m debug) With(keysAndValues ...interface{}) debug {
+
+	fields := make([]interface{}, 0, len(m.fields)+len(keysAndValues))
+	fields = append(fields, m.fields...)
+	fields = append(fields, keysAndValues...)
+	m.fields = fields
+
+	return m
+}
+
+/*
+**
+Function Buffered
+input buffer *logBuffer
+return debug, a copy of the logger writing in the buffer instead of the sink
+**
+*/
+func (m debug) Buffered(buffer *logBuffer) debug {
+	m.buffer = buffer
+	return m
+}
+
+func (m *debug) enabled(level logLevel) bool {
+	defaultLogSink.mu.Lock()
+	sinkLevel := defaultLogSink.level
+	defaultLogSink.mu.Unlock()
+
+	return level <= sinkLevel || (m.PrintDebug && level <= levelDebug)
+}
+
+func (m *debug) log(level logLevel, msg string, keysAndValues []interface{}) {
+
+	if !m.enabled(level) {
+		return
+	}
+
+	fields := make([]interface{}, 0, len(m.fields)+len(keysAndValues))
+	fields = append(fields, m.fields...)
+	fields = append(fields, keysAndValues...)
+
+	entry := logEntry{time: defaultLogSink.now(), level: level, msg: msg, fields: fields}
+
+	if m.buffer != nil {
+		m.buffer.add(entry)
+		return
+	}
+
+	defaultLogSink.write(entry)
+}
+
+/*
+**
+Functions Error, Warn, Info, Debug and Trace
+input msg string, what happened
+input keysAndValues ...interface{}, pairs of field name and value, like "project", projectID
+**
+*/
+func (m *debug) Error(msg string, keysAndValues ...interface{}) {
+	m.log(levelError, msg, keysAndValues)
+}
+
+func (m *debug) Warn(msg string, keysAndValues ...interface{}) {
+	m.log(levelWarn, msg, keysAndValues)
+}
+
+func (m *debug) Info(msg string, keysAndValues ...interface{}) {
+	m.log(levelInfo, msg, keysAndValues)
+}
+
+func (m *debug) Debug(msg string, keysAndValues ...interface{}) {
+	m.log(levelDebug, msg, keysAndValues)
+}
+
+func (m *debug) Trace(msg string, keysAndValues ...interface{}) {
+	m.log(levelTrace, msg, keysAndValues)
+}
+
+/*
+**
+Function Fatal
+Log an error and exit with exitError, the buffered entries are written first
+**
+*/
+func (m *debug) Fatal(msg string, keysAndValues ...interface{}) {
+	m.FatalWithCode(exitError, msg, keysAndValues...)
+}
+
+/*
+**
+Function FatalWithCode
+input code int, exit code, like exitConfigError
+Log an error and exit, the buffered entries are written first
+**
+*/
+func (m *debug) FatalWithCode(code int, msg string, keysAndValues ...interface{}) {
+
+	m.log(levelError, msg, append(keysAndValues, "exitCode", code))
+	if m.buffer != nil {
+		m.buffer.Flush()
+	}
+
+	if recoverFatal {
+		panic(fatalExit{code: code})
+	}
+	os.Exit(code)
+}
+
+// fatalExit stops a sync run by serve or daemon instead of exiting, see runSync
+type fatalExit struct {
+	code int
+}
+
+// recoverFatal is set by the commands running several syncs in the same process
+var recoverFatal bool
+
+// logger is used where no logger is passed, like the flags and config file checks
+var logger = debug{}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// captureLogs sends the log entries to a buffer until the returned function is called
+func captureLogs(format string, level string) (*bytes.Buffer, func()) {
+
+	output := &bytes.Buffer{}
+	oldWriter, oldFormat, oldLevel, oldNow := defaultLogSink.writer, defaultLogSink.format, defaultLogSink.level, defaultLogSink.now
+
+	configureLogging(format, level, false)
+	defaultLogSink.writer = output
+	defaultLogSink.now = func() time.Time {
+		return time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	}
+
+	return output, func() {
+		defaultLogSink.writer, defaultLogSink.format, defaultLogSink.level, defaultLogSink.now = oldWriter, oldFormat, oldLevel, oldNow
+	}
+}
+
+func TestLoggerJSONFormatFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	output, restore := captureLogs("json", "info")
+	defer restore()
+
+	customDebug := debug{}
+	projectLogger := customDebug.With("org", "123", "project", "abc")
+	projectLogger.Error("Request failed", "endpoint", "/v1/org/123", "error", errors.New("boom"), "attempts", 6)
+
+	line := map[string]interface{}{}
+	assert.Nil(json.Unmarshal(output.Bytes(), &line))
+	assert.Equal(map[string]interface{}{
+		"time":     "2024-06-01T12:00:00Z",
+		"level":    "error",
+		"msg":      "Request failed",
+		"org":      "123",
+		"project":  "abc",
+		"endpoint": "/v1/org/123",
+		"error":    "boom",
+		"attempts": float64(6),
+	}, line)
+}
+
+func TestLoggerTextFormatFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	output, restore := captureLogs("text", "info")
+	defer restore()
+
+	logger.Warn("Could not use the cache directory", "cacheDir", "/tmp/my cache", "delay", 2*time.Second)
+
+	assert.Equal(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC).Local().Format("2006/01/02 15:04:05")+
+		" *** WARN *** Could not use the cache directory cacheDir=\"/tmp/my cache\" delay=2s\n", output.String())
+}
+
+func TestLoggerLevelsFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	output, restore := captureLogs("text", "warn")
+	defer restore()
+
+	customDebug := debug{}
+	customDebug.Info("not logged")
+	customDebug.Debug("not logged")
+	customDebug.Warn("logged")
+	assert.Equal(1, strings.Count(output.String(), "\n"))
+
+	// --debug shows the debug entries but not the trace ones
+	output.Reset()
+	customDebug.setDebug(true)
+	customDebug.Debug("logged")
+	customDebug.Trace("not logged")
+	assert.Equal(1, strings.Count(output.String(), "\n"))
+
+	output.Reset()
+	configureLogging("text", "trace", false)
+	customDebug.Trace("logged")
+	assert.Contains(output.String(), "*** TRACE *** logged")
+
+	assert.NotNil(configureLogging("xml", "info", false))
+	assert.NotNil(configureLogging("text", "verbose", false))
+
+	_, err := parseLogLevel("DEBUG")
+	assert.Nil(err)
+}
+
+func TestLoggerBufferedFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	output, restore := captureLogs("text", "info")
+	defer restore()
+
+	buffer := &logBuffer{}
+	customDebug := debug{}
+	projectLogger := customDebug.Buffered(buffer).With("project", "abc")
+
+	projectLogger.Info("Step 1/4 - Retrieving project")
+	logger.Info("Not buffered")
+	projectLogger.Info("Step 2/4 - Retrieving a list of existing Jira tickets")
+
+	assert.Equal(1, strings.Count(output.String(), "\n"))
+
+	buffer.Flush()
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	assert.Equal(3, len(lines))
+	assert.Contains(lines[0], "Not buffered")
+	assert.Contains(lines[1], "Step 1/4 - Retrieving project project=abc")
+	assert.Contains(lines[2], "Step 2/4 - Retrieving a list of existing Jira tickets project=abc")
+
+	// the copies don't share their fields
+	assert.Equal(0, len(customDebug.fields))
+}
@@ -2,121 +2,531 @@ package main
 
 import (
 	"fmt"
-	"log"
+	"net/http"
 	"os"
+	"sort"
 	"strings"
+	"time"
 )
 
 func main() {
+	os.Exit(run())
+}
+
+/*
+**
+function run
+return int, the exit code, see exit_codes.go
+Run the tool with the command line arguments
+**
+*/
+func run() int {
+	return runArgs(os.Args[1:])
+}
+
+/*
+**
+function runSync
+input args []string, the options of the sync
+return int, the exit code of the sync
+Run a sync in a process running several of them, like serve or daemon. A fatal
+error ends the sync with its exit code instead of the process.
+**
+*/
+func runSync(args []string) (exitCode int) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			fatal, ok := r.(fatalExit)
+			if !ok {
+				panic(r)
+			}
+			// the state file stays locked until it is closed
+			defaultEventSink.close()
+			defaultEventSink = nil
+			defaultStateStore.close()
+			defaultStateStore = nil
+			defaultCheckpoint = nil
+			exitCode = fatal.code
+		}
+	}()
+
+	return runArgs(args)
+}
+
+/*
+**
+function runArgs
+input args []string, the command line arguments without the program name
+return int, the exit code, see exit_codes.go
+**
+*/
+func runArgs(args []string) int {
+	// subcommands
+	commandLine := args
+	command := ""
+	if len(args) > 0 {
+		switch args[0] {
+		case "cache":
+			return runCacheCommand(args[1:])
+		case "state":
+			return runStateCommand(args[1:])
+		case "serve":
+			return runServeCommand(args[1:])
+		case "daemon":
+			return runDaemonCommand(args[1:])
+		case "plan", "apply":
+			command = args[0]
+			args = args[1:]
+		}
+	}
+
+	planFile := ""
+	if command == "apply" {
+		if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+			logger.Error("Missing plan file", "usage", "apply <planfile> [options]")
+			return exitConfigError
+		}
+		planFile = args[0]
+		args = args[1:]
+	}
+
+	defaultRunStatus = &runStatus{}
+	// serve and daemon run several syncs, each one starts from scratch
+	defaultRunReport = nil
+	defaultRunMetrics = nil
+	defaultEventSink = nil
+	defaultGitHubIssues = newGitHubIssueCache()
+
 	// set Flags
 	options := flags{}
-	options.setOption(os.Args[1:])
+	options.setOption(args)
+
+	// a plan is a dry run written in a plan file
+	if command == "plan" {
+		options.optionalFlags.dryRun = true
+	}
 
 	// enable debug
 	customDebug := debug{}
 	customDebug.setDebug(options.optionalFlags.debug)
 
+	// the options were checked by setOption
+	configureLogging(options.optionalFlags.logFormat, options.optionalFlags.logLevel, options.optionalFlags.debug)
+
 	// test if mandatory flags are present
 	options.mandatoryFlags.checkMandatoryAreSet()
 
-	// Create the log file for the current run
-	filenameNotCreated := CreateLogFile(customDebug, "ErrorsFile_")
+	// every file of the run carries the run ID so runs sharing a directory don't mix their files
+	output, err := newRunOutput(options.optionalFlags.outputDir, time.Now())
+	if err != nil {
+		customDebug.Fatal("Could not use the output directory", "outputDir", options.optionalFlags.outputDir, "error", err)
+	}
+	defaultRunOutput = output
+	customDebug.Info("Starting run", "runId", defaultRunOutput.runID, "outputDir", options.optionalFlags.outputDir)
 
-	// Get the project ids associated with org
-	// If project ID is not specified => get all the projects
-	projectIDs, er := getProjectsIds(options, customDebug, filenameNotCreated)
-	if er != nil {
-		log.Fatal(er)
+	// proxy and TLS settings apply to every request
+	transport, err := newTransport(options.optionalFlags)
+	if err != nil {
+		customDebug.FatalWithCode(exitConfigError, "Invalid network configuration", "error", err)
 	}
+	defaultSnykClient.httpClient.Transport = transport
 
-	customDebug.Debug("*** INFO *** options.optionalFlags: ", options.optionalFlags)
+	if options.optionalFlags.record != "" {
+		recorder, err := newRecordingTransport(transport, options.optionalFlags.record)
+		if err != nil {
+			customDebug.Fatal("Could not use the record directory", "record", options.optionalFlags.record, "error", err)
+		}
+		if err := writeRecordedCommand(options.optionalFlags.record, commandLine); err != nil {
+			customDebug.Warn("Could not save the command line", "record", options.optionalFlags.record, "error", err)
+		}
+		defaultSnykClient.httpClient.Transport = recorder
+	}
 
-	maturityFilter := createMaturityFilter(strings.Split(options.optionalFlags.maturityFilterString, ","))
-	numberIssueCreated := 0
-	notCreatedJiraIssues := ""
-	jiraResponse := ""
-	var projectsTickets map[string]interface{}
-	logFile := make(map[string]map[string]interface{})
+	// OAuth bearer tokens are used for v1 and REST when a client is configured
+	defaultSnykClient.auth = newOAuthTokenSource(options.mandatoryFlags)
 
-	// Create the log file for the current run
-	filename := CreateLogFile(customDebug, "listOfTicketCreated_")
+	// a replay must only see the recorded responses
+	if options.optionalFlags.replay != "" {
+		replayer, err := newReplayTransport(options.optionalFlags.replay)
+		if err != nil {
+			customDebug.Fatal("Could not use the replay directory", "replay", options.optionalFlags.replay, "error", err)
+		}
+		defaultSnykClient.httpClient.Transport = replayer
+		options.optionalFlags.noCache = true
+		options.optionalFlags.requestsPerMinute = 0
+	}
 
-	for _, project := range projectIDs {
+	// the GitHub requests go through the same proxy, TLS settings and recording
+	defaultGitHubClient.httpClient.Transport = defaultSnykClient.httpClient.Transport
 
-		log.Println("*** INFO *** Step 1/4 - Retrieving project", project)
-		projectInfo, err := getProjectDetails(options.mandatoryFlags, project, customDebug)
+	// apply creates the tickets of the plan, the cache is not used so the
+	// tickets and issues are checked against the current state
+	var plan *Plan
+	if command == "apply" {
+		loaded, err := readPlan(planFile, options.mandatoryFlags.orgID)
 		if err != nil {
-			customDebug.Debug("*** ERROR *** could not get project details. Skipping project ", project)
-			continue
+			customDebug.FatalWithCode(exitConfigError, "Could not use the plan", "planFile", planFile, "error", err)
 		}
+		plan = &loaded
+		options.optionalFlags.noCache = true
+		customDebug.Info("Applying plan", "planFile", planFile, "planRunId", plan.RunID, "items", len(plan.Items))
+	}
+
+	// the start of the run is saved at the end so issues
+	// introduced while the tool runs are picked up next time
+	runStart := time.Now()
+	options.optionalFlags.resolveIntroducedSince(options.mandatoryFlags.orgID, runStart)
+	options.optionalFlags.resolveIncremental(options.mandatoryFlags.orgID, runStart)
+
+	// the Pushgateway and the notification webhooks are reached with the proxy
+	// and TLS settings, never through --record or --replay
+	outboundClient := &http.Client{Timeout: 30 * time.Second, Transport: transport}
+
+	notifyTemplate, err := readNotifyTemplate(options.optionalFlags.notifyTemplate)
+	if err != nil {
+		customDebug.FatalWithCode(exitConfigError, "Not a valid notifyTemplate", "notifyTemplate", options.optionalFlags.notifyTemplate, "error", err)
+	}
 
-		log.Println("*** INFO *** Step 2/4 - Retrieving a list of existing Jira tickets")
-		tickets, err := getJiraTickets(options.mandatoryFlags, project, customDebug)
+	// the notifications are built from the report
+	reportFile := defaultRunOutput.resolve(options.optionalFlags.reportFile)
+	if reportFile != "" || len(newNotifiers(options.optionalFlags)) > 0 {
+		defaultRunReport = newRunReport(options.optionalFlags, options.mandatoryFlags.orgID, runStart)
+	}
+
+	metricsFile := defaultRunOutput.resolve(options.optionalFlags.metricsFile)
+	if metricsFile != "" || options.optionalFlags.pushgatewayURL != "" {
+		defaultRunMetrics = newRunMetrics(options.mandatoryFlags.orgID, runStart)
+		defaultRunMetrics.pushClient = outboundClient
+	}
+
+	eventsFile := defaultRunOutput.resolve(options.optionalFlags.eventsFile)
+	if eventsFile != "" || options.optionalFlags.eventsURL != "" {
+		defaultEventSink = newEventSink(options.optionalFlags, options.mandatoryFlags.orgID, eventsFile, options.optionalFlags.eventsURL, outboundClient)
+	}
+
+	// the mappings of the previous runs are used with the ones of Snyk, a dry run doesn't change them
+	defaultStateStore = nil
+	stateFile := defaultRunOutput.resolve(options.optionalFlags.stateFile)
+	if stateFile != "" {
+		store, err := openStateStore(stateFile, options.mandatoryFlags.orgID, options.optionalFlags.dryRun)
 		if err != nil {
-			customDebug.Debug("*** ERROR *** could not get already existing tickets details. Skipping project ", project)
-			continue
+			customDebug.Fatal("Could not open the state file", "stateFile", stateFile, "error", err)
 		}
+		defaultStateStore = store
+	}
 
-		customDebug.Debug("*** INFO *** List of already existing tickets: ", tickets)
+	// Create the error journal for the current run
+	filenameNotCreated := CreateLogFile(customDebug, ErrorsFilePrefix)
 
-		log.Println("*** INFO *** Step 3/4 - Getting vulns")
-		vulnsPerPath, skippedIssues, err := getVulnsWithoutTicket(options, project, maturityFilter, tickets, customDebug)
-		if err != nil {
-			customDebug.Debug("*** ERROR *** could not get vulnerability details. Skipping project ", project)
-			continue
+	// Get the project ids associated with org
+	// If project ID is not specified => get all the projects
+	// apply only goes through the projects of the plan
+	var projectIDs []string
+	excludedProjects := make(map[string]string)
+	var er error
+	if plan != nil {
+		projectIDs = plan.projectIDs()
+	} else {
+		projectIDs, excludedProjects, er = getProjectsIds(options, customDebug, filenameNotCreated)
+	}
+	if er != nil {
+		if defaultRunStatus.hasAuthFailed() {
+			customDebug.FatalWithCode(exitAuthError, "Could not get the projects", "org", options.mandatoryFlags.orgID, "error", er)
 		}
+		customDebug.Fatal("Could not get the projects", "org", options.mandatoryFlags.orgID, "error", er)
+	}
 
-		customDebug.Debug("*** INFO *** # of vulns without tickets: ", len(vulnsPerPath))
+	customDebug.Debug("Options", "optionalFlags", fmt.Sprintf("%+v", options.optionalFlags))
 
-		if len(skippedIssues) > 0 {
-			customDebug.Debug("*** INFO *** List of skipped vulns: ", skippedIssues)
-			customDebug.Debug("*** INFO *** These have been skipped because data couldn't be retrieved from Snyk")
+	// the projects done and the tickets created are checkpointed so an interrupted
+	// run can be resumed, dry runs and plans create nothing. An apply has the checkpoint
+	// of its plan and continues the previous apply of the plan. A single project run
+	// leaves the checkpoint of the org to the run it belongs to
+	defaultCheckpoint = nil
+	lastRunStart := runStart
+	if !options.optionalFlags.dryRun && options.optionalFlags.projectID == "" {
+		checkpointFile := checkpointPath(options.mandatoryFlags.orgID)
+		resume := options.optionalFlags.resume
+		if plan != nil {
+			checkpointFile = planCheckpointPath(options.mandatoryFlags.orgID, plan.RunID)
+			resume = true
+		}
+		if _, err := os.Stat(checkpointFile); err == nil && !resume {
+			customDebug.Warn("The checkpoint of an interrupted run is replaced, use --resume to continue it", "checkpoint", checkpointFile)
+		}
+		runCheckpoint, resumed, err := openCheckpoint(checkpointFile, resume, runStart)
+		if err != nil {
+			customDebug.Fatal("Could not use the checkpoint", "checkpoint", checkpointFile, "error", err)
 		}
+		defaultCheckpoint = runCheckpoint
+		if resumed {
+			remaining := runCheckpoint.remainingProjects(projectIDs)
+			customDebug.Info("Resuming the interrupted run", "checkpoint", checkpointFile, "startedAt", runCheckpoint.startedAt, "projectsDone", len(projectIDs)-len(remaining), "projectsRemaining", len(remaining))
+			projectIDs = remaining
+			// the issues introduced while the run was interrupted are picked up next time
+			lastRunStart = runCheckpoint.startedAt
+		}
+	} else if options.optionalFlags.resume {
+		customDebug.Warn("--resume is ignored by dry runs, plan and --projectID")
+	}
+
+	for _, projectID := range projectIDs {
+		recordProject(projectID, projectStatusProcessed, "")
+	}
+	excludedIDs := make([]string, 0, len(excludedProjects))
+	for projectID := range excludedProjects {
+		excludedIDs = append(excludedIDs, projectID)
+	}
+	sort.Strings(excludedIDs)
+	for _, projectID := range excludedIDs {
+		recordProject(projectID, projectStatusExcluded, excludedProjects[projectID])
+	}
 
-		if len(vulnsPerPath) == 0 {
-			log.Println("*** INFO *** Step 4/4 - No new Jira ticket required")
+	maturityFilter := createMaturityFilter(strings.Split(options.optionalFlags.maturityFilterString, ","))
+	runFailed := false
+	// an issue skipped or a ticket not created is tried again by the next run
+	runIncomplete := false
+	logFile := make(map[string]map[string]interface{})
+
+	// Create the log file for the current run
+	filename := CreateLogFile(customDebug, TicketsFilePrefix)
+
+	// one limiter for all the workers so the parallel requests stay under the Snyk rate limit
+	defaultSnykClient.limiter = newRateLimiter(options.optionalFlags.requestsPerMinute)
+
+	if options.optionalFlags.cacheDir != "" && !options.optionalFlags.noCache {
+		defaultSnykClient.cache = options.optionalFlags.newCache(customDebug)
+	}
+
+	// projects are processed in parallel, their output is printed in the order
+	// of the list as soon as the previous projects are done
+	results := make([]projectResult, len(projectIDs))
+	done := make([]chan struct{}, len(projectIDs))
+	for index := range done {
+		done[index] = make(chan struct{})
+	}
+
+	go forEachParallel(options.optionalFlags.concurrency, len(projectIDs), func(index int) {
+		// the projects in flight are finished, the next ones are left to --resume
+		if isStopRequested() {
+			result := projectResult{log: &logBuffer{}, failed: true}
+			projectDebug := customDebug.Buffered(result.log)
+			projectDebug.Warn("Project not processed, the run is stopping", "project", projectIDs[index])
+			recordProject(projectIDs[index], projectStatusFailed, "not processed, the run was stopped")
+			defaultRunStatus.projectFailed()
+			results[index] = result
+			close(done[index])
+			return
+		}
+		if plan != nil {
+			results[index] = applyProject(options, projectIDs[index], plan.itemsFor(projectIDs[index]), maturityFilter, customDebug)
 		} else {
-			log.Println("*** INFO *** Step 4/4 - Opening Jira tickets")
-			numberIssueCreated, jiraResponse, notCreatedJiraIssues, projectsTickets = openJiraTickets(options, projectInfo, vulnsPerPath, customDebug)
-			if jiraResponse == "" && !options.optionalFlags.dryRun {
-				log.Println("*** ERROR *** Failed to create Jira ticket(s)")
-			}
-			if options.optionalFlags.dryRun {
-				fmt.Printf("\n----------PROJECT ID %s----------\n Dry run mode: no issue created\n------------------------------------------------------------------------\n", project)
-			} else {
-				fmt.Printf("\n----------PROJECT ID %s---------- \n Number of tickets created: %d\n List of issueIds for which Jira ticket(s) could not be created: %s\n-------------------------------------------------------------------\n", project, numberIssueCreated, notCreatedJiraIssues)
-			}
+			results[index] = processProject(options, projectIDs[index], maturityFilter, customDebug)
+		}
+		close(done[index])
+	})
+
+	for index := range projectIDs {
+		<-done[index]
+		result := results[index]
+
+		result.log.Flush()
+		// the banner repeats the summary entry for people reading the console
+		if defaultLogSink.format == "text" {
+			fmt.Print(result.summary)
+		}
+
+		if result.failed {
+			runFailed = true
+		} else if !defaultCheckpoint.hasPendingIn(projectIDs[index]) {
+			defaultCheckpoint.projectDone(projectIDs[index])
+		}
+		if defaultRunStatus.isIncomplete(projectIDs[index]) {
+			runIncomplete = true
+		}
 
-			// Adding new project tickets detail to logfile struct
-			// need to merge the map{string}interface{}
-			// the new project one with the one containing all the
-			// projects (could not find a better way for now)
-			if projectsTickets != nil {
-				newLogFile := make(map[string]interface{})
-				for k, v := range projectsTickets {
-					if _, ok := projectsTickets[k]; ok {
-						newLogFile[k] = v
-					}
+		// Adding new project tickets detail to logfile struct
+		// need to merge the map{string}interface{}
+		// the new project one with the one containing all the
+		// projects (could not find a better way for now)
+		if result.projectsTickets != nil {
+			newLogFile := make(map[string]interface{})
+			for k, v := range result.projectsTickets {
+				if _, ok := result.projectsTickets[k]; ok {
+					newLogFile[k] = v
 				}
+			}
 
-				for k, v := range logFile["projects"] {
-					if _, ok := logFile["projects"][k]; ok {
-						newLogFile[k] = v
-					}
+			for k, v := range logFile["projects"] {
+				if _, ok := logFile["projects"][k]; ok {
+					newLogFile[k] = v
 				}
-				logFile["projects"] = newLogFile
 			}
+			logFile["projects"] = newLogFile
 		}
 	}
 
+	// every issue is done, the last events are sent before the run ends
+	defaultEventSink.close()
+	defaultEventSink = nil
+	if err := defaultStateStore.close(); err != nil {
+		customDebug.Error("Could not close the state file", "stateFile", stateFile, "error", err)
+	}
+	defaultStateStore = nil
+
 	// writing into the file
 	writeLogFile(logFile, filename, customDebug)
 
+	if command == "plan" {
+		planFile = defaultRunOutput.resolve(options.optionalFlags.planFile)
+		if planFile == "" {
+			planFile = defaultRunOutput.path(PlanFilePrefix, ".json")
+		}
+		ticketsPlan := newPlan(options.mandatoryFlags.orgID, options.mandatoryFlags.endpointAPI, logFile["projects"], time.Now())
+		if err := writePlan(ticketsPlan, planFile); err != nil {
+			customDebug.Error("Could not write the plan", "planFile", planFile, "error", err)
+			return exitError
+		}
+		customDebug.Info("Plan written", "planFile", planFile, "items", len(ticketsPlan.Items))
+	}
+
+	if reportFile != "" {
+		if err := defaultRunReport.write(reportFile, options.optionalFlags.reportFormat, time.Now()); err != nil {
+			customDebug.Error("Could not write the run report", "file", reportFile, "error", err)
+		} else {
+			customDebug.Info("Run report written", "file", reportFile, "format", options.optionalFlags.reportFormat)
+		}
+	}
+
 	// TODO: add the list of not created tickets
 
+	// only a complete run moves the lastRun threshold forward, apply only sees the issues of the plan
+	// and a single project run doesn't see the other projects of the org
+	if !runFailed && !runIncomplete && !options.optionalFlags.dryRun && plan == nil && options.optionalFlags.projectID == "" {
+		// an incremental run doesn't reset the full sync period
+		fullSync := options.optionalFlags.incrementalSince.IsZero()
+		writeLastSuccessfulRun(options.mandatoryFlags.orgID, lastRunStart, fullSync, customDebug)
+	}
+
+	// the failed projects and the pending tickets are tried again by --resume, a complete run has nothing to resume
+	if !runFailed && !defaultCheckpoint.hasPending() {
+		if err := defaultCheckpoint.remove(); err != nil {
+			customDebug.Error("Could not remove the checkpoint", "error", err)
+		}
+	}
+	defaultCheckpoint = nil
+
+	if len(excludedProjects) > 0 {
+		customDebug.Info("Projects excluded", "count", len(excludedProjects), "projects", excludedProjects)
+		if defaultLogSink.format == "text" {
+			fmt.Println(formatExcludedProjects(excludedProjects))
+		}
+	}
+
+	if command == "plan" && defaultLogSink.format == "text" {
+		fmt.Printf("\n******** Plan written in %s, create the tickets with: apply %s ********\n", planFile, planFile)
+	} else if options.optionalFlags.dryRun {
+		customDebug.Info("Dry run list of tickets written", "file", filename)
+		if defaultLogSink.format == "text" {
+			fmt.Println("\n*************************************************************************************************************")
+			fmt.Printf("\n******** Dry run list of ticket can be found in log file %s ********", filename)
+			fmt.Println("\n*************************************************************************************************************")
+		}
+	}
+
+	exitCode := defaultRunStatus.exitCode(options.optionalFlags.failOn)
+
+	if err := defaultRunMetrics.write(metricsFile, options.optionalFlags.pushgatewayURL, time.Now(), exitCode); err != nil {
+		customDebug.Error("Could not export the metrics", "metricsFile", metricsFile, "pushgatewayURL", options.optionalFlags.pushgatewayURL, "error", err)
+	}
+
+	if defaultRunReport != nil {
+		sendNotifications(outboundClient, options.optionalFlags, notifyTemplate, defaultRunReport.build(time.Now()), exitCode, customDebug)
+	}
+	customDebug.Info("Run done", "runId", defaultRunOutput.runID, "exitCode", exitCode, "failOn", options.optionalFlags.failOn,
+		"ticketsCreated", defaultRunStatus.ticketsCreated, "ticketsFailed", defaultRunStatus.ticketsFailed, "projectsFailed", defaultRunStatus.projectsFailed)
+
+	return exitCode
+}
+
+/*
+**
+function processProject
+input options flags, project string, the ID of the project to process
+input maturityFilter []string
+input customDebug debug
+return projectResult, the output of the project and the tickets created
+Run the 4 steps for one project. The log entries are kept in the result and
+written by main in the order of the projects, it can run in parallel with other projects.
+**
+*/
+func processProject(options flags, project string, maturityFilter []string, customDebug debug) projectResult {
+
+	result := projectResult{log: &logBuffer{}}
+	customDebug = customDebug.Buffered(result.log).With("org", options.mandatoryFlags.orgID, "project", project)
+
+	customDebug.Info("Step 1/4 - Retrieving project")
+	projectInfo, err := getProjectDetails(options.mandatoryFlags, project, customDebug)
+	if err != nil {
+		customDebug.Error("Could not get project details. Skipping project", "error", err)
+		recordProject(project, projectStatusFailed, "could not get project details")
+		defaultRunStatus.projectFailed()
+		result.failed = true
+		return result
+	}
+
+	tracker := options.trackerFor(project, projectInfo.K("name").String().Value)
+	customDebug.Info("Step 2/4 - Retrieving a list of existing tickets", "tracker", tracker.name(), "target", tracker.target())
+	tickets, err := tracker.existingTickets(project, customDebug)
+	if err != nil {
+		customDebug.Error("Could not get already existing tickets details. Skipping project", "error", err)
+		recordProject(project, projectStatusFailed, "could not get the existing tickets")
+		defaultRunStatus.projectFailed()
+		result.failed = true
+		return result
+	}
+	tickets = defaultStateStore.mergeTickets(project, tickets, customDebug)
+	tickets = defaultCheckpoint.mergeTickets(project, tickets, customDebug)
+
+	customDebug.Debug("List of already existing tickets", "tickets", tickets)
+
+	customDebug.Info("Step 3/4 - Getting vulns")
+	vulnsPerPath, skippedIssues, err := getVulnsWithoutTicket(options, project, maturityFilter, tickets, customDebug)
+	if err != nil {
+		customDebug.Error("Could not get vulnerability details. Skipping project", "error", err)
+		recordProject(project, projectStatusFailed, "could not get the issues")
+		defaultRunStatus.projectFailed()
+		result.failed = true
+		return result
+	}
+
+	customDebug.Debug("Vulns without tickets", "count", len(vulnsPerPath))
+
+	if len(skippedIssues) > 0 {
+		customDebug.Warn("Issues skipped because data couldn't be retrieved from Snyk", "issues", skippedIssues)
+	}
+
+	if len(vulnsPerPath) == 0 {
+		customDebug.Info("Step 4/4 - No new Jira ticket required")
+		customDebug.Info("Project done", "ticketsCreated", 0)
+		return result
+	}
+
+	customDebug.Info("Step 4/4 - Opening Jira tickets")
+	numberIssueCreated, jiraResponse, notCreatedJiraIssues, projectsTickets := openJiraTickets(options, projectInfo, vulnsPerPath, customDebug)
+	if jiraResponse == "" && !options.optionalFlags.dryRun {
+		customDebug.Error("Failed to create Jira ticket(s)")
+		recordProject(project, projectStatusFailed, "failed to create Jira ticket(s)")
+		result.failed = true
+	}
+	customDebug.Info("Project done", "dryRun", options.optionalFlags.dryRun, "ticketsCreated", numberIssueCreated, "ticketsNotCreated", notCreatedJiraIssues)
 	if options.optionalFlags.dryRun {
-		fmt.Println("\n*************************************************************************************************************")
-		fmt.Printf("\n******** Dry run list of ticket can be found in log file %s ********", filename)
-		fmt.Println("\n*************************************************************************************************************")
+		result.summary = fmt.Sprintf("\n----------PROJECT ID %s----------\n Dry run mode: no issue created\n%s------------------------------------------------------------------------\n", project, formatProjectPreview(projectsTickets[project]))
+	} else {
+		result.summary = fmt.Sprintf("\n----------PROJECT ID %s---------- \n Number of tickets created: %d\n List of issueIds for which Jira ticket(s) could not be created: %s\n-------------------------------------------------------------------\n", project, numberIssueCreated, notCreatedJiraIssues)
 	}
+	result.projectsTickets = projectsTickets
+
+	return result
 }
@@ -0,0 +1,347 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/michael-go/go-jsn/jsn"
+)
+
+// metricsJob is the Pushgateway job of the metrics
+const metricsJob = "jira_tickets_for_new_vulns"
+
+// bounds of the API latency histogram, in seconds
+var requestDurationBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// metricsPathIDs are the path segments followed by an ID, the IDs are replaced
+// so every org, project or issue doesn't get its own series
+var metricsPathIDs = map[string]bool{
+	"org":      true,
+	"orgs":     true,
+	"project":  true,
+	"projects": true,
+	"issue":    true,
+	"issues":   true,
+	"target":   true,
+	"targets":  true,
+}
+
+type requestSeries struct {
+	method   string
+	endpoint string
+	status   string
+}
+
+type ticketSeries struct {
+	outcome   string
+	severity  string
+	issueType string
+}
+
+type requestStats struct {
+	count   int
+	sum     float64
+	buckets []int
+}
+
+// runMetrics collects the metrics of the run while the projects and issues are processed in parallel
+type runMetrics struct {
+	mu               sync.Mutex
+	orgID            string
+	startedAt        time.Time
+	projectStatus    map[string]string
+	issuesConsidered int
+	tickets          map[ticketSeries]int
+	requests         map[requestSeries]*requestStats
+	pushClient       *http.Client
+}
+
+// defaultRunMetrics is nil unless metrics are requested, every method is a no-op then
+var defaultRunMetrics *runMetrics
+
+/*
+**
+function newRunMetrics
+input orgID string
+input startedAt time.Time, start of the run
+return *runMetrics
+**
+*/
+func newRunMetrics(orgID string, startedAt time.Time) *runMetrics {
+	return &runMetrics{
+		orgID:         orgID,
+		startedAt:     startedAt,
+		projectStatus: make(map[string]string),
+		tickets:       make(map[ticketSeries]int),
+		requests:      make(map[requestSeries]*requestStats),
+	}
+}
+
+/*
+**
+function setProject
+input projectID string, status string, one of the project status constants
+**
+*/
+func (m *runMetrics) setProject(projectID string, status string) {
+
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.projectStatus[projectID] = status
+}
+
+/*
+**
+function addIssue
+input issue jsn.Json, open source issue, code issue from the list or code issue details
+input outcome string, one of the outcome constants
+Every issue is considered, the created and failed tickets are counted per severity and type
+**
+*/
+func (m *runMetrics) addIssue(issue jsn.Json, outcome string) {
+
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.issuesConsidered++
+	if outcome != outcomeCreated && outcome != outcomeFailed {
+		return
+	}
+
+	described := describeIssue(issue)
+	m.tickets[ticketSeries{outcome: outcome, severity: described.Severity, issueType: described.IssueType}]++
+}
+
+/*
+**
+function observeRequest
+input method string, endpointURL string
+input status string, HTTP status code or error when there was no response
+input duration time.Duration
+**
+*/
+func (m *runMetrics) observeRequest(method string, endpointURL string, status string, duration time.Duration) {
+
+	if m == nil {
+		return
+	}
+
+	key := requestSeries{method: method, endpoint: metricsEndpoint(endpointURL), status: status}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats, found := m.requests[key]
+	if !found {
+		stats = &requestStats{buckets: make([]int, len(requestDurationBuckets))}
+		m.requests[key] = stats
+	}
+
+	seconds := duration.Seconds()
+	stats.count++
+	stats.sum += seconds
+	for index, bound := range requestDurationBuckets {
+		if seconds <= bound {
+			stats.buckets[index]++
+		}
+	}
+}
+
+/*
+**
+function metricsEndpoint
+input endpointURL string
+return string, the path of the URL with the IDs replaced by {id} and without the query
+**
+*/
+func metricsEndpoint(endpointURL string) string {
+
+	parsedURL, err := url.Parse(endpointURL)
+	if err != nil {
+		return "unknown"
+	}
+
+	segments := strings.Split(parsedURL.Path, "/")
+	for index := 1; index < len(segments); index++ {
+		if metricsPathIDs[segments[index-1]] && segments[index] != "" {
+			segments[index] = "{id}"
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
+
+/*
+**
+function render
+input finishedAt time.Time
+input exitCode int
+return []byte, the metrics in the Prometheus text format, the series are sorted so the output is stable
+**
+*/
+func (m *runMetrics) render(finishedAt time.Time, exitCode int) []byte {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var buffer bytes.Buffer
+	org := "org=" + strconv.Quote(m.orgID)
+
+	writeMetricHeader(&buffer, "snyk_jira_sync_projects", "gauge", "Projects of the run by status")
+	projectCounts := map[string]int{projectStatusProcessed: 0, projectStatusExcluded: 0, projectStatusFailed: 0}
+	for _, status := range m.projectStatus {
+		projectCounts[status]++
+	}
+	for _, status := range sortedKeys(projectCounts) {
+		fmt.Fprintf(&buffer, "snyk_jira_sync_projects{%s,status=%q} %d\n", org, status, projectCounts[status])
+	}
+
+	writeMetricHeader(&buffer, "snyk_jira_sync_issues_considered", "gauge", "Issues without ticket considered by the run")
+	fmt.Fprintf(&buffer, "snyk_jira_sync_issues_considered{%s} %d\n", org, m.issuesConsidered)
+
+	writeMetricHeader(&buffer, "snyk_jira_sync_tickets", "gauge", "Tickets created or failed by severity and issue type")
+	ticketKeys := make([]ticketSeries, 0, len(m.tickets))
+	for key := range m.tickets {
+		ticketKeys = append(ticketKeys, key)
+	}
+	sort.Slice(ticketKeys, func(i, j int) bool {
+		return fmt.Sprint(ticketKeys[i]) < fmt.Sprint(ticketKeys[j])
+	})
+	for _, key := range ticketKeys {
+		fmt.Fprintf(&buffer, "snyk_jira_sync_tickets{%s,outcome=%q,severity=%q,type=%q} %d\n", org, key.outcome, key.severity, key.issueType, m.tickets[key])
+	}
+
+	requestKeys := make([]requestSeries, 0, len(m.requests))
+	for key := range m.requests {
+		requestKeys = append(requestKeys, key)
+	}
+	sort.Slice(requestKeys, func(i, j int) bool {
+		return fmt.Sprint(requestKeys[i]) < fmt.Sprint(requestKeys[j])
+	})
+
+	writeMetricHeader(&buffer, "snyk_jira_sync_api_requests", "gauge", "Snyk API requests by endpoint and status, retries included")
+	for _, key := range requestKeys {
+		fmt.Fprintf(&buffer, "snyk_jira_sync_api_requests{%s,method=%q,endpoint=%q,status=%q} %d\n", org, key.method, key.endpoint, key.status, m.requests[key].count)
+	}
+
+	writeMetricHeader(&buffer, "snyk_jira_sync_api_request_duration_seconds", "histogram", "Latency of the Snyk API requests by endpoint and status")
+	for _, key := range requestKeys {
+		stats := m.requests[key]
+		labels := fmt.Sprintf("%s,method=%q,endpoint=%q,status=%q", org, key.method, key.endpoint, key.status)
+		for index, bound := range requestDurationBuckets {
+			fmt.Fprintf(&buffer, "snyk_jira_sync_api_request_duration_seconds_bucket{%s,le=%q} %d\n", labels, strconv.FormatFloat(bound, 'f', -1, 64), stats.buckets[index])
+		}
+		fmt.Fprintf(&buffer, "snyk_jira_sync_api_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, stats.count)
+		fmt.Fprintf(&buffer, "snyk_jira_sync_api_request_duration_seconds_sum{%s} %s\n", labels, strconv.FormatFloat(stats.sum, 'f', -1, 64))
+		fmt.Fprintf(&buffer, "snyk_jira_sync_api_request_duration_seconds_count{%s} %d\n", labels, stats.count)
+	}
+
+	writeMetricHeader(&buffer, "snyk_jira_sync_run_duration_seconds", "gauge", "Duration of the run")
+	fmt.Fprintf(&buffer, "snyk_jira_sync_run_duration_seconds{%s} %s\n", org, strconv.FormatFloat(finishedAt.Sub(m.startedAt).Seconds(), 'f', 3, 64))
+
+	writeMetricHeader(&buffer, "snyk_jira_sync_last_run_timestamp_seconds", "gauge", "End of the run, in seconds since the epoch")
+	fmt.Fprintf(&buffer, "snyk_jira_sync_last_run_timestamp_seconds{%s} %d\n", org, finishedAt.Unix())
+
+	writeMetricHeader(&buffer, "snyk_jira_sync_exit_code", "gauge", "Exit code of the run")
+	fmt.Fprintf(&buffer, "snyk_jira_sync_exit_code{%s} %d\n", org, exitCode)
+
+	return buffer.Bytes()
+}
+
+func writeMetricHeader(buffer *bytes.Buffer, name string, metricType string, help string) {
+	fmt.Fprintf(buffer, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+func sortedKeys(values map[string]int) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+/*
+**
+function write
+input filename string, file read by the node exporter textfile collector, empty to skip
+input pushgatewayURL string, Pushgateway base URL, empty to skip
+input finishedAt time.Time
+input exitCode int
+return error
+The file is replaced atomically so the collector never reads a partial file
+**
+*/
+func (m *runMetrics) write(filename string, pushgatewayURL string, finishedAt time.Time, exitCode int) error {
+
+	if m == nil {
+		return nil
+	}
+
+	data := m.render(finishedAt, exitCode)
+
+	if filename != "" {
+		if err := writeFileAtomic(filename, data, 0644); err != nil {
+			return err
+		}
+	}
+
+	if pushgatewayURL != "" {
+		return pushMetrics(m.pushClient, pushgatewayURL, m.orgID, data)
+	}
+
+	return nil
+}
+
+/*
+**
+function pushMetrics
+input client *http.Client, http.DefaultClient when nil
+input pushgatewayURL string
+input orgID string, the metrics are grouped by org so the runs of different orgs don't replace each other
+input data []byte, metrics in the text format
+return error
+**
+*/
+func pushMetrics(client *http.Client, pushgatewayURL string, orgID string, data []byte) error {
+
+	endpoint := strings.TrimSuffix(pushgatewayURL, "/") + "/metrics/job/" + metricsJob + "/org/" + url.PathEscape(orgID)
+
+	request, err := http.NewRequest("PUT", endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(response.Body)
+		return fmt.Errorf("push to %s failed with %s: %s", endpoint, response.Status, bodyExcerpt(body))
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/michael-go/go-jsn/jsn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunMetricsFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	startedAt := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	metrics := newRunMetrics("123", startedAt)
+
+	vuln, _ := jsn.NewJson(map[string]interface{}{
+		"id":        "SNYK-JS-MINIMIST-559764",
+		"issueType": "vuln",
+		"issueData": map[string]interface{}{"title": "Prototype Pollution", "severity": "high"},
+	})
+
+	metrics.setProject("project-a", projectStatusProcessed)
+	metrics.setProject("project-b", projectStatusProcessed)
+	metrics.setProject("project-b", projectStatusFailed)
+	metrics.setProject("project-c", projectStatusExcluded)
+
+	metrics.addIssue(vuln, outcomeCreated)
+	metrics.addIssue(vuln, outcomeCreated)
+	metrics.addIssue(vuln, outcomeFailed)
+	metrics.addIssue(vuln, outcomeFiltered)
+
+	metrics.observeRequest("GET", "https://api.snyk.io/v1/org/123/project/abc/jira-issues", "200", 200*time.Millisecond)
+	metrics.observeRequest("GET", "https://api.snyk.io/v1/org/456/project/def/jira-issues", "200", 3*time.Second)
+	metrics.observeRequest("POST", "https://api.snyk.io/rest/orgs/123/projects?version=2024-01-01", "error", time.Second)
+
+	output := string(metrics.render(startedAt.Add(90*time.Second), exitPartialFailure))
+
+	assert.Contains(output, "# TYPE snyk_jira_sync_projects gauge\n")
+	assert.Contains(output, `snyk_jira_sync_projects{org="123",status="processed"} 1`+"\n")
+	assert.Contains(output, `snyk_jira_sync_projects{org="123",status="failed"} 1`+"\n")
+	assert.Contains(output, `snyk_jira_sync_projects{org="123",status="excluded"} 1`+"\n")
+	assert.Contains(output, `snyk_jira_sync_issues_considered{org="123"} 4`+"\n")
+	assert.Contains(output, `snyk_jira_sync_tickets{org="123",outcome="created",severity="high",type="vuln"} 2`+"\n")
+	assert.Contains(output, `snyk_jira_sync_tickets{org="123",outcome="failed",severity="high",type="vuln"} 1`+"\n")
+	assert.Contains(output, `snyk_jira_sync_api_requests{org="123",method="GET",endpoint="/v1/org/{id}/project/{id}/jira-issues",status="200"} 2`+"\n")
+	assert.Contains(output, `snyk_jira_sync_api_requests{org="123",method="POST",endpoint="/rest/orgs/{id}/projects",status="error"} 1`+"\n")
+	assert.Contains(output, `snyk_jira_sync_api_request_duration_seconds_bucket{org="123",method="GET",endpoint="/v1/org/{id}/project/{id}/jira-issues",status="200",le="0.25"} 1`+"\n")
+	assert.Contains(output, `snyk_jira_sync_api_request_duration_seconds_bucket{org="123",method="GET",endpoint="/v1/org/{id}/project/{id}/jira-issues",status="200",le="+Inf"} 2`+"\n")
+	assert.Contains(output, `snyk_jira_sync_api_request_duration_seconds_sum{org="123",method="GET",endpoint="/v1/org/{id}/project/{id}/jira-issues",status="200"} 3.2`+"\n")
+	assert.Contains(output, `snyk_jira_sync_run_duration_seconds{org="123"} 90.000`+"\n")
+	assert.Contains(output, `snyk_jira_sync_last_run_timestamp_seconds{org="123"} 1717243290`+"\n")
+	assert.Contains(output, `snyk_jira_sync_exit_code{org="123"} 4`+"\n")
+
+	// the output is stable
+	assert.Equal(output, string(metrics.render(startedAt.Add(90*time.Second), exitPartialFailure)))
+
+	// textfile collector
+	filename := filepath.Join(t.TempDir(), "snyk_jira_sync.prom")
+	assert.Nil(metrics.write(filename, "", startedAt.Add(90*time.Second), exitPartialFailure))
+	data, err := os.ReadFile(filename)
+	assert.Nil(err)
+	assert.Equal(output, string(data))
+
+	// without metrics nothing is collected
+	var noMetrics *runMetrics
+	noMetrics.addIssue(vuln, outcomeCreated)
+	noMetrics.observeRequest("GET", "https://api.snyk.io/v1/org/123", "200", time.Second)
+	assert.Nil(noMetrics.write(filename, "", startedAt, exitSuccess))
+}
+
+func TestPushMetricsFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	var method, path, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		method, path, body = r.Method, r.URL.Path, string(data)
+		if strings.Contains(r.URL.Path, "broken") {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("text format parsing error"))
+		}
+	}))
+	defer server.Close()
+
+	metrics := newRunMetrics("123", time.Now())
+	assert.Nil(metrics.write("", server.URL+"/", time.Now(), exitSuccess))
+	assert.Equal("PUT", method)
+	assert.Equal("/metrics/job/jira_tickets_for_new_vulns/org/123", path)
+	assert.Contains(body, "snyk_jira_sync_exit_code{org=\"123\"} 0")
+
+	err := pushMetrics(nil, server.URL+"/broken", "123", []byte("invalid"))
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "text format parsing error")
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// severities from the least to the most important
+var severityRank = map[string]int{"low": 1, "medium": 2, "high": 3, "critical": 4}
+
+// defaultNotifyTemplate is used when --notifyTemplate is not set, {{link .}} is the
+// Jira link of an issue in the syntax of the chat
+const defaultNotifyTemplate = `Snyk to Jira sync for org {{.OrgID}}: {{if .DryRun}}[Dry run] {{.Totals.DryRun}} ticket(s) would be created{{else}}{{.Totals.Created}} ticket(s) created{{end}}, {{.Totals.Failed}} failed, {{.Totals.ProjectsFailed}} project(s) failed
+{{- if .Critical}}
+
+*Critical issues*
+{{- range .Critical}}
+- {{link .}} {{.Title}} (project {{.ProjectID}})
+{{- end}}
+{{- end}}
+{{- range .Projects}}
+
+*Project {{.ProjectID}}*
+{{- range .Tickets}}
+- {{link .}} [{{.Severity}}] {{.Title}}
+{{- end}}
+{{- end}}
+{{- if .Failed}}
+
+*Tickets not created*
+{{- range .Failed}}
+- {{.IssueID}} (project {{.ProjectID}}): {{.Reason}}
+{{- end}}
+{{- end}}
+{{- if .FailedProjects}}
+
+*Projects failed*
+{{- range .FailedProjects}}
+- {{.ProjectID}}: {{.Reason}}
+{{- end}}
+{{- end}}
+{{- if .ExcludedProjects}}
+
+*Projects skipped*
+{{- range .ExcludedProjects}}
+- {{.ProjectID}}: {{.Reason}}
+{{- end}}
+{{- end}}
+`
+
+// NotificationProject lists the tickets created for a project
+type NotificationProject struct {
+	ProjectID string        `json:"projectId"`
+	Tickets   []ReportIssue `json:"tickets"`
+}
+
+// Notification is the summary of the run given to the message template and to the webhooks
+type Notification struct {
+	RunID            string                `json:"runId"`
+	OrgID            string                `json:"orgId"`
+	DryRun           bool                  `json:"dryRun"`
+	StartedAt        time.Time             `json:"startedAt"`
+	FinishedAt       time.Time             `json:"finishedAt"`
+	ExitCode         int                   `json:"exitCode"`
+	Totals           ReportTotals          `json:"totals"`
+	Projects         []NotificationProject `json:"projects"`
+	Critical         []ReportIssue         `json:"critical"`
+	Failed           []ReportIssue         `json:"failed"`
+	FailedProjects   []ReportProject       `json:"failedProjects"`
+	ExcludedProjects []ReportProject       `json:"excludedProjects"`
+}
+
+/*
+**
+function newNotification
+input report RunReport, the issues are sorted by project
+input exitCode int
+return Notification, the created tickets are grouped by project
+**
+*/
+func newNotification(report RunReport, exitCode int) Notification {
+
+	notification := Notification{
+		RunID:      report.RunID,
+		OrgID:      report.OrgID,
+		DryRun:     report.DryRun,
+		StartedAt:  report.StartedAt,
+		FinishedAt: report.FinishedAt,
+		ExitCode:   exitCode,
+		Totals:     report.Totals,
+	}
+
+	for _, issue := range report.Issues {
+		switch issue.Outcome {
+		case outcomeCreated, outcomeDryRun:
+			last := len(notification.Projects) - 1
+			if last < 0 || notification.Projects[last].ProjectID != issue.ProjectID {
+				notification.Projects = append(notification.Projects, NotificationProject{ProjectID: issue.ProjectID})
+				last++
+			}
+			notification.Projects[last].Tickets = append(notification.Projects[last].Tickets, issue)
+			if issue.Severity == "critical" {
+				notification.Critical = append(notification.Critical, issue)
+			}
+		case outcomeFailed:
+			notification.Failed = append(notification.Failed, issue)
+		}
+	}
+
+	for _, project := range report.Projects {
+		switch project.Status {
+		case projectStatusFailed:
+			notification.FailedProjects = append(notification.FailedProjects, project)
+		case projectStatusExcluded:
+			notification.ExcludedProjects = append(notification.ExcludedProjects, project)
+		}
+	}
+
+	return notification
+}
+
+/*
+**
+function shouldNotify
+input minSeverity string, empty to notify every run
+return bool, true when a ticket of at least this severity was created
+**
+*/
+func (notification Notification) shouldNotify(minSeverity string) bool {
+
+	if minSeverity == "" {
+		return true
+	}
+
+	for _, project := range notification.Projects {
+		for _, ticket := range project.Tickets {
+			if severityRank[ticket.Severity] >= severityRank[minSeverity] {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// notifier posts the summary of the run to a chat or a webhook
+type notifier struct {
+	kind string // slack, teams or webhook
+	url  string
+}
+
+/*
+**
+function newNotifiers
+input Of optionalFlags
+return []notifier, one per webhook set
+**
+*/
+func newNotifiers(Of optionalFlags) []notifier {
+
+	notifiers := []notifier{}
+	if Of.notifySlackWebhook != "" {
+		notifiers = append(notifiers, notifier{kind: "slack", url: Of.notifySlackWebhook})
+	}
+	if Of.notifyTeamsWebhook != "" {
+		notifiers = append(notifiers, notifier{kind: "teams", url: Of.notifyTeamsWebhook})
+	}
+	if Of.notifyWebhook != "" {
+		notifiers = append(notifiers, notifier{kind: "webhook", url: Of.notifyWebhook})
+	}
+
+	return notifiers
+}
+
+/*
+**
+function readNotifyTemplate
+input filename string, file with the Go template of the message, the default template is used when empty
+return *template.Template
+return error, when the file cannot be read or the template is not valid
+**
+*/
+func readNotifyTemplate(filename string) (*template.Template, error) {
+
+	if filename == "" {
+		return parseNotifyTemplate("")
+	}
+
+	text, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseNotifyTemplate(string(text))
+}
+
+/*
+**
+function parseNotifyTemplate
+input text string, Go template of the message, the default one when empty
+return *template.Template
+return error, when the template is not valid
+**
+*/
+func parseNotifyTemplate(text string) (*template.Template, error) {
+
+	if text == "" {
+		text = defaultNotifyTemplate
+	}
+
+	// link is replaced by each notifier, this one is only there to parse the template
+	return template.New("notification").Funcs(template.FuncMap{"link": notifyLink("")}).Parse(text)
+}
+
+// notifyLink formats the Jira link of an issue, or its ID when there is no link
+func notifyLink(kind string) func(ReportIssue) string {
+	return func(issue ReportIssue) string {
+
+		text := issue.JiraKey
+		if text == "" {
+			text = issue.IssueID
+		}
+		if issue.JiraURL == "" {
+			return text
+		}
+
+		switch kind {
+		case "slack":
+			return "<" + issue.JiraURL + "|" + text + ">"
+		case "teams":
+			return "[" + text + "](" + issue.JiraURL + ")"
+		}
+		return text + " " + issue.JiraURL
+	}
+}
+
+/*
+**
+function body
+input notification Notification
+input tmpl *template.Template
+return []byte, the JSON body expected by the chat or the webhook
+return error
+**
+*/
+func (n notifier) body(notification Notification, tmpl *template.Template) ([]byte, error) {
+
+	var text bytes.Buffer
+	clone, err := tmpl.Clone()
+	if err != nil {
+		return nil, err
+	}
+	if err := clone.Funcs(template.FuncMap{"link": notifyLink(n.kind)}).Execute(&text, notification); err != nil {
+		return nil, err
+	}
+
+	switch n.kind {
+	case "slack":
+		return json.Marshal(map[string]string{"text": text.String()})
+	case "teams":
+		// markdown is rendered, the new lines must be doubled to be kept
+		return json.Marshal(map[string]string{
+			"@type":    "MessageCard",
+			"@context": "https://schema.org/extensions",
+			"summary":  "Snyk to Jira sync",
+			"text":     strings.ReplaceAll(text.String(), "\n", "\n\n"),
+		})
+	}
+
+	return json.Marshal(struct {
+		Text string `json:"text"`
+		Notification
+	}{text.String(), notification})
+}
+
+/*
+**
+function send
+input client *http.Client
+input body []byte
+return error, when the request fails or the response is not a success
+**
+*/
+func (n notifier) send(client *http.Client, body []byte) error {
+
+	response, err := client.Post(n.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= 300 {
+		responseBody, _ := ioutil.ReadAll(response.Body)
+		return fmt.Errorf("%s notification failed with %s: %s", n.kind, response.Status, bodyExcerpt(responseBody))
+	}
+
+	return nil
+}
+
+/*
+**
+function sendNotifications
+input client *http.Client
+input Of optionalFlags, the webhooks, the template and the severity threshold are used
+input tmpl *template.Template, parsed message template
+input report RunReport
+input exitCode int
+input customDebug debug
+A notification that fails is logged, it does not change the exit code
+**
+*/
+func sendNotifications(client *http.Client, Of optionalFlags, tmpl *template.Template, report RunReport, exitCode int, customDebug debug) {
+
+	notifiers := newNotifiers(Of)
+	if len(notifiers) == 0 {
+		return
+	}
+
+	notification := newNotification(report, exitCode)
+	if !notification.shouldNotify(Of.notifyMinSeverity) {
+		customDebug.Info("No ticket above the notification threshold, nothing sent", "notifyMinSeverity", Of.notifyMinSeverity)
+		return
+	}
+
+	for _, n := range notifiers {
+		body, err := n.body(notification, tmpl)
+		if err == nil {
+			err = n.send(client, body)
+		}
+		if err != nil {
+			customDebug.Error("Could not send the notification", "notifier", n.kind, "error", err)
+			continue
+		}
+		customDebug.Info("Notification sent", "notifier", n.kind)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func notificationTestReport() RunReport {
+	return RunReport{
+		RunID:  "20240601T120000Z-1a2b3c",
+		OrgID:  "123",
+		Totals: ReportTotals{Projects: 3, ProjectsFailed: 1, ProjectsExcluded: 1, Issues: 3, Created: 2, Failed: 1},
+		Projects: []ReportProject{
+			{ProjectID: "project-a", Status: projectStatusProcessed},
+			{ProjectID: "project-b", Status: projectStatusFailed, Reason: "could not get the issues"},
+			{ProjectID: "project-c", Status: projectStatusExcluded, Reason: "project is inactive"},
+		},
+		Issues: []ReportIssue{
+			{ProjectID: "project-a", IssueID: "SNYK-JS-1", Title: "Prototype Pollution", Severity: "critical", Outcome: outcomeCreated, JiraKey: "FPI-1", JiraURL: "https://example.atlassian.net/browse/FPI-1"},
+			{ProjectID: "project-a", IssueID: "SNYK-JS-2", Title: "ReDoS", Severity: "medium", Outcome: outcomeCreated, JiraKey: "FPI-2"},
+			{ProjectID: "project-a", IssueID: "SNYK-JS-3", Title: "XSS", Severity: "high", Outcome: outcomeFailed, Reason: "Request failed"},
+			{ProjectID: "project-a", IssueID: "SNYK-JS-4", Title: "DoS", Severity: "low", Outcome: outcomeFiltered, Reason: "no upgrade available"},
+		},
+	}
+}
+
+func TestNotificationFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	notification := newNotification(notificationTestReport(), exitPartialFailure)
+
+	assert.Equal(1, len(notification.Projects))
+	assert.Equal(2, len(notification.Projects[0].Tickets))
+	assert.Equal("SNYK-JS-1", notification.Critical[0].IssueID)
+	assert.Equal("SNYK-JS-3", notification.Failed[0].IssueID)
+	assert.Equal("project-b", notification.FailedProjects[0].ProjectID)
+	assert.Equal("project-c", notification.ExcludedProjects[0].ProjectID)
+
+	assert.True(notification.shouldNotify(""))
+	assert.True(notification.shouldNotify("critical"))
+	assert.True(notification.shouldNotify("medium"))
+
+	// the failed high issue is not a new ticket
+	notification.Projects[0].Tickets = notification.Projects[0].Tickets[1:]
+	assert.False(notification.shouldNotify("high"))
+
+	tmpl, err := parseNotifyTemplate("")
+	assert.Nil(err)
+
+	body, err := notifier{kind: "slack"}.body(newNotification(notificationTestReport(), exitPartialFailure), tmpl)
+	assert.Nil(err)
+	slack := map[string]string{}
+	assert.Nil(json.Unmarshal(body, &slack))
+	assert.Equal(`Snyk to Jira sync for org 123: 2 ticket(s) created, 1 failed, 1 project(s) failed
+
+*Critical issues*
+- <https://example.atlassian.net/browse/FPI-1|FPI-1> Prototype Pollution (project project-a)
+
+*Project project-a*
+- <https://example.atlassian.net/browse/FPI-1|FPI-1> [critical] Prototype Pollution
+- FPI-2 [medium] ReDoS
+
+*Tickets not created*
+- SNYK-JS-3 (project project-a): Request failed
+
+*Projects failed*
+- project-b: could not get the issues
+
+*Projects skipped*
+- project-c: project is inactive
+`, slack["text"])
+
+	body, err = notifier{kind: "teams"}.body(newNotification(notificationTestReport(), exitPartialFailure), tmpl)
+	assert.Nil(err)
+	teams := map[string]string{}
+	assert.Nil(json.Unmarshal(body, &teams))
+	assert.Equal("MessageCard", teams["@type"])
+	assert.Contains(teams["text"], "[FPI-1](https://example.atlassian.net/browse/FPI-1) Prototype Pollution")
+
+	// custom template
+	tmpl, err = parseNotifyTemplate("{{.Totals.Created}} new tickets{{range .Critical}}, {{link .}}{{end}}")
+	assert.Nil(err)
+	body, err = notifier{kind: "webhook"}.body(newNotification(notificationTestReport(), exitPartialFailure), tmpl)
+	assert.Nil(err)
+	webhook := Notification{}
+	assert.Nil(json.Unmarshal(body, &webhook))
+	assert.Equal(4, webhook.ExitCode)
+	assert.Equal("123", webhook.OrgID)
+	assert.Contains(string(body), `"text":"2 new tickets, FPI-1 https://example.atlassian.net/browse/FPI-1"`)
+
+	_, err = parseNotifyTemplate("{{.Totals.Created")
+	assert.NotNil(err)
+}
+
+func TestSendNotificationsFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	var mu sync.Mutex
+	received := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		received[r.URL.Path] = string(data)
+		mu.Unlock()
+		if r.URL.Path == "/teams" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	Of := optionalFlags{
+		notifySlackWebhook: server.URL + "/slack",
+		notifyTeamsWebhook: server.URL + "/teams",
+		notifyWebhook:      server.URL + "/webhook",
+	}
+	tmpl, _ := parseNotifyTemplate("")
+	client := &http.Client{Timeout: 5 * time.Second}
+
+	sendNotifications(client, Of, tmpl, notificationTestReport(), exitSuccess, debug{})
+	assert.Equal(3, len(received))
+	assert.Contains(received["/slack"], "Prototype Pollution")
+
+	// below the threshold nothing is sent
+	received = map[string]string{}
+	Of.notifyMinSeverity = "critical"
+	report := notificationTestReport()
+	report.Issues = report.Issues[1:]
+	sendNotifications(client, Of, tmpl, report, exitSuccess, debug{})
+	assert.Equal(0, len(received))
+}
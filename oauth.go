@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokens are refreshed this long before they expire so a request never
+// leaves with a token about to expire
+const oauthExpiryLeeway = time.Minute
+
+// oauthTokenSource gets and caches the bearer tokens of the OAuth 2.0
+// client credentials flow
+type oauthTokenSource struct {
+	clientID     string
+	clientSecret string
+	tokenURL     string
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+	now    func() time.Time
+}
+
+// oauthTokenError is a failed request to the token endpoint, StatusCode is 0
+// when the endpoint could not be reached
+type oauthTokenError struct {
+	StatusCode int
+	Status     string
+	Cause      error
+}
+
+func (e *oauthTokenError) Error() string {
+	return e.Cause.Error()
+}
+
+func (e *oauthTokenError) Unwrap() error {
+	return e.Cause
+}
+
+// oauthTokenResponse is the response of the token endpoint
+type oauthTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+/*
+**
+function newOAuthTokenSource
+input Mf MandatoryFlags, oauthClientID, oauthClientSecret and oauthTokenURL are used
+return *oauthTokenSource, nil when OAuth is not configured
+The token URL defaults to <api>/oauth2/token
+**
+*/
+func newOAuthTokenSource(Mf MandatoryFlags) *oauthTokenSource {
+
+	if len(Mf.oauthClientID) == 0 {
+		return nil
+	}
+
+	tokenURL := Mf.oauthTokenURL
+	if len(tokenURL) == 0 {
+		tokenURL = strings.TrimSuffix(Mf.endpointAPI, "/") + "/oauth2/token"
+	}
+
+	return &oauthTokenSource{
+		clientID:     Mf.oauthClientID,
+		clientSecret: Mf.oauthClientSecret,
+		tokenURL:     tokenURL,
+		now:          time.Now,
+	}
+}
+
+/*
+**
+function Token
+input httpClient *http.Client, used to call the token endpoint so the proxy and TLS settings apply
+input customDebug debug
+return string, a valid bearer token
+return error, when no token could be obtained
+The token is cached until shortly before it expires, concurrent callers wait for
+the same refresh
+**
+*/
+func (s *oauthTokenSource) Token(httpClient *http.Client, customDebug debug) (string, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.token) > 0 && s.now().Before(s.expiry.Add(-oauthExpiryLeeway)) {
+		return s.token, nil
+	}
+
+	customDebug.Debug("Requesting a new OAuth token", "endpoint", s.tokenURL)
+
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_id", s.clientID)
+	form.Set("client_secret", s.clientSecret)
+
+	request, err := http.NewRequest("POST", s.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return "", &oauthTokenError{Cause: fmt.Errorf("could not reach the OAuth token endpoint %s: %s", s.tokenURL, err.Error())}
+	}
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return "", &oauthTokenError{Cause: fmt.Errorf("could not read the OAuth token response: %s", err.Error())}
+	}
+
+	if response.StatusCode >= 300 {
+		return "", &oauthTokenError{StatusCode: response.StatusCode, Status: response.Status, Cause: fmt.Errorf("OAuth token request to %s failed with %s", s.tokenURL, response.Status)}
+	}
+
+	tokenResponse := oauthTokenResponse{}
+	err = json.Unmarshal(body, &tokenResponse)
+	if err != nil {
+		return "", fmt.Errorf("could not read the OAuth token response: %s", err.Error())
+	}
+
+	if len(tokenResponse.AccessToken) == 0 {
+		return "", errors.New("the OAuth token response has no access_token")
+	}
+
+	if len(tokenResponse.TokenType) > 0 && !strings.EqualFold(tokenResponse.TokenType, "bearer") {
+		return "", fmt.Errorf("unsupported OAuth token type %s", tokenResponse.TokenType)
+	}
+
+	// without expiry the token is used until the API refuses it
+	expiresIn := time.Duration(tokenResponse.ExpiresIn) * time.Second
+	if expiresIn <= 0 {
+		expiresIn = 24 * time.Hour
+	}
+
+	s.token = tokenResponse.AccessToken
+	s.expiry = s.now().Add(expiresIn)
+
+	return s.token, nil
+}
+
+/*
+**
+function Invalidate
+input token string, the token refused by the API
+Forget the token so the next request gets a new one. A token already
+replaced by another request is kept.
+**
+*/
+func (s *oauthTokenSource) Invalidate(token string) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token == token {
+		s.token = ""
+	}
+}
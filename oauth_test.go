@@ -0,0 +1,149 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOAuthTokenSourceFunc(t *testing.T) {
+
+	assert := assert.New(t)
+	cD := debug{}
+	cD.setDebug(false)
+
+	issued := 0
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("grant_type") != "client_credentials" || r.Form.Get("client_id") != "id" || r.Form.Get("client_secret") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		issued++
+		fmt.Fprintf(w, `{"access_token": "token-%d", "token_type": "bearer", "expires_in": 3600}`, issued)
+	}))
+	defer tokenServer.Close()
+
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	source := newOAuthTokenSource(MandatoryFlags{oauthClientID: "id", oauthClientSecret: "secret", oauthTokenURL: tokenServer.URL})
+	source.now = func() time.Time { return now }
+
+	token, err := source.Token(http.DefaultClient, cD)
+	assert.Nil(err)
+	assert.Equal("token-1", token)
+
+	// cached
+	token, _ = source.Token(http.DefaultClient, cD)
+	assert.Equal("token-1", token)
+
+	// refreshed before it expires
+	now = now.Add(59*time.Minute + 30*time.Second)
+	token, _ = source.Token(http.DefaultClient, cD)
+	assert.Equal("token-2", token)
+
+	// an old token refused by the API does not drop the new one
+	source.Invalidate("token-1")
+	token, _ = source.Token(http.DefaultClient, cD)
+	assert.Equal("token-2", token)
+
+	source.Invalidate("token-2")
+	token, _ = source.Token(http.DefaultClient, cD)
+	assert.Equal("token-3", token)
+
+	wrongSecret := newOAuthTokenSource(MandatoryFlags{oauthClientID: "id", oauthClientSecret: "wrong", oauthTokenURL: tokenServer.URL})
+	_, err = wrongSecret.Token(http.DefaultClient, cD)
+	assert.NotNil(err)
+
+	assert.Nil(newOAuthTokenSource(MandatoryFlags{apiToken: "123"}))
+	assert.Equal("https://api.snyk.io/oauth2/token", newOAuthTokenSource(MandatoryFlags{endpointAPI: "https://api.snyk.io/", oauthClientID: "id"}).tokenURL)
+}
+
+func TestOAuthBearerRequestsFunc(t *testing.T) {
+
+	assert := assert.New(t)
+	cD := debug{}
+	cD.setDebug(false)
+	CreateLogFile(cD, "ErrorsFile_")
+
+	issued := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/oauth2/token" {
+			issued++
+			fmt.Fprintf(w, `{"access_token": "token-%d", "token_type": "bearer", "expires_in": 3600}`, issued)
+			return
+		}
+		// the first token is revoked
+		if r.Header.Get("Authorization") != "Bearer token-2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"data": [], "links": {}}`))
+	}))
+	defer server.Close()
+
+	defaultSnykClient.auth = newOAuthTokenSource(MandatoryFlags{endpointAPI: server.URL, oauthClientID: "id", oauthClientSecret: "secret"})
+	defer func() { defaultSnykClient.auth = nil }()
+
+	// v1 and REST use the same bearer token
+	_, err := makeSnykAPIRequest("GET", server.URL+"/v1/org/123/project/456", "", nil, cD)
+	assert.Nil(err)
+	_, err = makeSnykAPIRequest_REST("GET", server.URL, "/rest/orgs/123/projects?version=2024-10-15", "", nil, cD)
+	assert.Nil(err)
+	assert.Equal(2, issued)
+
+	removeLogFile()
+}
+
+func TestOAuthTokenRetryFunc(t *testing.T) {
+
+	assert := assert.New(t)
+	cD := debug{}
+	cD.setDebug(false)
+	CreateLogFile(cD, "ErrorsFile_")
+	defer removeLogFile()
+
+	tokenStatus := []int{http.StatusServiceUnavailable, http.StatusOK}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/oauth2/token" {
+			status := tokenStatus[0]
+			if len(tokenStatus) > 1 {
+				tokenStatus = tokenStatus[1:]
+			}
+			if status != http.StatusOK {
+				w.WriteHeader(status)
+				return
+			}
+			w.Write([]byte(`{"access_token": "token-1", "token_type": "bearer", "expires_in": 3600}`))
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	defaultSnykClient.auth = newOAuthTokenSource(MandatoryFlags{endpointAPI: server.URL, oauthClientID: "id", oauthClientSecret: "secret"})
+	defer func() { defaultSnykClient.auth = nil }()
+	defaultRunStatus = &runStatus{}
+
+	// the token endpoint is down for a moment
+	_, err := makeSnykAPIRequest("GET", server.URL+"/v1/org/123/project/456", "", nil, cD)
+	assert.Nil(err)
+
+	// an outage is not an authentication failure
+	defaultSnykClient.auth = newOAuthTokenSource(MandatoryFlags{endpointAPI: server.URL, oauthClientID: "id", oauthClientSecret: "secret"})
+	tokenStatus = []int{http.StatusBadGateway}
+	_, err = makeSnykAPIRequest("GET", server.URL+"/v1/org/123/project/456", "", nil, cD)
+	assert.True(errors.Is(err, ErrServer))
+	assert.False(defaultRunStatus.hasAuthFailed())
+
+	// refused credentials are
+	tokenStatus = []int{http.StatusUnauthorized}
+	_, err = makeSnykAPIRequest("GET", server.URL+"/v1/org/123/project/456", "", nil, cD)
+	assert.True(errors.Is(err, ErrUnauthorized))
+	assert.True(defaultRunStatus.hasAuthFailed())
+	defaultRunStatus = &runStatus{}
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrorsFilePrefix is the prefix of the error journal of a run
+const ErrorsFilePrefix = "ErrorsFile_"
+
+// TicketsFilePrefix is the prefix of the file listing the tickets of a run
+const TicketsFilePrefix = "listOfTicketCreated_"
+
+// maxJournalBodySize is the size of the HTTP body excerpt kept in the journal
+const maxJournalBodySize = 512
+
+// runOutput is where the files of a run are written, every run has its own ID
+// so concurrent runs sharing a directory never write in the same files
+type runOutput struct {
+	dir       string
+	runID     string
+	journalMu sync.Mutex
+}
+
+var defaultRunOutput = &runOutput{runID: newRunID(time.Now())}
+
+/*
+**
+function newRunID
+input now time.Time, start of the run
+return string, sortable UTC timestamp followed by a random suffix like 20240601T120000Z-1a2b3c
+**
+*/
+func newRunID(now time.Time) string {
+
+	suffix := make([]byte, 3)
+	if _, err := rand.Read(suffix); err != nil {
+		// the nanoseconds still tell apart runs started in the same second
+		return fmt.Sprintf("%s-%06x", now.UTC().Format("20060102T150405Z"), now.Nanosecond()&0xffffff)
+	}
+
+	return now.UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix)
+}
+
+/*
+**
+function newRunOutput
+input dir string, directory of the output files, the working directory when empty
+input now time.Time, start of the run
+return *runOutput
+return error, when the directory cannot be created
+**
+*/
+func newRunOutput(dir string, now time.Time) (*runOutput, error) {
+
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	return &runOutput{dir: dir, runID: newRunID(now)}, nil
+}
+
+/*
+**
+function path
+input prefix string, kind of file like ErrorsFile_
+input extension string
+return string, the file of this run in the output directory
+**
+*/
+func (o *runOutput) path(prefix string, extension string) string {
+	return filepath.Join(o.dir, prefix+o.runID+extension)
+}
+
+/*
+**
+function resolve
+input filename string, file chosen by the user
+return string, relative files are placed in the output directory
+**
+*/
+func (o *runOutput) resolve(filename string) string {
+
+	if filename == "" || filepath.IsAbs(filename) {
+		return filename
+	}
+
+	return filepath.Join(o.dir, filename)
+}
+
+// journalPath is the error journal of the run
+func (o *runOutput) journalPath() string {
+	return o.path(ErrorsFilePrefix, ".jsonl")
+}
+
+// journalEntry is one line of the error journal
+type journalEntry struct {
+	Time      time.Time `json:"time"`
+	RunID     string    `json:"runId"`
+	Severity  string    `json:"severity"`
+	Function  string    `json:"function"`
+	Message   string    `json:"message"`
+	OrgID     string    `json:"org,omitempty"`
+	ProjectID string    `json:"project,omitempty"`
+	IssueID   string    `json:"issue,omitempty"`
+	Endpoint  string    `json:"endpoint,omitempty"`
+	Status    int       `json:"status,omitempty"`
+	Body      string    `json:"body,omitempty"`
+}
+
+/*
+**
+function appendJournal
+input entry journalEntry
+return error
+The entry is written with a single append so the lines of parallel
+projects and of other processes never interleave
+**
+*/
+func (o *runOutput) appendJournal(entry journalEntry) error {
+
+	entry.RunID = o.runID
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	o.journalMu.Lock()
+	defer o.journalMu.Unlock()
+
+	f, err := os.OpenFile(o.journalPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(append(data, '\n'))
+	closeErr := f.Close()
+	if err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+/*
+**
+function parseSeverity
+input errorText string, message that may start with *** ERROR ***, *** WARN *** or *** INFO ***
+return string, the severity, error by default
+return string, the message without the severity marker
+**
+*/
+func parseSeverity(errorText string) (string, string) {
+
+	message := strings.TrimSpace(errorText)
+	for _, severity := range []string{"error", "warn", "info"} {
+		marker := "*** " + strings.ToUpper(severity) + " ***"
+		if strings.HasPrefix(message, marker) {
+			return severity, strings.TrimSpace(strings.TrimPrefix(message, marker))
+		}
+	}
+
+	return "error", message
+}
+
+/*
+**
+function newJournalEntry
+input function string, function where the error happened
+input errorText string
+input customDebug debug, the org, project and issue fields of the logger give the context
+return journalEntry
+**
+*/
+func newJournalEntry(function string, errorText string, customDebug debug) journalEntry {
+
+	severity, message := parseSeverity(errorText)
+	entry := journalEntry{
+		Time:     defaultLogSink.now().UTC(),
+		Severity: severity,
+		Function: function,
+		Message:  message,
+	}
+
+	for index := 0; index+1 < len(customDebug.fields); index += 2 {
+		value := fmt.Sprint(customDebug.fields[index+1])
+		switch customDebug.fields[index] {
+		case "org":
+			entry.OrgID = value
+		case "project":
+			entry.ProjectID = value
+		case "issue":
+			entry.IssueID = value
+		case "endpoint":
+			entry.Endpoint = value
+		}
+	}
+
+	return entry
+}
+
+// bodyExcerpt keeps the beginning of a response body for the journal
+func bodyExcerpt(body []byte) string {
+
+	if len(body) <= maxJournalBodySize {
+		return string(body)
+	}
+
+	return string(body[:maxJournalBodySize]) + "..."
+}
+
+/*
+**
+function writeFileAtomic
+input filename string
+input data []byte
+input perm os.FileMode
+return error
+The data is written in a temporary file of the same directory then renamed,
+so readers never see a half written file
+**
+*/
+func writeFileAtomic(filename string, data []byte, perm os.FileMode) error {
+
+	dir := filepath.Dir(filename)
+	tmpFile, err := ioutil.TempFile(dir, "."+filepath.Base(filename)+".tmp-*")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmpFile.Write(data)
+	if err == nil {
+		err = tmpFile.Chmod(perm)
+	}
+	closeErr := tmpFile.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		return err
+	}
+
+	if err := os.Rename(tmpFile.Name(), filename); err != nil {
+		os.Remove(tmpFile.Name())
+		return err
+	}
+
+	return nil
+}
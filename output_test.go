@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunOutputFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	dir := filepath.Join(t.TempDir(), "runs", "nightly")
+
+	output, err := newRunOutput(dir, now)
+	assert.Nil(err)
+	assert.DirExists(dir)
+	assert.True(strings.HasPrefix(output.runID, "20240601T120000Z-"))
+
+	// two runs started in the same second don't share their files
+	other, _ := newRunOutput(dir, now)
+	assert.NotEqual(output.runID, other.runID)
+
+	assert.Equal(filepath.Join(dir, "ErrorsFile_"+output.runID+".jsonl"), output.journalPath())
+	assert.Equal(filepath.Join(dir, "report.csv"), output.resolve("report.csv"))
+	assert.Equal("/tmp/report.csv", output.resolve("/tmp/report.csv"))
+	assert.Equal("", output.resolve(""))
+
+	// the working directory is used by default
+	current, _ := newRunOutput("", now)
+	assert.Equal("listOfTicketCreated_"+current.runID+".json", current.path(TicketsFilePrefix, ".json"))
+}
+
+func TestErrorJournalFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	output, _ := newRunOutput(t.TempDir(), time.Now())
+	oldOutput := defaultRunOutput
+	defaultRunOutput = output
+	defer func() { defaultRunOutput = oldOutput }()
+
+	cD := debug{}
+	projectDebug := cD.With("org", "123", "project", "abc")
+
+	writeErrorFile("getJiraTickets", "Could not get the tickets\n", projectDebug)
+	writeErrorFile("getVulnsWithoutTicket", " *** WARN *** IAC projects are not supported", projectDebug.With("issue", "SNYK-JS-1"))
+
+	body := []byte(strings.Repeat("a", maxJournalBodySize+10))
+	reportSnykAPIError(&SnykAPIError{Kind: ErrServer, StatusCode: 500, Status: "500 Internal Server Error", Endpoint: "/v1/org/123", Body: body}, projectDebug)
+
+	f, err := os.Open(output.journalPath())
+	assert.Nil(err)
+	defer f.Close()
+
+	entries := []journalEntry{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		entry := journalEntry{}
+		assert.Nil(json.Unmarshal(scanner.Bytes(), &entry))
+		entries = append(entries, entry)
+	}
+	assert.Equal(3, len(entries))
+
+	assert.Equal("error", entries[0].Severity)
+	assert.Equal("getJiraTickets", entries[0].Function)
+	assert.Equal("Could not get the tickets", entries[0].Message)
+	assert.Equal("123", entries[0].OrgID)
+	assert.Equal("abc", entries[0].ProjectID)
+	assert.Equal(output.runID, entries[0].RunID)
+	assert.False(entries[0].Time.IsZero())
+
+	assert.Equal("warn", entries[1].Severity)
+	assert.Equal("IAC projects are not supported", entries[1].Message)
+	assert.Equal("SNYK-JS-1", entries[1].IssueID)
+
+	assert.Equal("makeSnykAPIRequest", entries[2].Function)
+	assert.Equal("/v1/org/123", entries[2].Endpoint)
+	assert.Equal(500, entries[2].Status)
+	assert.Equal(strings.Repeat("a", maxJournalBodySize)+"...", entries[2].Body)
+
+	// a journal that can't be written doesn't stop the run
+	defaultRunOutput = &runOutput{dir: filepath.Join(t.TempDir(), "missing"), runID: "run"}
+	writeErrorFile("getJiraTickets", "Could not get the tickets", cD)
+}
+
+func TestWriteFileAtomicFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "listOfTicketCreated_run.json")
+
+	assert.Nil(writeFileAtomic(filename, []byte("first"), 0644))
+	assert.Nil(writeFileAtomic(filename, []byte("second"), 0644))
+
+	data, err := os.ReadFile(filename)
+	assert.Nil(err)
+	assert.Equal("second", string(data))
+
+	// no temporary file is left behind
+	files, _ := os.ReadDir(dir)
+	assert.Equal(1, len(files))
+
+	assert.NotNil(writeFileAtomic(filepath.Join(dir, "missing", "file.json"), []byte("data"), 0644))
+}
@@ -0,0 +1,400 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/michael-go/go-jsn/jsn"
+)
+
+// PlanFilePrefix is the prefix of the plan file written by the plan command
+const PlanFilePrefix = "plan_"
+
+// planVersion is increased when the plan file format changes
+const planVersion = 1
+
+// Plan is the list of tickets written by the plan command and created by the apply command
+type Plan struct {
+	Version   int        `json:"version"`
+	RunID     string     `json:"runId"`
+	OrgID     string     `json:"orgId"`
+	CreatedAt time.Time  `json:"createdAt"`
+	Items     []PlanItem `json:"items"`
+}
+
+// PlanItem is a ticket to create, the hash covers the Snyk identifiers, the endpoint and the payload
+type PlanItem struct {
+	ProjectID   string `json:"projectId"`
+	IssueID     string `json:"issueId"`
+	JiraProject string `json:"jiraProject"`
+	// jira, or github for the GitHub issues of the jiraProject repository
+	Tracker  string          `json:"tracker"`
+	Summary  string          `json:"summary"`
+	Endpoint string          `json:"endpoint"`
+	Payload  json.RawMessage `json:"payload"`
+	Hash     string          `json:"hash"`
+}
+
+/*
+**
+function newPlan
+input orgID string
+input endpointAPI string, removed from the endpoint of the tickets so the plan can be applied through another API URL
+input projectsTickets map[string]interface{}, the []Tickets of the dry run per project
+input createdAt time.Time
+return Plan, the items sorted by project then by issue
+**
+*/
+func newPlan(orgID string, endpointAPI string, projectsTickets map[string]interface{}, createdAt time.Time) Plan {
+
+	plan := Plan{
+		Version:   planVersion,
+		RunID:     defaultRunOutput.runID,
+		OrgID:     orgID,
+		CreatedAt: createdAt.UTC(),
+		Items:     []PlanItem{},
+	}
+
+	for projectID, tickets := range projectsTickets {
+		ticketList, _ := tickets.([]Tickets)
+		for _, ticket := range ticketList {
+			if len(ticket.Payload) == 0 {
+				continue
+			}
+			item := PlanItem{
+				ProjectID:   projectID,
+				IssueID:     ticket.IssueID,
+				JiraProject: ticket.JiraProject,
+				Tracker:     ticket.Tracker,
+				Summary:     ticket.Summary,
+				Endpoint:    strings.TrimPrefix(ticket.Endpoint, endpointAPI),
+				Payload:     ticket.Payload,
+			}
+			item.Hash = item.hash(orgID)
+			plan.Items = append(plan.Items, item)
+		}
+	}
+
+	sort.SliceStable(plan.Items, func(i, j int) bool {
+		if plan.Items[i].ProjectID != plan.Items[j].ProjectID {
+			return plan.Items[i].ProjectID < plan.Items[j].ProjectID
+		}
+		return plan.Items[i].IssueID < plan.Items[j].IssueID
+	})
+
+	return plan
+}
+
+/*
+**
+function hash
+input orgID string
+return string, hex sha256 of the org, project, issue, endpoint, tracker, target and compacted payload
+The payload is compacted so the indentation of the plan file doesn't change the hash.
+**
+*/
+func (item PlanItem) hash(orgID string) string {
+
+	parts := []string{orgID, item.ProjectID, item.IssueID, item.Endpoint, item.Tracker, item.JiraProject}
+
+	sum := sha256.New()
+	for _, part := range parts {
+		sum.Write([]byte(part))
+		sum.Write([]byte{0})
+	}
+	sum.Write(compactJSON(item.Payload))
+
+	return "sha256:" + hex.EncodeToString(sum.Sum(nil))
+}
+
+/*
+**
+function writePlan
+input plan Plan
+input filename string
+return error
+**
+*/
+func writePlan(plan Plan, filename string) error {
+
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(filename, data, 0644)
+}
+
+/*
+**
+function readPlan
+input filename string
+input orgID string, the org of the run, it must be the org of the plan
+return Plan
+return error, when the file cannot be read, is for another org or an item was changed
+**
+*/
+func readPlan(filename string, orgID string) (Plan, error) {
+
+	plan := Plan{}
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return plan, err
+	}
+
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return plan, fmt.Errorf("not a valid plan file: %w", err)
+	}
+
+	if plan.Version != planVersion {
+		return plan, fmt.Errorf("plan version %d is not supported, expected %d", plan.Version, planVersion)
+	}
+
+	if plan.OrgID != orgID {
+		return plan, fmt.Errorf("the plan was created for org %s, not %s", plan.OrgID, orgID)
+	}
+
+	// an item edited after the plan was written is never applied
+	for _, item := range plan.Items {
+		if item.Hash != item.hash(plan.OrgID) {
+			return plan, fmt.Errorf("the hash of issue %s of project %s does not match, the plan was changed", item.IssueID, item.ProjectID)
+		}
+	}
+
+	return plan, nil
+}
+
+// projectIDs lists the projects of the plan in the order of the items
+func (plan Plan) projectIDs() []string {
+
+	projectIDs := []string{}
+	for _, item := range plan.Items {
+		if len(projectIDs) == 0 || projectIDs[len(projectIDs)-1] != item.ProjectID {
+			projectIDs = append(projectIDs, item.ProjectID)
+		}
+	}
+
+	return projectIDs
+}
+
+// itemsFor lists the items of a project
+func (plan Plan) itemsFor(projectID string) []PlanItem {
+
+	items := []PlanItem{}
+	for _, item := range plan.Items {
+		if item.ProjectID == projectID {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+/*
+**
+function applyProject
+input options flags
+input project string, the ID of the project
+input items []PlanItem, the tickets of the plan for this project
+input maturityFilter []string
+input customDebug debug
+return projectResult, the output of the project and the tickets created
+Create the tickets of the plan. An item is refused when its issue has a ticket
+or is not open anymore (fixed, ignored or not matching the filters of the run).
+**
+*/
+func applyProject(options flags, project string, items []PlanItem, maturityFilter []string, customDebug debug) projectResult {
+
+	result := projectResult{log: &logBuffer{}}
+	customDebug = customDebug.Buffered(result.log).With("org", options.mandatoryFlags.orgID, "project", project)
+
+	// the items of a project were routed to the same tracker when the plan was written
+	tracker := options.trackerOf("", "")
+	if len(items) > 0 {
+		tracker = options.trackerOf(items[0].Tracker, items[0].JiraProject)
+	}
+
+	customDebug.Info("Step 1/3 - Retrieving a list of existing tickets", "tracker", tracker.name(), "target", tracker.target())
+	tickets, err := tracker.existingTickets(project, customDebug)
+	if err != nil {
+		customDebug.Error("Could not get already existing tickets details. Skipping project", "error", err)
+		recordProject(project, projectStatusFailed, "could not get the existing tickets")
+		defaultRunStatus.projectFailed()
+		result.failed = true
+		return result
+	}
+	tickets = defaultStateStore.mergeTickets(project, tickets, customDebug)
+	// the tickets created by the previous apply of the plan
+	tickets = defaultCheckpoint.mergeTickets(project, tickets, customDebug)
+
+	// the planned issues are listed even when ticketed, a refused item is reported with the details of its issue
+	planned := make(map[string]bool, len(items))
+	for _, item := range items {
+		planned[item.IssueID] = true
+	}
+	notPlannedTickets := make(map[string]string, len(tickets))
+	for issueID, jiraKey := range tickets {
+		if !planned[issueID] {
+			notPlannedTickets[issueID] = jiraKey
+		}
+	}
+
+	customDebug.Info("Step 2/3 - Getting open issues")
+	openIssues, _, err := getVulnsWithoutTicket(options, project, maturityFilter, notPlannedTickets, customDebug)
+	if err != nil {
+		customDebug.Error("Could not get vulnerability details. Skipping project", "error", err)
+		recordProject(project, projectStatusFailed, "could not get the issues")
+		defaultRunStatus.projectFailed()
+		result.failed = true
+		return result
+	}
+
+	customDebug.Info("Step 3/3 - Applying the plan", "items", len(items))
+	var ticketArray []Tickets
+	issueCreated := 0
+	notApplied := ""
+	// the tickets are listed again when a ticket is pending, its result is unknown
+	pending := &pendingLookup{tracker: tracker, projectID: project}
+
+	for _, item := range items {
+
+		customDebug := customDebug.With("issue", item.IssueID)
+
+		vuln, open := openIssues[item.IssueID]
+		jsonVuln := planItemIssue(item)
+		if open {
+			jsonVuln, _ = jsn.NewJson(vuln)
+		}
+
+		if jiraKey, found := tickets[item.IssueID]; found {
+			customDebug.Warn("Issue already has a ticket, not applied", "jiraKey", jiraKey)
+			writeErrorFile("applyProject", fmt.Sprintf("*** WARN *** Issue %s already has the ticket %s, not applied\n", item.IssueID, jiraKey), customDebug)
+			notApplied += "\nissue ID: " + item.IssueID + " already ticketed (" + jiraKey + ")"
+			recordIssue(project, jsonVuln, outcomeAlreadyTicketed, "ticketed since the plan was written", jiraKey)
+			continue
+		}
+
+		if !open {
+			customDebug.Warn("Issue is not open anymore, not applied")
+			writeErrorFile("applyProject", fmt.Sprintf("*** WARN *** Issue %s is fixed, ignored or does not match the filters anymore, not applied\n", item.IssueID), customDebug)
+			notApplied += "\nissue ID: " + item.IssueID + " not open anymore"
+			recordIssue(project, jsonVuln, outcomeSkipped, "fixed, ignored or not matching the filters since the plan was written", "")
+			continue
+		}
+
+		if options.optionalFlags.dryRun {
+			recordIssue(project, jsonVuln, outcomeDryRun, "", "")
+			defaultRunStatus.ticketCreated()
+			continue
+		}
+
+		// posted by an apply of the plan that was interrupted, the ticket is only created when the tracker doesn't have it
+		if defaultCheckpoint.isPending(project, item.IssueID) {
+			jiraKey, err := pending.find(item.IssueID, customDebug)
+			if err != nil {
+				customDebug.Warn("Ticket may have been created by the previous apply, not applied", "error", err)
+				writeErrorFile("applyProject", fmt.Sprintf("*** WARN *** Ticket for %s may have been created by the previous apply and the tickets could not be listed, not applied\n", item.IssueID), customDebug)
+				notApplied += "\nissue ID: " + item.IssueID + " may have been created by the previous apply"
+				recordIssue(project, jsonVuln, outcomeSkipped, "ticket may have been created by the previous apply", "")
+				continue
+			}
+			if jiraKey != "" {
+				customDebug.Info("Ticket created by the previous apply", "jiraKey", jiraKey)
+				notApplied += "\nissue ID: " + item.IssueID + " already ticketed (" + jiraKey + ")"
+				recordIssue(project, jsonVuln, outcomeAlreadyTicketed, "created by the previous apply", jiraKey)
+				continue
+			}
+		}
+
+		ticket, err := applyPlanItem(tracker, options.mandatoryFlags, item, customDebug)
+		if err != nil {
+			customDebug.Error("Ticket not created", "endpoint", item.Endpoint, "error", err)
+			writeErrorFile("applyProject", fmt.Sprintf("*** ERROR *** Failed to open a ticket: %s\nERROR:%s", item.Endpoint, err), customDebug)
+			notApplied += "\nissue ID: " + item.IssueID + " failed"
+			recordIssue(project, jsonVuln, outcomeFailed, fmt.Sprint(err), "")
+			defaultRunStatus.ticketFailed()
+			continue
+		}
+
+		issueCreated++
+		ticketArray = append(ticketArray, *ticket)
+		recordIssue(project, jsonVuln, outcomeCreated, "", ticketKey(ticket))
+		defaultStateStore.setContentHash(project, item.IssueID, payloadHash(item.Payload))
+		defaultRunStatus.ticketCreated()
+	}
+
+	customDebug.Info("Project done", "dryRun", options.optionalFlags.dryRun, "ticketsCreated", issueCreated, "ticketsNotApplied", notApplied)
+	result.summary = fmt.Sprintf("\n----------PROJECT ID %s---------- \n Number of tickets created: %d\n List of issueIds of the plan not applied: %s\n-------------------------------------------------------------------\n", project, issueCreated, notApplied)
+	result.projectsTickets = map[string]interface{}{project: ticketArray}
+
+	return result
+}
+
+/*
+**
+function applyPlanItem
+input tr tracker, the tracker of the item
+input Mf MandatoryFlags
+input item PlanItem
+input customDebug debug
+return *Tickets, the created ticket for the list of tickets
+return error, when the request fails or the response is empty
+**
+*/
+func applyPlanItem(tr tracker, Mf MandatoryFlags, item PlanItem, customDebug debug) (*Tickets, error) {
+
+	// the Snyk endpoints are kept relative to the API URL, the GitHub ones are absolute
+	endpoint := item.Endpoint
+	if tr.name() == TrackerJira {
+		endpoint = Mf.endpointAPI + item.Endpoint
+	}
+
+	// written before the request, a crash before the result is known must not lead to a second ticket
+	defaultCheckpoint.ticketPending(item.ProjectID, item.IssueID)
+
+	_, issueDetail, err := tr.create(trackerRequest{IssueID: item.IssueID, Endpoint: endpoint, Payload: item.Payload}, customDebug)
+	if err != nil {
+		// after a 5xx or a connection error the ticket may exist, it stays pending until it is found
+		if !errors.Is(err, ErrServer) && !errors.Is(err, ErrConnection) {
+			defaultCheckpoint.ticketFailed(item.ProjectID, item.IssueID)
+		}
+		return nil, err
+	}
+
+	payload, _ := jsn.NewJson(item.Payload)
+	summary := payload.K("fields").K("summary").String().Value
+	description := payload.K("fields").K("description").String().Value
+	if tr.name() == TrackerGitHub {
+		summary = payload.K("title").String().Value
+		description = payload.K("body").String().Value
+	}
+
+	ticket := &Tickets{
+		Summary:         summary,
+		Description:     description,
+		JiraIssueDetail: issueDetail,
+	}
+	defaultCheckpoint.ticketCreated(item.ProjectID, item.IssueID, ticketKey(ticket))
+
+	return ticket, nil
+}
+
+// planItemIssue is the issue of a plan item in the shape of the aggregated issues, for
+// the items whose issue is not open anymore. The summary of the ticket is its title
+func planItemIssue(item PlanItem) jsn.Json {
+
+	issue, _ := jsn.NewJson(map[string]interface{}{
+		"id":        item.IssueID,
+		"issueData": map[string]interface{}{"title": item.Summary},
+	})
+
+	return issue
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	projectsTickets := map[string]interface{}{
+		"project-b": []Tickets{
+			{Summary: "ReDoS", IssueID: "SNYK-JS-2", JiraProject: "FPI", Endpoint: "https://api.snyk.io/v1/org/123/project/project-b/issue/SNYK-JS-2/jira-issue", Payload: json.RawMessage(`{"fields":{"summary":"ReDoS"}}`)},
+		},
+		"project-a": []Tickets{
+			{Summary: "XSS", IssueID: "SNYK-JS-3", JiraProject: "FPI", Endpoint: "https://api.snyk.io/v1/org/123/project/project-a/issue/SNYK-JS-3/jira-issue", Payload: json.RawMessage(`{"fields":{"summary":"XSS"}}`)},
+			{Summary: "DoS", IssueID: "SNYK-JS-1", JiraProject: "FPI", Endpoint: "https://api.snyk.io/v1/org/123/project/project-a/issue/SNYK-JS-1/jira-issue", Payload: json.RawMessage(`{"fields":{"summary":"DoS"}}`)},
+			// a created ticket has no payload, it is not planned
+			{Summary: "Created"},
+		},
+	}
+
+	plan := newPlan("123", "https://api.snyk.io", projectsTickets, time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+
+	assert.Equal(3, len(plan.Items))
+	assert.Equal("SNYK-JS-1", plan.Items[0].IssueID)
+	assert.Equal("SNYK-JS-3", plan.Items[1].IssueID)
+	assert.Equal("project-b", plan.Items[2].ProjectID)
+	assert.Equal("/v1/org/123/project/project-a/issue/SNYK-JS-1/jira-issue", plan.Items[0].Endpoint)
+	assert.True(strings.HasPrefix(plan.Items[0].Hash, "sha256:"))
+
+	// the tracker and the target of every item are hashed
+	item := plan.Items[0]
+	item.Tracker = TrackerJira
+	moved := item
+	moved.JiraProject = "OTHER"
+	assert.NotEqual(item.hash("123"), moved.hash("123"))
+	moved = item
+	moved.Tracker = TrackerGitHub
+	assert.NotEqual(item.hash("123"), moved.hash("123"))
+	assert.Equal([]string{"project-a", "project-b"}, plan.projectIDs())
+	assert.Equal(2, len(plan.itemsFor("project-a")))
+
+	// the indentation of the plan file doesn't change the hash
+	filename := filepath.Join(t.TempDir(), "plan.json")
+	assert.Nil(writePlan(plan, filename))
+	read, err := readPlan(filename, "123")
+	assert.Nil(err)
+	assert.Equal(plan.Items[0].Hash, read.Items[0].Hash)
+	assert.JSONEq(string(plan.Items[0].Payload), string(read.Items[0].Payload))
+
+	_, err = readPlan(filename, "456")
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "org 123")
+
+	// an edited payload is refused
+	data, _ := ioutil.ReadFile(filename)
+	assert.Nil(ioutil.WriteFile(filename, []byte(strings.ReplaceAll(string(data), `"DoS"`, `"Denial of Service"`)), 0644))
+	_, err = readPlan(filename, "123")
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "SNYK-JS-1")
+
+	_, err = readPlan(filepath.Join(t.TempDir(), "missing.json"), "123")
+	assert.NotNil(err)
+}
+
+func TestApplyProjectFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	server := HTTPResponseEndToEnd()
+	defer server.Close()
+
+	cD := debug{}
+	CreateLogFile(cD, ErrorsFilePrefix)
+	defer removeLogFile()
+
+	options := flags{}
+	options.mandatoryFlags = MandatoryFlags{orgID: "123", endpointAPI: server.URL, apiToken: "123", jiraProjectKey: "FPI"}
+	options.optionalFlags = optionalFlags{severity: "low", issueType: "all", issueConcurrency: 1}
+
+	items := []PlanItem{
+		{ProjectID: "123", IssueID: "SNYK-JS-ACORN-559469", Endpoint: "/v1/org/123/project/123/issue/SNYK-JS-ACORN-559469/jira-issue", Payload: json.RawMessage(`{"fields":{"summary":"Acorn ReDoS"}}`)},
+		// ticketed since the plan was written
+		{ProjectID: "123", IssueID: "SNYK-JS-HANDLEBARS-174183", Endpoint: "/v1/org/123/project/123/issue/SNYK-JS-HANDLEBARS-174183/jira-issue", Payload: json.RawMessage(`{}`)},
+		// fixed since the plan was written
+		{ProjectID: "123", IssueID: "SNYK-JS-FIXED-1", Endpoint: "/v1/org/123/project/123/issue/SNYK-JS-FIXED-1/jira-issue", Payload: json.RawMessage(`{}`)},
+	}
+
+	c, _, _ := openCheckpoint(planCheckpointPath("123", "20240601T120000Z"), false, time.Now())
+	defaultCheckpoint = c
+	defer func() { defaultCheckpoint = nil; os.Remove(c.filename) }()
+
+	defaultRunStatus = &runStatus{}
+	defaultRunReport = newRunReport(options.optionalFlags, "123", time.Now())
+	defer func() { defaultRunReport = nil }()
+	result := applyProject(options, "123", items, nil, cD)
+
+	assert.False(result.failed)
+	assert.Equal(1, defaultRunStatus.ticketsCreated)
+	assert.Equal(0, defaultRunStatus.ticketsFailed)
+
+	tickets := result.projectsTickets["123"].([]Tickets)
+	assert.Equal(1, len(tickets))
+	assert.Equal("Acorn ReDoS", tickets[0].Summary)
+	assert.Equal("FPI-001", ticketKey(&tickets[0]))
+
+	assert.Contains(result.summary, "Number of tickets created: 1")
+	assert.Contains(result.summary, "SNYK-JS-HANDLEBARS-174183 already ticketed")
+	assert.Contains(result.summary, "SNYK-JS-FIXED-1 not open anymore")
+
+	// the refused items are in the report
+	outcomes := map[string]string{}
+	for _, issue := range defaultRunReport.build(time.Now()).Issues {
+		assert.Equal("", outcomes[issue.IssueID], issue.IssueID+" is reported once")
+		outcomes[issue.IssueID] = issue.Outcome
+	}
+	assert.Equal(outcomeCreated, outcomes["SNYK-JS-ACORN-559469"])
+	assert.Equal(outcomeAlreadyTicketed, outcomes["SNYK-JS-HANDLEBARS-174183"])
+	assert.Equal(outcomeSkipped, outcomes["SNYK-JS-FIXED-1"])
+
+	// the created ticket is checkpointed like the ones of a run
+	assert.False(c.hasPending())
+	assert.Equal(map[string]string{"SNYK-JS-ACORN-559469": "FPI-001"}, c.mergeTickets("123", map[string]string{}, cD))
+
+	// applied again, the plan continues from its checkpoint
+	resumed, _, err := openCheckpoint(c.filename, true, time.Now())
+	assert.Nil(err)
+	defaultCheckpoint = resumed
+	defaultRunStatus = &runStatus{}
+	result = applyProject(options, "123", items[:1], nil, cD)
+	assert.False(result.failed)
+	assert.Equal(0, defaultRunStatus.ticketsCreated)
+	assert.Contains(result.summary, "SNYK-JS-ACORN-559469 already ticketed")
+}
+
+func TestPlanAndApplyEndToEndFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	os.Setenv("EXECUTION_ENVIRONMENT", "test")
+
+	server := HTTPResponseEndToEnd()
+	defer server.Close()
+
+	oldArgs := os.Args
+	oldOutput := defaultRunOutput
+	defer func() {
+		os.Args = oldArgs
+		defaultRunOutput = oldOutput
+	}()
+
+	dir := t.TempDir()
+	planFile := filepath.Join(dir, "plan.json")
+	options := []string{"--orgID=123", "--token=123", "--jiraProjectID=123", "--api=" + server.URL, "--output-dir=" + dir}
+
+	os.Args = append([]string{oldArgs[0], "plan", "--planFile=" + planFile}, options...)
+	assert.Equal(exitSuccess, run())
+
+	plan, err := readPlan(planFile, "123")
+	assert.Nil(err)
+	assert.Equal(3, len(plan.Items))
+	assert.Equal(defaultRunOutput.runID, plan.RunID)
+
+	os.Args = append([]string{oldArgs[0], "apply", planFile}, options...)
+	assert.Equal(exitSuccess, run())
+	assert.Equal(3, defaultRunStatus.ticketsCreated)
+
+	// the plan file is required
+	os.Args = append([]string{oldArgs[0], "apply"}, options...)
+	assert.Equal(exitConfigError, run())
+}
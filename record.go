@@ -0,0 +1,402 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const redacted = "REDACTED"
+
+// headers and fields whose values are never written to a recording
+var secretHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+var secretFields = []string{"token", "apitoken", "api_key", "apikey", "access_token", "refresh_token", "client_secret", "password", "secret"}
+
+// command line flags whose name contains one of these are never written to a recording,
+// the webhook URLs carry their credentials
+var secretFlagParts = []string{"secret", "token", "password", "webhook"}
+
+// recordedExchange is one request/response pair saved by --record
+type recordedExchange struct {
+	Method          string              `json:"method"`
+	URL             string              `json:"url"`
+	RequestHeaders  map[string][]string `json:"requestHeaders,omitempty"`
+	RequestBody     string              `json:"requestBody,omitempty"`
+	StatusCode      int                 `json:"statusCode"`
+	ResponseHeaders map[string][]string `json:"responseHeaders,omitempty"`
+	ResponseBody    string              `json:"responseBody"`
+}
+
+// exchangeCounter numbers the identical requests so they are replayed in the recorded order
+type exchangeCounter struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (c *exchangeCounter) next(key string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.counts == nil {
+		c.counts = make(map[string]int)
+	}
+	c.counts[key]++
+	return c.counts[key]
+}
+
+// recordingTransport sends the requests and saves every exchange in dir
+type recordingTransport struct {
+	next    http.RoundTripper
+	dir     string
+	counter exchangeCounter
+}
+
+// replayTransport serves the exchanges saved in dir instead of the network
+type replayTransport struct {
+	dir     string
+	counter exchangeCounter
+}
+
+/*
+**
+function newRecordingTransport
+input next http.RoundTripper, transport actually sending the requests
+input dir string, directory of the recording, created if missing
+return *recordingTransport
+return error
+**
+*/
+func newRecordingTransport(next http.RoundTripper, dir string) (*recordingTransport, error) {
+
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	return &recordingTransport{next: next, dir: dir}, nil
+}
+
+/*
+**
+function newReplayTransport
+input dir string, directory written by --record
+return *replayTransport
+return error, when the directory doesn't exist
+**
+*/
+func newReplayTransport(dir string) (*replayTransport, error) {
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	return &replayTransport{dir: dir}, nil
+}
+
+func (t *recordingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+
+	requestBody, err := readRequestBody(request)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := t.next.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+
+	responseBody, err := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	response.Body = ioutil.NopCloser(bytes.NewReader(responseBody))
+
+	exchange := recordedExchange{
+		Method:          request.Method,
+		URL:             redactURL(request.URL),
+		RequestHeaders:  redactHeaders(request.Header),
+		RequestBody:     string(redactBody(requestBody)),
+		StatusCode:      response.StatusCode,
+		ResponseHeaders: redactHeaders(response.Header),
+		ResponseBody:    string(redactBody(responseBody)),
+	}
+
+	key := exchangeKey(exchange.Method, exchange.URL, exchange.RequestBody)
+	data, err := json.MarshalIndent(exchange, "", "  ")
+	if err == nil {
+		err = ioutil.WriteFile(filepath.Join(t.dir, exchangeFileName(key, t.counter.next(key))), data, 0600)
+	}
+	if err != nil {
+		// the run goes on, only the recording is incomplete
+		logger.Warn("Could not record the response", "method", exchange.Method, "endpoint", exchange.URL, "error", err)
+	}
+
+	return response, nil
+}
+
+func (t *replayTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+
+	requestBody, err := readRequestBody(request)
+	if err != nil {
+		return nil, err
+	}
+
+	method := request.Method
+	redactedURL := redactURL(request.URL)
+	key := exchangeKey(method, redactedURL, string(redactBody(requestBody)))
+
+	exchange := recordedExchange{}
+	data, err := ioutil.ReadFile(filepath.Join(t.dir, exchangeFileName(key, t.counter.next(key))))
+	if err == nil {
+		err = json.Unmarshal(data, &exchange)
+	}
+	if err != nil {
+		// not retried by the client so a missing exchange fails fast
+		exchange = recordedExchange{
+			StatusCode:   http.StatusNotFound,
+			ResponseBody: fmt.Sprintf("no recorded response for %s %s", method, redactedURL),
+		}
+	}
+
+	header := http.Header{}
+	for name, values := range exchange.ResponseHeaders {
+		header[name] = values
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", exchange.StatusCode, http.StatusText(exchange.StatusCode)),
+		StatusCode:    exchange.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(strings.NewReader(exchange.ResponseBody)),
+		ContentLength: int64(len(exchange.ResponseBody)),
+		Request:       request,
+	}, nil
+}
+
+/*
+**
+function writeRecordedCommand
+input dir string, directory of the recording
+input args []string, command line arguments of the run
+return error
+Keep the command line next to the exchanges so the run can be replayed with the same options.
+The secrets are redacted, replace them with any value when replaying.
+**
+*/
+func writeRecordedCommand(dir string, args []string) error {
+
+	redactedArgs := make([]string, 0, len(args))
+	redactNext := false
+	for _, arg := range args {
+		if redactNext {
+			redactedArgs = append(redactedArgs, redacted)
+			redactNext = false
+			continue
+		}
+
+		name := strings.TrimLeft(arg, "-")
+		value := ""
+		if index := strings.Index(name, "="); index >= 0 {
+			name, value = name[:index], name[index+1:]
+		}
+
+		if strings.HasPrefix(arg, "-") && isSecretFlag(name) {
+			if len(value) == 0 && !strings.Contains(arg, "=") {
+				redactNext = true
+				redactedArgs = append(redactedArgs, arg)
+			} else {
+				redactedArgs = append(redactedArgs, "--"+name+"="+redacted)
+			}
+			continue
+		}
+
+		redactedArgs = append(redactedArgs, arg)
+	}
+
+	data, err := json.MarshalIndent(map[string][]string{"args": redactedArgs}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(dir, "command.json"), data, 0600)
+}
+
+// readRequestBody reads the body and puts it back so the request can still be sent
+func readRequestBody(request *http.Request) ([]byte, error) {
+
+	if request.Body == nil {
+		return nil, nil
+	}
+
+	body, err := ioutil.ReadAll(request.Body)
+	request.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	request.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	return body, nil
+}
+
+func exchangeKey(method string, redactedURL string, redactedBody string) string {
+	hash := sha256.Sum256([]byte(method + "\n" + redactedURL + "\n" + redactedBody))
+	return hex.EncodeToString(hash[:])
+}
+
+// the host is not part of the key so a recording can be replayed against any api url
+func exchangeFileName(key string, occurrence int) string {
+	return fmt.Sprintf("%s-%03d.json", key[:16], occurrence)
+}
+
+func isSecretName(name string) bool {
+	name = strings.ToLower(name)
+	for _, secret := range secretFields {
+		if name == secret {
+			return true
+		}
+	}
+	return false
+}
+
+// isSecretFlag is true for the flags holding a token, a secret or a webhook URL
+func isSecretFlag(name string) bool {
+	name = strings.ToLower(name)
+	for _, part := range secretFlagParts {
+		if strings.Contains(name, part) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactURL(requestURL *url.URL) string {
+
+	redactedURL := *requestURL
+	redactedURL.User = nil
+	// only the path and the query are kept, see exchangeFileName
+	redactedURL.Scheme = ""
+	redactedURL.Host = ""
+
+	query := redactedURL.Query()
+	for name := range query {
+		if isSecretName(name) {
+			query.Set(name, redacted)
+		}
+	}
+	redactedURL.RawQuery = query.Encode()
+
+	return redactedURL.String()
+}
+
+func redactHeaders(header http.Header) map[string][]string {
+
+	redactedHeaders := make(map[string][]string)
+	for name, values := range header {
+		redactedHeaders[name] = values
+		for _, secret := range secretHeaders {
+			if strings.EqualFold(name, secret) {
+				redactedHeaders[name] = []string{redacted}
+			}
+		}
+	}
+
+	return redactedHeaders
+}
+
+/*
+**
+function redactBody
+input body []byte
+return []byte, the body with the values of the secret fields replaced
+Bodies that are not JSON are kept as they are
+**
+*/
+func redactBody(body []byte) []byte {
+
+	if len(body) == 0 {
+		return body
+	}
+
+	var content interface{}
+	if json.Unmarshal(body, &content) != nil {
+		return redactForm(body)
+	}
+
+	if !redactValue(content) {
+		return body
+	}
+
+	redactedBody, err := json.Marshal(content)
+	if err != nil {
+		return body
+	}
+
+	return redactedBody
+}
+
+// redactForm redacts the form encoded bodies, like the OAuth token requests
+func redactForm(body []byte) []byte {
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return body
+	}
+
+	changed := false
+	for name := range form {
+		if isSecretName(name) {
+			form.Set(name, redacted)
+			changed = true
+		}
+	}
+
+	if !changed {
+		return body
+	}
+
+	return []byte(form.Encode())
+}
+
+// redactValue replaces the secret fields in place and reports if something changed
+func redactValue(value interface{}) bool {
+
+	changed := false
+
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		for name, fieldValue := range typedValue {
+			if isSecretName(name) {
+				typedValue[name] = redacted
+				changed = true
+				continue
+			}
+			if redactValue(fieldValue) {
+				changed = true
+			}
+		}
+	case []interface{}:
+		for _, element := range typedValue {
+			if redactValue(element) {
+				changed = true
+			}
+		}
+	}
+
+	return changed
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordAndReplayFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("Set-Cookie", "session=secret-session")
+		w.Write([]byte(`{"call": ` + string(rune('0'+calls)) + `, "body": ` + string(body) + `}`))
+	}))
+	defer server.Close()
+
+	dir, _ := ioutil.TempDir("", "record")
+	defer os.RemoveAll(dir)
+
+	recorder, err := newRecordingTransport(http.DefaultTransport, dir)
+	assert.Nil(err)
+	client := &http.Client{Transport: recorder}
+
+	send := func(client *http.Client, baseURL string, token string) string {
+		request, _ := http.NewRequest("POST", baseURL+"/v1/org/123/project/456/aggregated-issues", strings.NewReader(`{"password": "`+token+`"}`))
+		request.Header.Set("Authorization", "token "+token)
+		response, err := client.Do(request)
+		assert.Nil(err)
+		defer response.Body.Close()
+		body, _ := ioutil.ReadAll(response.Body)
+		return string(body)
+	}
+
+	first := send(client, server.URL, "real-token")
+	second := send(client, server.URL, "real-token")
+	assert.NotEqual(first, second)
+
+	// nothing secret is written in the recording
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	assert.Equal(2, len(files))
+	for _, file := range files {
+		content, _ := ioutil.ReadFile(file)
+		assert.NotContains(string(content), "real-token")
+		assert.NotContains(string(content), "secret-session")
+		assert.NotContains(string(content), "127.0.0.1")
+	}
+
+	// replayed in the recorded order against another host with another token
+	replayer, err := newReplayTransport(dir)
+	assert.Nil(err)
+	client = &http.Client{Transport: replayer}
+
+	assert.JSONEq(strings.Replace(first, "real-token", redacted, 1), send(client, "http://replay.invalid", "other-token"))
+	assert.JSONEq(strings.Replace(second, "real-token", redacted, 1), send(client, "http://replay.invalid", "other-token"))
+	assert.Equal(2, calls)
+
+	response, err := client.Get("http://replay.invalid/v1/org/123/project/789")
+	assert.Nil(err)
+	assert.Equal(http.StatusNotFound, response.StatusCode)
+}
+
+func TestWriteRecordedCommandFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	dir, _ := ioutil.TempDir("", "record")
+	defer os.RemoveAll(dir)
+
+	err := writeRecordedCommand(dir, []string{"--orgID=123", "--token=real-token", "--token", "other-token", "--debug", "--oauthClientSecret=x", "--githubToken=gh-token", "--notifySlackWebhook", "https://hooks.slack.com/services/T000/B000/XXXX"})
+	assert.Nil(err)
+
+	content, _ := ioutil.ReadFile(filepath.Join(dir, "command.json"))
+	command := map[string][]string{}
+	json.Unmarshal(content, &command)
+
+	assert.Equal([]string{"--orgID=123", "--token=REDACTED", "--token", "REDACTED", "--debug", "--oauthClientSecret=REDACTED", "--githubToken=REDACTED", "--notifySlackWebhook", "REDACTED"}, command["args"])
+}
+
+func TestRedactBodyFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	assert.Equal("client_id=id&client_secret=REDACTED&grant_type=client_credentials", string(redactBody([]byte("grant_type=client_credentials&client_id=id&client_secret=secret"))))
+	assert.JSONEq(`{"fields": {"summary": "x"}, "auth": {"token": "REDACTED"}}`, string(redactBody([]byte(`{"fields": {"summary": "x"}, "auth": {"token": "123"}}`))))
+	assert.Equal(`{"fields":{}}`, string(redactBody([]byte(`{"fields":{}}`))))
+}
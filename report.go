@@ -0,0 +1,466 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/michael-go/go-jsn/jsn"
+)
+
+// outcomes of an issue in the run report
+const (
+	outcomeCreated         = "created"
+	outcomeAlreadyTicketed = "already-ticketed"
+	outcomeFiltered        = "filtered"
+	outcomeSkipped         = "skipped"
+	outcomeFailed          = "failed"
+	outcomeDryRun          = "dry-run"
+)
+
+// status of a project in the run report
+const (
+	projectStatusProcessed = "processed"
+	projectStatusExcluded  = "excluded"
+	projectStatusFailed    = "failed"
+)
+
+var reportFormats = []string{"json", "csv", "junit"}
+
+// ReportIssue is one candidate issue of the run
+type ReportIssue struct {
+	ProjectID string `json:"projectId"`
+	IssueID   string `json:"issueId"`
+	IssueType string `json:"issueType"`
+	Title     string `json:"title"`
+	Severity  string `json:"severity"`
+	Outcome   string `json:"outcome"`
+	Reason    string `json:"reason,omitempty"`
+	JiraKey   string `json:"jiraKey,omitempty"`
+	JiraURL   string `json:"jiraUrl,omitempty"`
+}
+
+// ReportProject is one project of the run
+type ReportProject struct {
+	ProjectID string `json:"projectId"`
+	Status    string `json:"status"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// ReportTotals counts the projects and the issues per outcome
+type ReportTotals struct {
+	Projects         int `json:"projects"`
+	ProjectsFailed   int `json:"projectsFailed"`
+	ProjectsExcluded int `json:"projectsExcluded"`
+	Issues           int `json:"issues"`
+	Created          int `json:"created"`
+	AlreadyTicketed  int `json:"alreadyTicketed"`
+	Filtered         int `json:"filtered"`
+	Skipped          int `json:"skipped"`
+	Failed           int `json:"failed"`
+	DryRun           int `json:"dryRun"`
+}
+
+// RunReport is the content of the run report
+type RunReport struct {
+	RunID      string          `json:"runId"`
+	OrgID      string          `json:"orgId"`
+	DryRun     bool            `json:"dryRun"`
+	StartedAt  time.Time       `json:"startedAt"`
+	FinishedAt time.Time       `json:"finishedAt"`
+	Totals     ReportTotals    `json:"totals"`
+	Projects   []ReportProject `json:"projects"`
+	Issues     []ReportIssue   `json:"issues"`
+}
+
+// runReport collects the outcomes while the projects and issues are processed in parallel
+type runReport struct {
+	mu          sync.Mutex
+	runID       string
+	orgID       string
+	dryRun      bool
+	jiraURL     string
+	startedAt   time.Time
+	projects    []ReportProject
+	projectRank map[string]int
+	issues      []ReportIssue
+}
+
+// defaultRunReport is nil unless a report is requested, every method is a no-op then
+var defaultRunReport *runReport
+
+func isReportFormat(format string) bool {
+	for _, reportFormat := range reportFormats {
+		if format == reportFormat {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+**
+function recordProject
+input projectID string, status string, reason string
+Give the status of a project to the run report and the metrics
+**
+*/
+func recordProject(projectID string, status string, reason string) {
+	defaultRunReport.setProject(projectID, status, reason)
+	defaultRunMetrics.setProject(projectID, status)
+}
+
+/*
+**
+function recordIssue
+input projectID string, issue jsn.Json, outcome string, reason string, jiraKey string
+Give the outcome of an issue to the run report, the metrics, the event sink and the state store
+**
+*/
+func recordIssue(projectID string, issue jsn.Json, outcome string, reason string, jiraKey string) {
+	defaultRunReport.addIssue(projectID, issue, outcome, reason, jiraKey)
+	defaultRunMetrics.addIssue(issue, outcome)
+	defaultEventSink.emit(projectID, issue, outcome, reason, jiraKey)
+	defaultStateStore.record(projectID, issue, outcome, jiraKey)
+	if outcome == outcomeSkipped || outcome == outcomeFailed {
+		defaultRunStatus.issueNotTicketed(projectID)
+	}
+}
+
+/*
+**
+function newRunReport
+input Of optionalFlags, dryRun and jiraURL are used
+input orgID string
+input startedAt time.Time, start of the run
+return *runReport
+**
+*/
+func newRunReport(Of optionalFlags, orgID string, startedAt time.Time) *runReport {
+	return &runReport{
+		runID:       defaultRunOutput.runID,
+		orgID:       orgID,
+		dryRun:      Of.dryRun,
+		jiraURL:     strings.TrimSuffix(Of.jiraURL, "/"),
+		startedAt:   startedAt,
+		projectRank: make(map[string]int),
+	}
+}
+
+/*
+**
+function setProject
+input projectID string, status string, reason string
+Add the project or update its status, the projects keep the order they are first seen in
+**
+*/
+func (r *runReport) setProject(projectID string, status string, reason string) {
+
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if rank, found := r.projectRank[projectID]; found {
+		r.projects[rank].Status = status
+		r.projects[rank].Reason = reason
+		return
+	}
+
+	r.projectRank[projectID] = len(r.projects)
+	r.projects = append(r.projects, ReportProject{ProjectID: projectID, Status: status, Reason: reason})
+}
+
+/*
+**
+function addIssue
+input projectID string
+input issue jsn.Json, open source issue, code issue from the list or code issue details
+input outcome string, one of the outcome constants
+input reason string, why the issue was not ticketed
+input jiraKey string, key of the created or existing ticket
+**
+*/
+func (r *runReport) addIssue(projectID string, issue jsn.Json, outcome string, reason string, jiraKey string) {
+
+	if r == nil {
+		return
+	}
+
+	reportIssue := describeIssue(issue)
+	reportIssue.ProjectID = projectID
+	reportIssue.Outcome = outcome
+	reportIssue.Reason = reason
+	reportIssue.JiraKey = jiraKey
+	reportIssue.JiraURL = ticketURL(r.jiraURL, jiraKey)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.issues = append(r.issues, reportIssue)
+}
+
+// describeIssue reads the ID, type, title and severity of the different issue shapes
+func describeIssue(issue jsn.Json) ReportIssue {
+
+	// open source issue from aggregated-issues
+	if len(issue.K("issueData").K("title").String().Value) > 0 {
+		return ReportIssue{
+			IssueID:   issue.K("id").String().Value,
+			IssueType: issue.K("issueType").String().Value,
+			Title:     issue.K("issueData").K("title").String().Value,
+			Severity:  issue.K("issueData").K("severity").String().Value,
+		}
+	}
+
+	// code issue details, the title is added by getCodeIssueDetail
+	if len(issue.K("data").K("id").String().Value) > 0 {
+		return ReportIssue{
+			IssueID:   issue.K("data").K("id").String().Value,
+			IssueType: "code",
+			Title:     issue.K("title").String().Value,
+			Severity:  issue.K("data").K("attributes").K("severity").String().Value,
+		}
+	}
+
+	// code issue from the issues list
+	return ReportIssue{
+		IssueID:   issue.K("id").String().Value,
+		IssueType: "code",
+		Title:     issue.K("attributes").K("title").String().Value,
+		Severity:  issue.K("attributes").K("severity").String().Value,
+	}
+}
+
+/*
+**
+function build
+input finishedAt time.Time
+return RunReport, the issues sorted by project, in the order of the run, then by issue ID
+**
+*/
+func (r *runReport) build(finishedAt time.Time) RunReport {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report := RunReport{
+		RunID:      r.runID,
+		OrgID:      r.orgID,
+		DryRun:     r.dryRun,
+		StartedAt:  r.startedAt.UTC(),
+		FinishedAt: finishedAt.UTC(),
+		Projects:   append([]ReportProject{}, r.projects...),
+		Issues:     append([]ReportIssue{}, r.issues...),
+	}
+
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		rankI, rankJ := r.projectRank[report.Issues[i].ProjectID], r.projectRank[report.Issues[j].ProjectID]
+		if rankI != rankJ {
+			return rankI < rankJ
+		}
+		return report.Issues[i].IssueID < report.Issues[j].IssueID
+	})
+
+	for _, project := range report.Projects {
+		report.Totals.Projects++
+		switch project.Status {
+		case projectStatusFailed:
+			report.Totals.ProjectsFailed++
+		case projectStatusExcluded:
+			report.Totals.ProjectsExcluded++
+		}
+	}
+
+	for _, issue := range report.Issues {
+		report.Totals.Issues++
+		switch issue.Outcome {
+		case outcomeCreated:
+			report.Totals.Created++
+		case outcomeAlreadyTicketed:
+			report.Totals.AlreadyTicketed++
+		case outcomeFiltered:
+			report.Totals.Filtered++
+		case outcomeSkipped:
+			report.Totals.Skipped++
+		case outcomeFailed:
+			report.Totals.Failed++
+		case outcomeDryRun:
+			report.Totals.DryRun++
+		}
+	}
+
+	return report
+}
+
+/*
+**
+function write
+input filename string
+input format string, json, csv or junit
+input finishedAt time.Time
+return error
+**
+*/
+func (r *runReport) write(filename string, format string, finishedAt time.Time) error {
+
+	if r == nil {
+		return nil
+	}
+
+	report := r.build(finishedAt)
+
+	var data []byte
+	var err error
+	switch format {
+	case "json":
+		data, err = json.MarshalIndent(report, "", "  ")
+	case "csv":
+		data, err = report.csv()
+	case "junit":
+		data, err = report.junit()
+	default:
+		err = fmt.Errorf("%s is not a valid report format. Must be one of %s", format, strings.Join(reportFormats, ", "))
+	}
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(filename, data, 0644)
+}
+
+// csv has one line per issue, the projects without issue are not listed
+func (report RunReport) csv() ([]byte, error) {
+
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+
+	writer.Write([]string{"projectId", "issueId", "issueType", "title", "severity", "outcome", "reason", "jiraKey", "jiraUrl"})
+	for _, issue := range report.Issues {
+		writer.Write([]string{issue.ProjectID, issue.IssueID, issue.IssueType, issue.Title, issue.Severity, issue.Outcome, issue.Reason, issue.JiraKey, issue.JiraURL})
+	}
+	writer.Flush()
+
+	return buffer.Bytes(), writer.Error()
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+}
+
+/*
+**
+function junit
+return []byte, one test suite per project and one test case per issue
+Failed issues and projects are failures, the issues not ticketed are skipped
+**
+*/
+func (report RunReport) junit() ([]byte, error) {
+
+	suites := junitTestSuites{
+		Name: "snyk-jira-sync",
+		Time: fmt.Sprintf("%.3f", report.FinishedAt.Sub(report.StartedAt).Seconds()),
+	}
+
+	suiteIndex := make(map[string]int)
+	for _, project := range report.Projects {
+		suite := junitTestSuite{Name: project.ProjectID}
+		if project.Status != projectStatusProcessed {
+			testCase := junitTestCase{ClassName: project.ProjectID, Name: "project"}
+			if project.Status == projectStatusFailed {
+				testCase.Failure = &junitMessage{Message: project.Reason}
+			} else {
+				testCase.Skipped = &junitMessage{Message: project.Reason}
+			}
+			suite.TestCases = append(suite.TestCases, testCase)
+		}
+		suiteIndex[project.ProjectID] = len(suites.Suites)
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	for _, issue := range report.Issues {
+		index, found := suiteIndex[issue.ProjectID]
+		if !found {
+			suiteIndex[issue.ProjectID] = len(suites.Suites)
+			index = len(suites.Suites)
+			suites.Suites = append(suites.Suites, junitTestSuite{Name: issue.ProjectID})
+		}
+
+		testCase := junitTestCase{
+			ClassName: issue.ProjectID,
+			Name:      strings.TrimSpace(issue.IssueID + " " + issue.Title),
+		}
+		switch issue.Outcome {
+		case outcomeFailed:
+			testCase.Failure = &junitMessage{Message: issue.Reason}
+		case outcomeCreated, outcomeDryRun:
+			testCase.SystemOut = strings.TrimSpace(issue.JiraKey + " " + issue.JiraURL)
+		default:
+			message := issue.Outcome
+			if len(issue.Reason) > 0 {
+				message += ": " + issue.Reason
+			}
+			if len(issue.JiraKey) > 0 {
+				message += " (" + issue.JiraKey + ")"
+			}
+			testCase.Skipped = &junitMessage{Message: message}
+		}
+		suites.Suites[index].TestCases = append(suites.Suites[index].TestCases, testCase)
+	}
+
+	for index := range suites.Suites {
+		suite := &suites.Suites[index]
+		for _, testCase := range suite.TestCases {
+			suite.Tests++
+			if testCase.Failure != nil {
+				suite.Failures++
+			}
+			if testCase.Skipped != nil {
+				suite.Skipped++
+			}
+		}
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Skipped += suite.Skipped
+	}
+
+	data, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
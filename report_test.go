@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/michael-go/go-jsn/jsn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunReportFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	startedAt := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	report := newRunReport(optionalFlags{jiraURL: "https://example.atlassian.net/"}, "123", startedAt)
+
+	vuln, _ := jsn.NewJson(map[string]interface{}{
+		"id":        "SNYK-JS-MINIMIST-559764",
+		"issueType": "vuln",
+		"issueData": map[string]interface{}{"title": "Prototype Pollution", "severity": "medium"},
+	})
+	license, _ := jsn.NewJson(map[string]interface{}{
+		"id":        "snyk:lic:npm:gpl",
+		"issueType": "license",
+		"issueData": map[string]interface{}{"title": "GPL-3.0 license", "severity": "high"},
+	})
+	codeIssue, _ := jsn.NewJson(map[string]interface{}{
+		"id":         "code-1",
+		"attributes": map[string]interface{}{"title": "SQL Injection", "severity": "high"},
+	})
+
+	report.setProject("project-b", projectStatusProcessed, "")
+	report.setProject("project-a", projectStatusProcessed, "")
+	report.setProject("project-c", projectStatusExcluded, "project is inactive")
+
+	report.addIssue("project-a", vuln, outcomeCreated, "", "FPI-1")
+	report.addIssue("project-b", license, outcomeFailed, "Request failed", "")
+	report.addIssue("project-b", codeIssue, outcomeAlreadyTicketed, "", "FPI-2")
+	report.addIssue("project-a", license, outcomeFiltered, "no upgrade available", "")
+	report.setProject("project-a", projectStatusFailed, "failed to create Jira ticket(s)")
+
+	built := report.build(startedAt.Add(90 * time.Second))
+
+	assert.Equal(ReportTotals{Projects: 3, ProjectsFailed: 1, ProjectsExcluded: 1, Issues: 4, Created: 1, AlreadyTicketed: 1, Filtered: 1, Failed: 1}, built.Totals)
+
+	// projects in the order of the run, then the issue IDs
+	order := []string{}
+	for _, issue := range built.Issues {
+		order = append(order, issue.ProjectID+"/"+issue.IssueID)
+	}
+	assert.Equal([]string{"project-b/code-1", "project-b/snyk:lic:npm:gpl", "project-a/SNYK-JS-MINIMIST-559764", "project-a/snyk:lic:npm:gpl"}, order)
+
+	assert.Equal(ReportIssue{
+		ProjectID: "project-a",
+		IssueID:   "SNYK-JS-MINIMIST-559764",
+		IssueType: "vuln",
+		Title:     "Prototype Pollution",
+		Severity:  "medium",
+		Outcome:   outcomeCreated,
+		JiraKey:   "FPI-1",
+		JiraURL:   "https://example.atlassian.net/browse/FPI-1",
+	}, built.Issues[2])
+	assert.Equal("code", built.Issues[0].IssueType)
+	assert.Equal("SQL Injection", built.Issues[0].Title)
+
+	// csv
+	csvReport, err := built.csv()
+	assert.Nil(err)
+	lines := strings.Split(strings.TrimSpace(string(csvReport)), "\n")
+	assert.Equal(5, len(lines))
+	assert.Equal("projectId,issueId,issueType,title,severity,outcome,reason,jiraKey,jiraUrl", lines[0])
+	assert.Equal("project-b,snyk:lic:npm:gpl,license,GPL-3.0 license,high,failed,Request failed,,", lines[2])
+
+	// junit
+	junitReport, err := built.junit()
+	assert.Nil(err)
+	suites := junitTestSuites{}
+	assert.Nil(xml.Unmarshal(junitReport, &suites))
+	assert.Equal(6, suites.Tests)
+	assert.Equal(2, suites.Failures)
+	assert.Equal(3, suites.Skipped)
+	assert.Equal("90.000", suites.Time)
+	assert.Equal([]string{"project-b", "project-a", "project-c"}, []string{suites.Suites[0].Name, suites.Suites[1].Name, suites.Suites[2].Name})
+	assert.Equal("Request failed", suites.Suites[0].TestCases[1].Failure.Message)
+	assert.Equal("already-ticketed (FPI-2)", suites.Suites[0].TestCases[0].Skipped.Message)
+	assert.Equal("project", suites.Suites[1].TestCases[0].Name)
+	assert.Equal("failed to create Jira ticket(s)", suites.Suites[1].TestCases[0].Failure.Message)
+	assert.Equal("project is inactive", suites.Suites[2].TestCases[0].Skipped.Message)
+
+	// json
+	filename := filepath.Join(t.TempDir(), "report.json")
+	assert.Nil(report.write(filename, "json", startedAt.Add(90*time.Second)))
+	data, err := os.ReadFile(filename)
+	assert.Nil(err)
+	written := RunReport{}
+	assert.Nil(json.Unmarshal(data, &written))
+	assert.Equal(built, written)
+
+	assert.NotNil(report.write(filename, "xml", startedAt))
+
+	// without report nothing is collected
+	var noReport *runReport
+	noReport.addIssue("project-a", vuln, outcomeCreated, "", "")
+	noReport.setProject("project-a", projectStatusProcessed, "")
+	assert.Nil(noReport.write(filename, "json", startedAt))
+}
+
+func TestOpenJiraTicketsReportFunc(t *testing.T) {
+
+	assert := assert.New(t)
+	server := HTTPResponseCheckOpenJiraMultipleTickets()
+	defer server.Close()
+
+	projectInfo, _ := jsn.NewJson(readFixture("./fixtures/project.json"))
+	vulnsForJira := make(map[string]interface{})
+	err := json.Unmarshal(readFixture("./fixtures/vulnForJiraAggregatedWithPathList.json"), &vulnsForJira)
+	if err != nil {
+		panic(err)
+	}
+
+	flags := flags{}
+	flags.mandatoryFlags = MandatoryFlags{orgID: "123", endpointAPI: server.URL, apiToken: "123", jiraProjectID: "123"}
+	flags.optionalFlags = optionalFlags{jiraTicketType: "Bug", priorityIsSeverity: true, ifUpgradeAvailableOnly: true, jiraURL: "https://example.atlassian.net"}
+
+	cD := debug{}
+	CreateLogFile(cD, "ErrorsFile_")
+	defer removeLogFile()
+
+	defaultRunReport = newRunReport(flags.optionalFlags, "123", time.Now())
+	defer func() { defaultRunReport = nil }()
+
+	projectID := projectInfo.K("id").String().Value
+	defaultRunReport.setProject(projectID, projectStatusProcessed, "")
+	openJiraTickets(flags, projectInfo, vulnsForJira, cD)
+
+	built := defaultRunReport.build(time.Now())
+	assert.Equal(2, len(built.Issues))
+
+	created := built.Issues[0]
+	assert.Equal("SNYK-JS-MINIMIST-559764", created.IssueID)
+	assert.Equal(projectID, created.ProjectID)
+	assert.Equal(outcomeCreated, created.Outcome)
+	assert.Equal("FPI-001", created.JiraKey)
+	assert.Equal("https://example.atlassian.net/browse/FPI-001", created.JiraURL)
+
+	filtered := built.Issues[1]
+	assert.Equal("SNYK-JS-MINIMIST-559765", filtered.IssueID)
+	assert.Equal(outcomeFiltered, filtered.Outcome)
+	assert.Equal("no upgrade available", filtered.Reason)
+}
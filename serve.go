@@ -0,0 +1,339 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// WebhookPath is where Snyk posts the webhook events
+const WebhookPath = "/webhook"
+
+// maxWebhookBody is the largest webhook payload read, Snyk snapshots list the new and removed issues
+const maxWebhookBody = 5 << 20
+
+// Snyk webhook events handled by serve
+const (
+	webhookEventPing            = "ping"
+	webhookEventProjectSnapshot = "project_snapshot"
+)
+
+// syncJob is a sync of one project queued by a webhook event
+type syncJob struct {
+	orgID     string
+	projectID string
+}
+
+func (job syncJob) key() string {
+	return job.orgID + "/" + job.projectID
+}
+
+// webhookServer receives the Snyk webhooks and runs the syncs one at a time
+type webhookServer struct {
+	secret string
+	// runs a sync of the project, the exit code is logged
+	sync  func(job syncJob) int
+	queue chan syncJob
+	// a project already queued is not queued again
+	mu      sync.Mutex
+	pending map[string]bool
+	stopped bool
+}
+
+/*
+**
+function newWebhookServer
+input secret string, shared with Snyk to sign the payloads
+input queueSize int, events received while the queue is full are refused
+input syncArgs []string, options of the syncs, the org and project of the event are added
+return *webhookServer
+**
+*/
+func newWebhookServer(secret string, queueSize int, syncArgs []string) *webhookServer {
+
+	return &webhookServer{
+		secret: secret,
+		sync: func(job syncJob) int {
+			args := append(append([]string{}, syncArgs...), "--orgID="+job.orgID, "--projectID="+job.projectID)
+			return runSync(args)
+		},
+		queue:   make(chan syncJob, queueSize),
+		pending: make(map[string]bool),
+	}
+}
+
+/*
+**
+function verifySignature
+input body []byte
+input signature string, the X-Hub-Signature header, sha256=<hex HMAC of the body>
+return bool, true when the body was signed with the secret
+**
+*/
+func (s *webhookServer) verifySignature(body []byte, signature string) bool {
+
+	if !strings.HasPrefix(signature, "sha256=") {
+		return false
+	}
+	expected, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(s.secret))
+	mac.Write(body)
+
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+/*
+**
+function enqueue
+input job syncJob
+return bool, false when the queue is full
+return bool, true when the project was already waiting in the queue
+**
+*/
+func (s *webhookServer) enqueue(job syncJob) (bool, bool) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped {
+		return false, false
+	}
+
+	if s.pending[job.key()] {
+		return true, true
+	}
+
+	select {
+	case s.queue <- job:
+		s.pending[job.key()] = true
+		return true, false
+	default:
+		return false, false
+	}
+}
+
+/*
+**
+function work
+Run the queued syncs until the queue is closed. A project is removed from the
+pending list when its sync starts, an event received during the sync queues a new one.
+**
+*/
+func (s *webhookServer) work() {
+
+	for job := range s.queue {
+		s.mu.Lock()
+		delete(s.pending, job.key())
+		stopped := s.stopped
+		s.mu.Unlock()
+
+		if stopped {
+			logger.Warn("Queued sync dropped, the server is stopping", "org", job.orgID, "project", job.projectID)
+			continue
+		}
+
+		logger.Info("Sync started by a webhook", "org", job.orgID, "project", job.projectID)
+		exitCode := s.sync(job)
+		logger.Info("Sync done", "org", job.orgID, "project", job.projectID, "exitCode", exitCode)
+	}
+}
+
+// stop refuses the new events and drops the queued syncs
+func (s *webhookServer) stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.stopped {
+		s.stopped = true
+		close(s.queue)
+	}
+}
+
+// writeJSONResponse answers with the value in JSON
+func writeJSONResponse(w http.ResponseWriter, code int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(value)
+}
+
+// writeWebhookResponse answers Snyk with a JSON status
+func writeWebhookResponse(w http.ResponseWriter, code int, status string) {
+	writeJSONResponse(w, code, map[string]string{"status": status})
+}
+
+/*
+**
+function ServeHTTP
+Handle a Snyk webhook: the signature is checked, a ping is answered and a
+project snapshot queues a sync of the project.
+**
+*/
+func (s *webhookServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodPost {
+		writeWebhookResponse(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+	if err != nil {
+		writeWebhookResponse(w, http.StatusRequestEntityTooLarge, "payload too large")
+		return
+	}
+
+	if !s.verifySignature(body, r.Header.Get("X-Hub-Signature")) {
+		logger.Warn("Webhook refused, the signature does not match", "remote", r.RemoteAddr)
+		writeWebhookResponse(w, http.StatusUnauthorized, "invalid signature")
+		return
+	}
+
+	// the event is versioned, like project_snapshot/v0
+	event := strings.SplitN(r.Header.Get("X-Snyk-Event"), "/", 2)[0]
+	switch event {
+	case webhookEventPing:
+		logger.Info("Webhook ping received")
+		writeWebhookResponse(w, http.StatusOK, "pong")
+		return
+	case webhookEventProjectSnapshot:
+	default:
+		logger.Debug("Webhook event ignored", "event", event)
+		writeWebhookResponse(w, http.StatusOK, "ignored")
+		return
+	}
+
+	var payload struct {
+		Org struct {
+			ID string `json:"id"`
+		} `json:"org"`
+		Project struct {
+			ID string `json:"id"`
+		} `json:"project"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil || payload.Org.ID == "" || payload.Project.ID == "" {
+		logger.Warn("Webhook refused, the org or the project is missing", "event", event)
+		writeWebhookResponse(w, http.StatusBadRequest, "org and project are required")
+		return
+	}
+
+	job := syncJob{orgID: payload.Org.ID, projectID: payload.Project.ID}
+	queued, duplicate := s.enqueue(job)
+	switch {
+	case !queued:
+		logger.Warn("Webhook refused, the queue is full", "org", job.orgID, "project", job.projectID, "queueSize", cap(s.queue))
+		writeWebhookResponse(w, http.StatusServiceUnavailable, "queue full")
+	case duplicate:
+		logger.Debug("Sync already queued", "org", job.orgID, "project", job.projectID)
+		writeWebhookResponse(w, http.StatusAccepted, "already queued")
+	default:
+		logger.Info("Sync queued", "org", job.orgID, "project", job.projectID, "queued", len(s.queue))
+		writeWebhookResponse(w, http.StatusAccepted, "queued")
+	}
+}
+
+/*
+**
+function runServeCommand
+input args []string, the serve options then, after --, the options of the syncs
+return int, the exit code
+Listen for the Snyk webhooks until SIGINT or SIGTERM. The sync running finishes
+the projects in flight before the server stops, the syncs still queued are dropped.
+**
+*/
+func runServeCommand(args []string) int {
+
+	fs := pflag.NewFlagSet("serve", pflag.ContinueOnError)
+	listen := fs.String("listen", ":8080", "Address the webhook server listens on")
+	webhookSecret := fs.String("webhookSecret", "", "Secret of the Snyk webhook, can also be set with the SNYK_WEBHOOK_SECRET env var")
+	queueSize := fs.Int("queueSize", 100, "Number of syncs waiting at most, the events received when it is full are refused")
+	if err := fs.Parse(args); err != nil {
+		logger.Error("Error parsing command line arguments", "usage", "serve --webhookSecret=<secret> [--listen=:8080] [--queueSize=100] -- [sync options]", "error", err)
+		return exitConfigError
+	}
+
+	secret := *webhookSecret
+	if secret == "" {
+		secret = os.Getenv("SNYK_WEBHOOK_SECRET")
+	}
+	if secret == "" {
+		logger.Error("--webhookSecret or the SNYK_WEBHOOK_SECRET env var is required")
+		return exitConfigError
+	}
+
+	if *queueSize < 1 {
+		logger.Error("queueSize must be at least 1", "queueSize", *queueSize)
+		return exitConfigError
+	}
+
+	// the sync options are checked now rather than on the first event
+	syncArgs := fs.Args()
+	options := flags{}
+	options.setOption(syncArgs)
+	configureLogging(options.optionalFlags.logFormat, options.optionalFlags.logLevel, options.optionalFlags.debug)
+	// the org comes from the events, the other required options are checked
+	if options.mandatoryFlags.orgID == "" {
+		options.mandatoryFlags.orgID = "from-events"
+	}
+	options.mandatoryFlags.checkMandatoryAreSet()
+
+	// a fatal error ends the sync, not the server
+	recoverFatal = true
+	defer func() { recoverFatal = false }()
+
+	server := newWebhookServer(secret, *queueSize, syncArgs)
+	done := make(chan struct{})
+	go func() {
+		server.work()
+		close(done)
+	}()
+
+	mux := http.NewServeMux()
+	mux.Handle(WebhookPath, server)
+	httpServer := &http.Server{Addr: *listen, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		logger.Info("Listening for Snyk webhooks", "listen", *listen, "path", WebhookPath, "queueSize", *queueSize)
+		serveErr <- httpServer.ListenAndServe()
+	}()
+
+	exitCode := exitSuccess
+	select {
+	case err := <-serveErr:
+		logger.Error("The webhook server stopped", "listen", *listen, "error", err)
+		exitCode = exitError
+	case sig := <-stop:
+		logger.Info("Stopping the webhook server, the projects in flight are finished first", "signal", sig.String())
+		requestStop()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := httpServer.Shutdown(ctx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Warn("The webhook server did not stop cleanly", "error", err)
+		}
+		cancel()
+	}
+
+	server.stop()
+	<-done
+
+	return exitCode
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func signWebhook(secret string, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func postWebhook(server *webhookServer, event string, body string, signature string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodPost, WebhookPath, strings.NewReader(body))
+	request.Header.Set("X-Snyk-Event", event)
+	request.Header.Set("X-Hub-Signature", signature)
+	response := httptest.NewRecorder()
+	server.ServeHTTP(response, request)
+	return response
+}
+
+func TestWebhookServerFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	server := newWebhookServer("secret", 2, nil)
+	synced := []syncJob{}
+	server.sync = func(job syncJob) int {
+		synced = append(synced, job)
+		return exitSuccess
+	}
+
+	snapshot := `{"org": {"id": "123"}, "project": {"id": "project-a"}, "newIssues": [], "removedIssues": []}`
+
+	// the payload must be signed with the secret
+	response := postWebhook(server, "project_snapshot/v0", snapshot, signWebhook("other", snapshot))
+	assert.Equal(http.StatusUnauthorized, response.Code)
+	response = postWebhook(server, "project_snapshot/v0", snapshot, "")
+	assert.Equal(http.StatusUnauthorized, response.Code)
+
+	response = postWebhook(server, "ping", `{"webhookId": "1"}`, signWebhook("secret", `{"webhookId": "1"}`))
+	assert.Equal(http.StatusOK, response.Code)
+	assert.Contains(response.Body.String(), "pong")
+
+	response = postWebhook(server, "project_snapshot/v0", `{"org": {"id": "123"}}`, signWebhook("secret", `{"org": {"id": "123"}}`))
+	assert.Equal(http.StatusBadRequest, response.Code)
+
+	// a burst of events for the same project queues one sync
+	response = postWebhook(server, "project_snapshot/v0", snapshot, signWebhook("secret", snapshot))
+	assert.Equal(http.StatusAccepted, response.Code)
+	assert.Contains(response.Body.String(), `"queued"`)
+	response = postWebhook(server, "project_snapshot/v0", snapshot, signWebhook("secret", snapshot))
+	assert.Equal(http.StatusAccepted, response.Code)
+	assert.Contains(response.Body.String(), "already queued")
+
+	other := `{"org": {"id": "123"}, "project": {"id": "project-b"}}`
+	response = postWebhook(server, "project_snapshot/v0", other, signWebhook("secret", other))
+	assert.Equal(http.StatusAccepted, response.Code)
+
+	// the queue is full
+	third := `{"org": {"id": "123"}, "project": {"id": "project-c"}}`
+	response = postWebhook(server, "project_snapshot/v0", third, signWebhook("secret", third))
+	assert.Equal(http.StatusServiceUnavailable, response.Code)
+
+	response = httptest.NewRecorder()
+	server.ServeHTTP(response, httptest.NewRequest(http.MethodGet, WebhookPath, nil))
+	assert.Equal(http.StatusMethodNotAllowed, response.Code)
+
+	// the queued syncs run in order
+	close(server.queue)
+	server.work()
+
+	assert.Equal([]syncJob{{orgID: "123", projectID: "project-a"}, {orgID: "123", projectID: "project-b"}}, synced)
+
+	// a stopped server refuses the events
+	server.stopped = true
+	response = postWebhook(server, "project_snapshot/v0", snapshot, signWebhook("secret", snapshot))
+	assert.Equal(http.StatusServiceUnavailable, response.Code)
+}
+
+func TestServeCommandFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	t.Setenv("SNYK_WEBHOOK_SECRET", "")
+	assert.Equal(exitConfigError, runServeCommand([]string{"--listen=:0"}))
+	assert.Equal(exitConfigError, runServeCommand([]string{"--webhookSecret=secret", "--queueSize=0"}))
+	assert.Equal(exitConfigError, runServeCommand([]string{"--unknown"}))
+}
@@ -3,8 +3,9 @@ package main
 import (
 	"errors"
 	"fmt"
-	"log"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/michael-go/go-jsn/jsn"
 )
@@ -33,13 +34,17 @@ func getOrgProjects(flags flags, customDebug debug) ([]jsn.Json, error) {
 	if len(flags.optionalFlags.targetID) > 0 {
 		projectsAPI += "&target_id=" + strings.Replace(flags.optionalFlags.targetID, ",", "%2C", -1)
 	}
+	// the last test date is only returned as part of the issue counts meta
+	if len(flags.optionalFlags.maxProjectAge) > 0 || !flags.optionalFlags.incrementalSince.IsZero() {
+		projectsAPI += "&meta.latest_issue_counts=true"
+	}
 
 	var err error
 
 	projectList, err := makeSnykAPIRequest_REST(verb, baseURL, projectsAPI, flags.mandatoryFlags.apiToken, nil, customDebug)
 	if err != nil {
 		filters := "projectCriticality: " + flags.optionalFlags.projectCriticality + "\n projectEnvironment: " + flags.optionalFlags.projectEnvironment + "\n projectLifecycle: " + flags.optionalFlags.projectLifecycle
-		log.Printf("*** ERROR *** Could not list the Project(s) for endpoint %s\n Applied Filters: %s\n", projectsAPI, filters)
+		customDebug.Error("Could not list the Project(s)", "org", flags.mandatoryFlags.orgID, "endpoint", projectsAPI, "projectCriticality", flags.optionalFlags.projectCriticality, "projectEnvironment", flags.optionalFlags.projectEnvironment, "projectLifecycle", flags.optionalFlags.projectLifecycle)
 		errorMessage := fmt.Sprintf("Failure, Could not list the Project(s) for endpoint %s .\n Applied filters: %s\n", projectsAPI, filters)
 		writeErrorFile("getOrgProjects", errorMessage, customDebug)
 		err = errors.New(errorMessage)
@@ -48,47 +53,96 @@ func getOrgProjects(flags flags, customDebug debug) ([]jsn.Json, error) {
 	return projectList, err
 }
 
-func getProjectsIds(options flags, customDebug debug, notCreatedLogFile string) ([]string, error) {
+func getProjectsIds(options flags, customDebug debug, notCreatedLogFile string) ([]string, map[string]string, error) {
 
 	var projectIds []string
+	excludedProjects := make(map[string]string)
 	if len(options.optionalFlags.projectID) == 0 {
-		filters :=
-			"projectCriticality: " + options.optionalFlags.projectCriticality +
-			"\n projectEnvironment: " + options.optionalFlags.projectEnvironment +
-			"\n projectLifecycle: " + options.optionalFlags.projectLifecycle +
-			"\n targetID:" + options.optionalFlags.targetID
-
-		log.Println("*** INFO *** Project ID not specified - listing all projects that match the following filters: ", filters)
+		customDebug.Info("Project ID not specified - listing all projects that match the filters",
+			"org", options.mandatoryFlags.orgID,
+			"projectCriticality", options.optionalFlags.projectCriticality,
+			"projectEnvironment", options.optionalFlags.projectEnvironment,
+			"projectLifecycle", options.optionalFlags.projectLifecycle,
+			"targetID", options.optionalFlags.targetID)
 
 		projects, err := getOrgProjects(options, customDebug)
 		if err != nil {
 			message := fmt.Sprintf("error while getting projects ID for org %s", options.mandatoryFlags.orgID)
 			writeErrorFile("getProjectsIds", message, customDebug)
-			return nil, err
+			return nil, nil, err
 		}
 
 		for _, project := range projects {
 			projectID := project.K("id").String().Value
+			if reason := projectExclusionReason(options.optionalFlags, project, time.Now()); reason != "" {
+				customDebug.Debug("Excluding project", "project", projectID, "reason", reason)
+				excludedProjects[projectID] = reason
+				continue
+			}
 			projectIds = append(projectIds, projectID)
 		}
 
-		if len(projectIds) == 0 {
+		if len(projectIds) == 0 && len(excludedProjects) == 0 {
 			ErrorMessage := fmt.Sprintf("Failure, Could not retrieve project ID")
 			writeErrorFile("getProjectsIds", ErrorMessage, customDebug)
-			return projectIds, errors.New(ErrorMessage)
+			return projectIds, excludedProjects, errors.New(ErrorMessage)
 		}
-		return projectIds, nil
+		return projectIds, excludedProjects, nil
 	}
 
 	projectIds = append(projectIds, options.optionalFlags.projectID)
 
-	return projectIds, nil
+	return projectIds, excludedProjects, nil
+}
+
+/*
+**
+function projectExclusionReason
+input optionalFlags, skipInactiveProjects, maxProjectAge and incrementalSince are used
+input project jsn.Json, a project from the REST projects listing
+input now time.Time, reference time for the age check
+return string, why the project should be skipped or empty to keep it
+Projects without a last test date are kept, we cannot tell they are stale or unchanged
+**
+*/
+func projectExclusionReason(Of optionalFlags, project jsn.Json, now time.Time) string {
+
+	status := project.K("attributes").K("status").String().Value
+	if Of.skipInactiveProjects && status == "inactive" {
+		return "project is inactive"
+	}
+
+	if len(Of.maxProjectAge) == 0 && Of.incrementalSince.IsZero() {
+		return ""
+	}
+
+	lastTested := project.K("meta").K("latest_issue_counts").K("updated_at").String().Value
+	if len(lastTested) == 0 {
+		return ""
+	}
+
+	lastTestedDate, err := time.Parse(time.RFC3339, lastTested)
+	if err != nil {
+		return ""
+	}
+
+	if maxAge, err := parseDuration(Of.maxProjectAge); err == nil && now.Sub(lastTestedDate) > maxAge {
+		return fmt.Sprintf("project last tested on %s, older than %s", lastTestedDate.Format("2006-01-02"), Of.maxProjectAge)
+	}
+
+	// the issues of a project only change when it is tested
+	if !Of.incrementalSince.IsZero() && lastTestedDate.Before(Of.incrementalSince) {
+		return fmt.Sprintf("project not tested since the last run on %s", Of.incrementalSince.UTC().Format(time.RFC3339))
+	}
+
+	return ""
 }
 
 func getProjectDetails(Mf MandatoryFlags, projectID string, customDebug debug) (jsn.Json, error) {
+	// never cached, the last tested date of the project keys the cached paths and code issue details
 	responseData, err := makeSnykAPIRequest("GET", Mf.endpointAPI+"/v1/org/"+Mf.orgID+"/project/"+projectID, Mf.apiToken, nil, customDebug)
 	if err != nil {
-		log.Printf("*** ERROR *** Could not get the Project detail for endpoint %s\n", Mf.endpointAPI)
+		customDebug.Error("Could not get the Project detail", "org", Mf.orgID, "project", projectID, "endpoint", Mf.endpointAPI, "error", err)
 		errorMessage := fmt.Sprintf("Failure, Could not get the Project detail for endpoint %s\n", Mf.endpointAPI)
 		err = errors.New(errorMessage)
 		writeErrorFile("getProjectDetails", errorMessage, customDebug)
@@ -101,5 +155,34 @@ func getProjectDetails(Mf MandatoryFlags, projectID string, customDebug debug) (
 		writeErrorFile("getProjectDetails", errorMessage, customDebug)
 	}
 
+	// the paths and code issue details cached for this project are valid until it is retested
+	if err == nil {
+		defaultSnykClient.cache.setProjectVersion(projectID, project)
+	}
+
 	return project, err
 }
+
+/*
+**
+function formatExcludedProjects
+input excludedProjects map[string]string, project ID and reason it was skipped
+return string, summary block listing the excluded projects
+**
+*/
+func formatExcludedProjects(excludedProjects map[string]string) string {
+
+	projectIDs := make([]string, 0, len(excludedProjects))
+	for projectID := range excludedProjects {
+		projectIDs = append(projectIDs, projectID)
+	}
+	sort.Strings(projectIDs)
+
+	summary := fmt.Sprintf("\n----------EXCLUDED PROJECTS----------\n Number of projects excluded: %d\n", len(projectIDs))
+	for _, projectID := range projectIDs {
+		summary += fmt.Sprintf(" - %s: %s\n", projectID, excludedProjects[projectID])
+	}
+	summary += "-------------------------------------------------------------------"
+
+	return summary
+}
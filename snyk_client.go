@@ -0,0 +1,401 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Errors returned by the Snyk API client, check them with errors.Is
+var (
+	ErrBadRequest    = errors.New("Bad Request, Request failed")
+	ErrUnauthorized  = errors.New("Authentication or permission error, Request failed")
+	ErrForbidden     = errors.New("Forbidden Entity, Request failed")
+	ErrNotFound      = errors.New("Not found, Request failed")
+	ErrUnprocessable = errors.New("Unprocessable Entity, Request failed")
+	ErrRateLimited   = errors.New("Rate limited, Request failed")
+	ErrServer        = errors.New("Failed too many times with 50x errors")
+	ErrConnection    = errors.New("Could not reach the endpoint, Request failed")
+	ErrRequestFailed = errors.New("Request failed")
+)
+
+// SnykAPIError carries the details of a failed request to the Snyk API
+type SnykAPIError struct {
+	Kind       error
+	StatusCode int
+	Status     string
+	Endpoint   string
+	Body       []byte
+	Cause      error
+}
+
+func (e *SnykAPIError) Error() string {
+	return e.Kind.Error()
+}
+
+func (e *SnykAPIError) Unwrap() error {
+	return e.Kind
+}
+
+// snykClient is shared by the v1 and REST requests so connections are reused
+type snykClient struct {
+	httpClient    *http.Client
+	maxRetries    int
+	baseBackoff   time.Duration
+	maxBackoff    time.Duration
+	maxRetryAfter time.Duration
+	userAgent     string
+	limiter       *rateLimiter
+	cache         *responseCache
+	auth          *oauthTokenSource
+	// report prints and journals a failed request, retryable tells the failed responses sent again
+	report    func(apiErr *SnykAPIError, customDebug debug)
+	retryable func(response *http.Response) bool
+}
+
+func newSnykClient() *snykClient {
+	return &snykClient{
+		httpClient: &http.Client{
+			Timeout: 60 * time.Second,
+		},
+		maxRetries:    5,
+		baseBackoff:   500 * time.Millisecond,
+		maxBackoff:    30 * time.Second,
+		maxRetryAfter: 2 * time.Minute,
+		userAgent:     "tech-services/snyk-jira-tickets-for-new-vulns",
+		report:        reportSnykAPIError,
+		retryable:     isRetryableResponse,
+	}
+}
+
+var defaultSnykClient = newSnykClient()
+
+/*
+**
+function do
+input verb string, endpointURL string, headers map[string]string, body []byte
+input customDebug debug
+return []byte, body of the successful response
+return error, *SnykAPIError when the request failed
+Send the request and retry on connection errors, 429 and 5xx with an
+exponential backoff and jitter. Retry-After and the rate limit reset headers
+are honoured when the API sends them.
+**
+*/
+func (c *snykClient) do(verb string, endpointURL string, headers map[string]string, body []byte, customDebug debug) ([]byte, error) {
+
+	var lastErr *SnykAPIError
+	tokenRefreshed := false
+
+	for attempt := 0; attempt <= c.maxRetries; attempt++ {
+
+		if attempt > 0 {
+			customDebug.Debug("Retrying request", "attempt", attempt, "method", verb, "endpoint", endpointURL)
+		}
+
+		// every attempt counts against the Snyk rate limit
+		c.limiter.Wait()
+
+		var bodyReader *bytes.Reader
+		if body != nil && verb != "GET" {
+			bodyReader = bytes.NewReader(body)
+		} else {
+			bodyReader = bytes.NewReader(nil)
+		}
+
+		request, err := http.NewRequest(verb, endpointURL, bodyReader)
+		if err != nil {
+			customDebug.Error("Could not create the request", "endpoint", endpointURL, "error", err)
+			return nil, &SnykAPIError{Kind: ErrRequestFailed, Endpoint: endpointURL, Cause: err}
+		}
+
+		for key, value := range headers {
+			request.Header.Set(key, value)
+		}
+		request.Header.Set("User-Agent", c.userAgent)
+
+		// with OAuth the bearer token replaces the API token for v1 and REST
+		bearerToken := ""
+		if c.auth != nil {
+			bearerToken, err = c.auth.Token(c.httpClient, customDebug)
+			if err != nil {
+				apiErr := oauthAPIError(err, c.auth.tokenURL)
+				if !errors.Is(apiErr, ErrConnection) && !isRetryableStatus(apiErr.StatusCode) {
+					c.report(apiErr, customDebug)
+					return nil, apiErr
+				}
+				customDebug.Warn("Could not get an OAuth token", "endpoint", c.auth.tokenURL, "error", err)
+				lastErr = apiErr
+				if attempt < c.maxRetries {
+					c.wait(attempt, nil, customDebug)
+				}
+				continue
+			}
+			request.Header.Set("Authorization", "Bearer "+bearerToken)
+		}
+
+		customDebug.Debug("Sending request", "method", verb, "endpoint", endpointURL)
+		if body != nil {
+			customDebug.Trace("Request body", "endpoint", endpointURL, "body", string(body))
+		}
+
+		requestStart := time.Now()
+		response, err := c.httpClient.Do(request)
+		if err != nil {
+			defaultRunMetrics.observeRequest(verb, endpointURL, "error", time.Since(requestStart))
+			customDebug.Warn("Request failed", "endpoint", endpointURL, "error", err)
+			lastErr = &SnykAPIError{Kind: ErrConnection, Endpoint: endpointURL, Cause: err}
+			if attempt < c.maxRetries {
+				c.wait(attempt, nil, customDebug)
+			}
+			continue
+		}
+
+		responseData, err := ioutil.ReadAll(response.Body)
+		response.Body.Close()
+		defaultRunMetrics.observeRequest(verb, endpointURL, strconv.Itoa(response.StatusCode), time.Since(requestStart))
+		if err != nil {
+			customDebug.Warn("Could not read the response", "endpoint", endpointURL, "error", err)
+			lastErr = &SnykAPIError{Kind: ErrConnection, StatusCode: response.StatusCode, Status: response.Status, Endpoint: endpointURL, Cause: err}
+			if attempt < c.maxRetries {
+				c.wait(attempt, nil, customDebug)
+			}
+			continue
+		}
+
+		if response.StatusCode < 300 {
+			return responseData, nil
+		}
+
+		apiErr := &SnykAPIError{
+			Kind:       errorKindForStatus(response.StatusCode),
+			StatusCode: response.StatusCode,
+			Status:     response.Status,
+			Endpoint:   endpointURL,
+			Body:       responseData,
+		}
+
+		// the token may have been revoked or expired early, get a new one once
+		if response.StatusCode == http.StatusUnauthorized && c.auth != nil && !tokenRefreshed {
+			customDebug.Info("Request was not authorized, refreshing the OAuth token", "endpoint", endpointURL)
+			c.auth.Invalidate(bearerToken)
+			tokenRefreshed = true
+			attempt--
+			continue
+		}
+
+		if !c.retryable(response) {
+			c.report(apiErr, customDebug)
+			return nil, apiErr
+		}
+
+		customDebug.Debug("Request failed, retrying", "endpoint", endpointURL, "status", response.Status)
+		lastErr = apiErr
+		if attempt < c.maxRetries {
+			c.wait(attempt, response.Header, customDebug)
+		}
+	}
+
+	customDebug.Error("Request failed too many times", "endpoint", endpointURL, "attempts", c.maxRetries+1)
+	c.report(lastErr, customDebug)
+
+	return nil, lastErr
+}
+
+/*
+**
+function oauthAPIError
+input err error, returned by the token source
+input tokenURL string
+return *SnykAPIError, unreachable endpoints and 429 or 5xx are retried like the
+other requests, only a 400 or 401 of the token endpoint is an authentication error
+**
+*/
+func oauthAPIError(err error, tokenURL string) *SnykAPIError {
+
+	apiErr := &SnykAPIError{Kind: ErrRequestFailed, Endpoint: tokenURL, Cause: err}
+
+	tokenErr := &oauthTokenError{}
+	if !errors.As(err, &tokenErr) {
+		return apiErr
+	}
+
+	apiErr.StatusCode = tokenErr.StatusCode
+	apiErr.Status = tokenErr.Status
+	switch tokenErr.StatusCode {
+	case 0:
+		apiErr.Kind = ErrConnection
+	case http.StatusBadRequest, http.StatusUnauthorized:
+		apiErr.Kind = ErrUnauthorized
+	default:
+		apiErr.Kind = errorKindForStatus(tokenErr.StatusCode)
+	}
+
+	return apiErr
+}
+
+/*
+**
+function wait
+input attempt int, number of the attempt that just failed
+input header http.Header, response headers, nil if there was no response
+Sleep before the next attempt
+**
+*/
+func (c *snykClient) wait(attempt int, header http.Header, customDebug debug) {
+
+	delay, found := retryAfter(header, time.Now())
+	if found {
+		if delay > c.maxRetryAfter {
+			delay = c.maxRetryAfter
+		}
+		customDebug.Info("Rate limited, waiting as requested by the API", "delay", delay)
+	} else {
+		delay = c.backoff(attempt)
+	}
+
+	time.Sleep(delay)
+}
+
+// backoff doubles the delay on every attempt, the jitter spreads concurrent retries
+func (c *snykClient) backoff(attempt int) time.Duration {
+
+	delay := c.baseBackoff << uint(attempt)
+	if delay <= 0 || delay > c.maxBackoff {
+		delay = c.maxBackoff
+	}
+
+	half := int64(delay / 2)
+	if half <= 0 {
+		return delay
+	}
+
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+/*
+**
+function retryAfter
+input header http.Header, response headers
+input now time.Time
+return time.Duration, how long the API asked us to wait
+return bool, false when no header asked for a delay
+Retry-After can be a number of seconds or a date, X-RateLimit-Reset a number
+of seconds or a unix timestamp
+**
+*/
+func retryAfter(header http.Header, now time.Time) (time.Duration, bool) {
+
+	if header == nil {
+		return 0, false
+	}
+
+	if value := header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second, true
+		}
+		if date, err := http.ParseTime(value); err == nil {
+			if date.Before(now) {
+				return 0, true
+			}
+			return date.Sub(now), true
+		}
+	}
+
+	if value := header.Get("X-RateLimit-Reset"); value != "" {
+		if reset, err := strconv.ParseInt(value, 10, 64); err == nil && reset >= 0 {
+			// big values are unix timestamps
+			if reset > 1000000000 {
+				date := time.Unix(reset, 0)
+				if date.Before(now) {
+					return 0, true
+				}
+				return date.Sub(now), true
+			}
+			return time.Duration(reset) * time.Second, true
+		}
+	}
+
+	return 0, false
+}
+
+// isRetryableResponse retries the 429 and 5xx responses of the Snyk API
+func isRetryableResponse(response *http.Response) bool {
+	return isRetryableStatus(response.StatusCode)
+}
+
+func isRetryableStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
+
+func errorKindForStatus(statusCode int) error {
+	switch {
+	case statusCode == http.StatusBadRequest:
+		return ErrBadRequest
+	case statusCode == http.StatusUnauthorized:
+		return ErrUnauthorized
+	case statusCode == http.StatusForbidden:
+		return ErrForbidden
+	case statusCode == http.StatusNotFound:
+		return ErrNotFound
+	case statusCode == http.StatusUnprocessableEntity:
+		return ErrUnprocessable
+	case statusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case statusCode >= 500:
+		return ErrServer
+	default:
+		return ErrRequestFailed
+	}
+}
+
+/*
+**
+function reportSnykAPIError
+input apiErr *SnykAPIError
+input customDebug debug
+Print the hints matching the error and write it in the error file
+**
+*/
+func reportSnykAPIError(apiErr *SnykAPIError, customDebug debug) {
+
+	if apiErr == nil {
+		return
+	}
+
+	status := apiErr.Status
+	if status == "" && apiErr.Cause != nil {
+		status = apiErr.Cause.Error()
+	}
+
+	fields := []interface{}{"endpoint", apiErr.Endpoint, "status", status}
+
+	switch {
+	case errors.Is(apiErr, ErrUnauthorized):
+		defaultRunStatus.authenticationFailed()
+		customDebug.Error("Request failed", append(fields, "hint", "Please check the API token and permissions")...)
+	case errors.Is(apiErr, ErrForbidden):
+		customDebug.Error("Request failed", append(fields, "hint", "Please check that all expected fields are present in the config file. Forbidden could indicate illegal strings in the body, such as Path Traversal")...)
+	case errors.Is(apiErr, ErrServer), errors.Is(apiErr, ErrRateLimited), errors.Is(apiErr, ErrConnection):
+		customDebug.Error("Request failed, ticket for this issue cannot be created. Skipping", fields...)
+	case errors.Is(apiErr, ErrNotFound):
+		customDebug.Debug("Request failed", fields...)
+	default:
+		customDebug.Error("Request failed", append(fields, "hint", "Please check that all expected fields are present in the config file")...)
+	}
+
+	if len(apiErr.Body) > 0 {
+		customDebug.Debug("Request failure details", "endpoint", apiErr.Endpoint, "details", string(apiErr.Body))
+	}
+
+	entry := newJournalEntry("makeSnykAPIRequest", fmt.Sprintf("*** INFO *** Request failed with error %s", status), customDebug)
+	entry.Endpoint = apiErr.Endpoint
+	entry.Status = apiErr.StatusCode
+	entry.Body = bodyExcerpt(apiErr.Body)
+	writeJournalEntry(entry, customDebug)
+}
@@ -5,7 +5,9 @@ import (
 	"log"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/michael-go/go-jsn/jsn"
 	"github.com/nsf/jsondiff"
 	"github.com/stretchr/testify/assert"
 )
@@ -322,7 +324,7 @@ func TestGetProjectsIdsAllProjects(t *testing.T) {
 
 	filenameNotCreated := CreateLogFile(cD, "ErrorsFile_")
 
-	list, er := getProjectsIds(flags, cD, filenameNotCreated)
+	list, excluded, er := getProjectsIds(flags, cD, filenameNotCreated)
 	listString := "[" + strings.Join(list, ",") + "]"
 
 	if er != nil {
@@ -334,6 +336,91 @@ func TestGetProjectsIdsAllProjects(t *testing.T) {
 
 	ResultList := readFixture("./fixtures/results/projectIdsList.txt")
 	assert.Equal(string(ResultList), listString)
+	assert.Equal(0, len(excluded))
+
+	return
+}
+
+// Test projectExclusionReason function
+func TestProjectExclusionReason(t *testing.T) {
+	assert := assert.New(t)
+
+	now, _ := time.Parse(time.RFC3339, "2024-06-01T00:00:00Z")
+
+	inactiveProject, _ := jsn.NewJson([]byte(`{"id": "1", "attributes": {"status": "inactive"}}`))
+	staleProject, _ := jsn.NewJson([]byte(`{"id": "2", "attributes": {"status": "active"}, "meta": {"latest_issue_counts": {"updated_at": "2024-01-01T10:00:00.000Z"}}}`))
+	recentProject, _ := jsn.NewJson([]byte(`{"id": "3", "attributes": {"status": "active"}, "meta": {"latest_issue_counts": {"updated_at": "2024-05-20T10:00:00.000Z"}}}`))
+	neverTestedProject, _ := jsn.NewJson([]byte(`{"id": "4", "attributes": {"status": "active"}, "meta": {}}`))
+
+	Of := optionalFlags{}
+	assert.Equal("", projectExclusionReason(Of, inactiveProject, now))
+	assert.Equal("", projectExclusionReason(Of, staleProject, now))
+
+	Of.skipInactiveProjects = true
+	assert.Equal("project is inactive", projectExclusionReason(Of, inactiveProject, now))
+	assert.Equal("", projectExclusionReason(Of, staleProject, now))
+
+	Of.maxProjectAge = "90d"
+	assert.Equal("project last tested on 2024-01-01, older than 90d", projectExclusionReason(Of, staleProject, now))
+	assert.Equal("", projectExclusionReason(Of, recentProject, now))
+	assert.Equal("", projectExclusionReason(Of, neverTestedProject, now))
+}
+
+func TestProjectExclusionReasonIncremental(t *testing.T) {
+	assert := assert.New(t)
+
+	now, _ := time.Parse(time.RFC3339, "2024-06-01T00:00:00Z")
+	lastRun, _ := time.Parse(time.RFC3339, "2024-05-15T00:00:00Z")
+
+	staleProject, _ := jsn.NewJson([]byte(`{"id": "2", "attributes": {"status": "active"}, "meta": {"latest_issue_counts": {"updated_at": "2024-01-01T10:00:00.000Z"}}}`))
+	recentProject, _ := jsn.NewJson([]byte(`{"id": "3", "attributes": {"status": "active"}, "meta": {"latest_issue_counts": {"updated_at": "2024-05-20T10:00:00.000Z"}}}`))
+	neverTestedProject, _ := jsn.NewJson([]byte(`{"id": "4", "attributes": {"status": "active"}, "meta": {}}`))
+
+	Of := optionalFlags{incrementalSince: lastRun}
+	assert.Equal("project not tested since the last run on 2024-05-15T00:00:00Z", projectExclusionReason(Of, staleProject, now))
+	assert.Equal("", projectExclusionReason(Of, recentProject, now))
+	assert.Equal("", projectExclusionReason(Of, neverTestedProject, now))
+
+	// the age limit is reported first
+	Of.maxProjectAge = "90d"
+	assert.Equal("project last tested on 2024-01-01, older than 90d", projectExclusionReason(Of, staleProject, now))
+}
+
+// Test getOrgProjects requests the issue counts meta when an age limit is set
+func TestGetOrgProjectsMaxProjectAge(t *testing.T) {
+	expectedTestURL := "/rest/orgs/123/projects?version=2024-10-15&limit=100&meta.latest_issue_counts=true"
+	assert := assert.New(t)
+	server := HTTPResponseCheckAndStub(expectedTestURL, "org")
+
+	defer server.Close()
+
+	// setting mandatory options
+	Mf := MandatoryFlags{}
+	Mf.orgID = "123"
+	Mf.endpointAPI = server.URL
+	Mf.apiToken = "123"
+	Mf.jiraProjectID = "123"
+
+	// setting optional options
+	Of := optionalFlags{}
+	Of.maxProjectAge = "30d"
+
+	flags := flags{}
+	flags.mandatoryFlags = Mf
+	flags.optionalFlags = Of
+
+	// setting debug
+	cD := debug{}
+	cD.setDebug(false)
+
+	CreateLogFile(cD, "ErrorsFile_")
+
+	response, err := getOrgProjects(flags, cD)
+
+	assert.Nil(err)
+	assert.Equal(2, len(response))
+
+	removeLogFile()
 
 	return
 }
@@ -2,7 +2,12 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/michael-go/go-jsn/jsn"
 	"github.com/nsf/jsondiff"
@@ -119,3 +124,97 @@ func TestRESTPaginationWithDoubleRestPrefix(t *testing.T) {
 	// Should have received data from both pages without errors
 	assert.Greater(len(response), 0, "Should have received paginated data")
 }
+
+func TestSnykClientRetryAfterFunc(t *testing.T) {
+
+	assert := assert.New(t)
+	cD := debug{}
+	cD.setDebug(false)
+	CreateLogFile(cD, "ErrorsFile_")
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		if calls == 2 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"ok": true}`))
+	}))
+	defer server.Close()
+
+	response, err := makeSnykAPIRequest("GET", server.URL+"/v1/org/123/project/123", "123", nil, cD)
+
+	assert.Nil(err)
+	assert.Equal(3, calls)
+	assert.Equal(`{"ok": true}`, string(response))
+	removeLogFile()
+}
+
+func TestSnykClientTypedErrorsFunc(t *testing.T) {
+
+	assert := assert.New(t)
+	cD := debug{}
+	cD.setDebug(false)
+	CreateLogFile(cD, "ErrorsFile_")
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch r.URL.Path {
+		case "/v1/notfound":
+			w.WriteHeader(http.StatusNotFound)
+		case "/v1/unauthorized":
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	_, err := makeSnykAPIRequest("GET", server.URL+"/v1/notfound", "123", nil, cD)
+	assert.True(errors.Is(err, ErrNotFound))
+	assert.Equal(1, calls)
+
+	_, err = makeSnykAPIRequest("GET", server.URL+"/v1/unauthorized", "123", nil, cD)
+	assert.True(errors.Is(err, ErrUnauthorized))
+
+	calls = 0
+	_, err = makeSnykAPIRequest("GET", server.URL+"/v1/server", "123", nil, cD)
+	assert.True(errors.Is(err, ErrServer))
+	assert.Equal(defaultSnykClient.maxRetries+1, calls)
+
+	var apiErr *SnykAPIError
+	assert.True(errors.As(err, &apiErr))
+	assert.Equal(http.StatusInternalServerError, apiErr.StatusCode)
+	removeLogFile()
+}
+
+func TestRetryAfterFunc(t *testing.T) {
+
+	assert := assert.New(t)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	header := http.Header{}
+	_, found := retryAfter(header, now)
+	assert.False(found)
+
+	header.Set("Retry-After", "7")
+	delay, found := retryAfter(header, now)
+	assert.True(found)
+	assert.Equal(7*time.Second, delay)
+
+	header.Set("Retry-After", now.Add(30*time.Second).Format(http.TimeFormat))
+	delay, _ = retryAfter(header, now)
+	assert.Equal(30*time.Second, delay)
+
+	header = http.Header{}
+	header.Set("X-RateLimit-Reset", fmt.Sprint(now.Add(time.Minute).Unix()))
+	delay, _ = retryAfter(header, now)
+	assert.Equal(time.Minute, delay)
+}
@@ -0,0 +1,430 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/michael-go/go-jsn/jsn"
+	"github.com/spf13/pflag"
+	bolt "go.etcd.io/bbolt"
+)
+
+// stateBucket is the top bucket of the state file, it holds a bucket per org
+// with a bucket per project keyed by issue ID
+var stateBucket = []byte("orgs")
+
+// IssueState is what the state store knows about an issue
+type IssueState struct {
+	OrgID       string    `json:"orgId"`
+	ProjectID   string    `json:"projectId"`
+	IssueID     string    `json:"issueId"`
+	IssueType   string    `json:"issueType,omitempty"`
+	Title       string    `json:"title,omitempty"`
+	Severity    string    `json:"severity,omitempty"`
+	JiraKey     string    `json:"jiraKey,omitempty"`
+	ContentHash string    `json:"contentHash,omitempty"`
+	Status      string    `json:"status"`
+	FirstSeen   time.Time `json:"firstSeen"`
+	LastSeen    time.Time `json:"lastSeen"`
+	LastRunID   string    `json:"lastRunId"`
+}
+
+// stateStore keeps the issue to ticket mappings of the org in a BoltDB file
+// between runs. The file is locked while a run uses it
+type stateStore struct {
+	db       *bolt.DB
+	orgID    string
+	runID    string
+	readOnly bool
+	now      func() time.Time
+}
+
+// defaultStateStore is nil unless --stateFile is set, every method is a no-op then
+var defaultStateStore *stateStore
+
+/*
+**
+function openStateStore
+input filename string, the file is created when missing
+input orgID string
+input readOnly bool, nothing is written, used by dry runs and exports
+return *stateStore, nil when readOnly and the file doesn't exist yet
+return error, when the file can't be opened or is locked by another run for more than 10s
+The read-only stores take a shared lock, they don't wait for each other.
+**
+*/
+func openStateStore(filename string, orgID string, readOnly bool) (*stateStore, error) {
+
+	if readOnly {
+		// nothing is stored yet, a nil store reads nothing
+		if _, err := os.Stat(filename); os.IsNotExist(err) {
+			return nil, nil
+		}
+		db, err := bolt.Open(filename, 0600, &bolt.Options{ReadOnly: true, Timeout: 10 * time.Second})
+		if err != nil {
+			return nil, err
+		}
+		return &stateStore{db: db, orgID: orgID, runID: defaultRunOutput.runID, readOnly: readOnly, now: time.Now}, nil
+	}
+
+	db, err := bolt.Open(filename, 0600, &bolt.Options{Timeout: 10 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(stateBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &stateStore{db: db, orgID: orgID, runID: defaultRunOutput.runID, readOnly: readOnly, now: time.Now}, nil
+}
+
+/*
+**
+function close
+return error
+The store can't be used afterwards
+**
+*/
+func (s *stateStore) close() error {
+
+	if s == nil {
+		return nil
+	}
+
+	return s.db.Close()
+}
+
+/*
+**
+function update
+input projectID string
+input issueID string
+input change func(*IssueState), changes the state of the issue, it is created when missing
+return error
+**
+*/
+func (s *stateStore) update(projectID string, issueID string, change func(*IssueState)) error {
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+
+		org, err := tx.Bucket(stateBucket).CreateBucketIfNotExists([]byte(s.orgID))
+		if err != nil {
+			return err
+		}
+		project, err := org.CreateBucketIfNotExists([]byte(projectID))
+		if err != nil {
+			return err
+		}
+
+		now := s.now().UTC()
+		state := IssueState{OrgID: s.orgID, ProjectID: projectID, IssueID: issueID, FirstSeen: now}
+		if data := project.Get([]byte(issueID)); data != nil {
+			if err := json.Unmarshal(data, &state); err != nil {
+				return err
+			}
+		}
+
+		change(&state)
+		state.LastSeen = now
+		state.LastRunID = s.runID
+
+		data, err := json.Marshal(state)
+		if err != nil {
+			return err
+		}
+
+		return project.Put([]byte(issueID), data)
+	})
+}
+
+/*
+**
+function record
+input projectID string
+input issue jsn.Json, any of the issue shapes read by describeIssue
+input outcome string, one of the outcome constants, kept as the status
+input jiraKey string, the key of the ticket, an empty key keeps the known one
+**
+*/
+func (s *stateStore) record(projectID string, issue jsn.Json, outcome string, jiraKey string) {
+
+	if s == nil || s.readOnly {
+		return
+	}
+
+	described := describeIssue(issue)
+	err := s.update(projectID, described.IssueID, func(state *IssueState) {
+		state.IssueType = described.IssueType
+		state.Title = described.Title
+		state.Severity = described.Severity
+		state.Status = outcome
+		if jiraKey != "" {
+			state.JiraKey = jiraKey
+		}
+	})
+	if err != nil {
+		logger.Error("Could not save the state of the issue", "project", projectID, "issue", described.IssueID, "error", err)
+	}
+}
+
+/*
+**
+function setContentHash
+input projectID string
+input issueID string
+input hash string, hash of the payload sent to create the ticket, see payloadHash
+**
+*/
+func (s *stateStore) setContentHash(projectID string, issueID string, hash string) {
+
+	if s == nil || s.readOnly || hash == "" {
+		return
+	}
+
+	err := s.update(projectID, issueID, func(state *IssueState) {
+		state.ContentHash = hash
+	})
+	if err != nil {
+		logger.Error("Could not save the content hash of the ticket", "project", projectID, "issue", issueID, "error", err)
+	}
+}
+
+/*
+**
+function get
+input projectID string
+input issueID string
+return IssueState
+return bool, false when the issue is not in the store
+**
+*/
+func (s *stateStore) get(projectID string, issueID string) (IssueState, bool) {
+
+	state := IssueState{}
+	found := false
+	if s == nil {
+		return state, found
+	}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		project := s.projectBucket(tx, projectID)
+		if project == nil {
+			return nil
+		}
+		data := project.Get([]byte(issueID))
+		if data == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(data, &state)
+	})
+	if err != nil {
+		logger.Error("Could not read the state of the issue", "project", projectID, "issue", issueID, "error", err)
+		return state, false
+	}
+
+	return state, found
+}
+
+// orgBucket is nil when nothing was stored for the org yet
+func (s *stateStore) orgBucket(tx *bolt.Tx) *bolt.Bucket {
+
+	// missing in a file opened read-only before any run wrote it
+	orgs := tx.Bucket(stateBucket)
+	if orgs == nil {
+		return nil
+	}
+
+	return orgs.Bucket([]byte(s.orgID))
+}
+
+// projectBucket is nil when nothing was stored for the project yet
+func (s *stateStore) projectBucket(tx *bolt.Tx, projectID string) *bolt.Bucket {
+
+	org := s.orgBucket(tx)
+	if org == nil {
+		return nil
+	}
+
+	return org.Bucket([]byte(projectID))
+}
+
+/*
+**
+function mergeTickets
+input projectID string
+input tickets map[string]string, issue ID to Jira key returned by getJiraTickets
+input customDebug debug
+return map[string]string, the tickets with the ones only known by the store added
+The tickets created by another route or lost by Snyk are not created again
+**
+*/
+func (s *stateStore) mergeTickets(projectID string, tickets map[string]string, customDebug debug) map[string]string {
+
+	if s == nil {
+		return tickets
+	}
+
+	merged := make(map[string]string, len(tickets))
+	for issueID, jiraKey := range tickets {
+		merged[issueID] = jiraKey
+	}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		project := s.projectBucket(tx, projectID)
+		if project == nil {
+			return nil
+		}
+		return project.ForEach(func(issueID []byte, data []byte) error {
+			state := IssueState{}
+			if err := json.Unmarshal(data, &state); err != nil {
+				return err
+			}
+			if _, found := merged[string(issueID)]; !found && state.JiraKey != "" {
+				customDebug.Debug("Ticket only known by the state store", "issue", string(issueID), "jiraKey", state.JiraKey)
+				merged[string(issueID)] = state.JiraKey
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		customDebug.Error("Could not read the tickets of the state store", "error", err)
+		return tickets
+	}
+
+	return merged
+}
+
+/*
+**
+function list
+return []IssueState, the issues of the org sorted by project then issue ID
+return error
+**
+*/
+func (s *stateStore) list() ([]IssueState, error) {
+
+	states := []IssueState{}
+	if s == nil {
+		return states, nil
+	}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		org := s.orgBucket(tx)
+		if org == nil {
+			return nil
+		}
+		// bolt keys are sorted
+		return org.ForEach(func(projectID []byte, _ []byte) error {
+			return org.Bucket(projectID).ForEach(func(_ []byte, data []byte) error {
+				state := IssueState{}
+				if err := json.Unmarshal(data, &state); err != nil {
+					return err
+				}
+				states = append(states, state)
+				return nil
+			})
+		})
+	})
+
+	return states, err
+}
+
+/*
+**
+function payloadHash
+input payload []byte, JSON body of the ticket
+return string, sha256 of the compacted payload, empty when there is no payload
+**
+*/
+func payloadHash(payload []byte) string {
+
+	if len(payload) == 0 {
+		return ""
+	}
+
+	sum := sha256.Sum256(compactJSON(payload))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// compactJSON removes the indentation so the same JSON always gives the same bytes
+func compactJSON(data []byte) []byte {
+
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, data); err != nil {
+		return data
+	}
+
+	return compacted.Bytes()
+}
+
+/*
+**
+function runStateCommand
+input args []string, arguments after "state"
+return int, exit code
+Handle the state subcommands, only export for now:
+
+	state export --stateFile=<file> --orgID=<org>
+
+**
+*/
+func runStateCommand(args []string) int {
+
+	if len(args) == 0 || args[0] != "export" {
+		logger.Error("Unknown state command", "usage", "state export --stateFile=<file> --orgID=<org>")
+		return exitConfigError
+	}
+
+	fs := pflag.NewFlagSet("state export", pflag.ContinueOnError)
+	stateFile := fs.String("stateFile", "", "State file to export")
+	orgID := fs.String("orgID", "", "Org of the issues to export")
+	if err := fs.Parse(args[1:]); err != nil {
+		logger.Error("Error parsing command line arguments", "error", err)
+		return exitConfigError
+	}
+
+	if len(*stateFile) == 0 || len(*orgID) == 0 {
+		logger.Error("--stateFile and --orgID are required")
+		return exitConfigError
+	}
+
+	// the file is not created by an export
+	if _, err := os.Stat(*stateFile); err != nil {
+		logger.Error("Could not open the state file", "stateFile", *stateFile, "error", err)
+		return exitConfigError
+	}
+
+	store, err := openStateStore(*stateFile, *orgID, true)
+	if err != nil {
+		logger.Error("Could not open the state file", "stateFile", *stateFile, "error", err)
+		return exitError
+	}
+	defer store.close()
+
+	states, err := store.list()
+	if err != nil {
+		logger.Error("Could not read the state file", "stateFile", *stateFile, "error", err)
+		return exitError
+	}
+
+	data, err := json.MarshalIndent(states, "", "  ")
+	if err != nil {
+		logger.Error("Could not export the state", "error", err)
+		return exitError
+	}
+	fmt.Println(string(data))
+
+	return exitSuccess
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/michael-go/go-jsn/jsn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStateStoreFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	filename := filepath.Join(t.TempDir(), "state.db")
+	store, err := openStateStore(filename, "123", false)
+	assert.Nil(err)
+
+	firstRun := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	store.runID = "run-1"
+	store.now = func() time.Time { return firstRun }
+
+	vuln, _ := jsn.NewJson(map[string]interface{}{
+		"id":        "SNYK-JS-MINIMIST-559764",
+		"issueType": "vuln",
+		"issueData": map[string]interface{}{"title": "Prototype Pollution", "severity": "high"},
+	})
+
+	store.record("project-a", vuln, outcomeCreated, "FPI-1")
+	store.setContentHash("project-a", "SNYK-JS-MINIMIST-559764", payloadHash([]byte(`{"fields": {"summary": "Prototype Pollution"}}`)))
+
+	// a later run keeps the first seen date and the known key
+	store.runID = "run-2"
+	store.now = func() time.Time { return firstRun.Add(24 * time.Hour) }
+	store.record("project-a", vuln, outcomeAlreadyTicketed, "")
+	store.record("project-b", vuln, outcomeFiltered, "")
+
+	state, found := store.get("project-a", "SNYK-JS-MINIMIST-559764")
+	assert.True(found)
+	assert.Equal(IssueState{
+		OrgID:       "123",
+		ProjectID:   "project-a",
+		IssueID:     "SNYK-JS-MINIMIST-559764",
+		IssueType:   "vuln",
+		Title:       "Prototype Pollution",
+		Severity:    "high",
+		JiraKey:     "FPI-1",
+		ContentHash: payloadHash([]byte(`{"fields":{"summary":"Prototype Pollution"}}`)),
+		Status:      outcomeAlreadyTicketed,
+		FirstSeen:   firstRun,
+		LastSeen:    firstRun.Add(24 * time.Hour),
+		LastRunID:   "run-2",
+	}, state)
+
+	_, found = store.get("project-c", "SNYK-JS-MINIMIST-559764")
+	assert.False(found)
+
+	// the tickets of Snyk win, the ones only in the store are added
+	merged := store.mergeTickets("project-a", map[string]string{"SNYK-JS-OTHER-1": "FPI-2"}, debug{})
+	assert.Equal(map[string]string{"SNYK-JS-OTHER-1": "FPI-2", "SNYK-JS-MINIMIST-559764": "FPI-1"}, merged)
+	merged = store.mergeTickets("project-b", map[string]string{}, debug{})
+	assert.Equal(0, len(merged))
+
+	states, err := store.list()
+	assert.Nil(err)
+	assert.Equal(2, len(states))
+	assert.Equal("project-b", states[1].ProjectID)
+	assert.Nil(store.close())
+
+	// a dry run reads the store without changing it
+	store, err = openStateStore(filename, "123", true)
+	assert.Nil(err)
+	store.record("project-a", vuln, outcomeDryRun, "")
+	state, _ = store.get("project-a", "SNYK-JS-MINIMIST-559764")
+	assert.Equal(outcomeAlreadyTicketed, state.Status)
+
+	// the read-only stores share the lock, an export doesn't wait for the dry run
+	export, err := openStateStore(filename, "123", true)
+	assert.Nil(err)
+	states, err = export.list()
+	assert.Nil(err)
+	assert.Equal(2, len(states))
+	assert.Nil(export.close())
+	assert.Nil(store.close())
+
+	// a dry run doesn't create the file
+	missing := filepath.Join(t.TempDir(), "missing.db")
+	store, err = openStateStore(missing, "123", true)
+	assert.Nil(err)
+	assert.Nil(store)
+	_, err = os.Stat(missing)
+	assert.True(os.IsNotExist(err))
+
+	// without store nothing is recorded
+	var noStore *stateStore
+	noStore.record("project-a", vuln, outcomeCreated, "FPI-1")
+	assert.Equal(map[string]string{"a": "b"}, noStore.mergeTickets("project-a", map[string]string{"a": "b"}, debug{}))
+	assert.Nil(noStore.close())
+}
+
+func TestStateStoreAvoidsDuplicatesFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	server := HTTPResponseEndToEnd()
+	defer server.Close()
+
+	cD := debug{}
+	CreateLogFile(cD, ErrorsFilePrefix)
+	defer removeLogFile()
+
+	store, err := openStateStore(filepath.Join(t.TempDir(), "state.db"), "123", false)
+	assert.Nil(err)
+	defaultStateStore = store
+	defer func() {
+		store.close()
+		defaultStateStore = nil
+	}()
+
+	// the ticket was created by another route, Snyk doesn't know it
+	store.update("123", "SNYK-JS-ACORN-559469", func(state *IssueState) {
+		state.JiraKey = "FPI-9"
+		state.Status = outcomeCreated
+	})
+
+	options := flags{}
+	options.mandatoryFlags = MandatoryFlags{orgID: "123", endpointAPI: server.URL, apiToken: "123", jiraProjectID: "123"}
+	options.optionalFlags = optionalFlags{severity: "low", issueType: "all", jiraTicketType: "Bug", concurrency: 1, issueConcurrency: 1}
+
+	defaultRunStatus = &runStatus{}
+	result := processProject(options, "123", nil, cD)
+
+	assert.False(result.failed)
+	assert.Equal(2, defaultRunStatus.ticketsCreated)
+	assert.True(strings.Contains(result.summary, "Number of tickets created: 2"))
+
+	state, _ := store.get("123", "SNYK-JS-ACORN-559469")
+	assert.Equal(outcomeAlreadyTicketed, state.Status)
+	assert.Equal("FPI-9", state.JiraKey)
+
+	state, found := store.get("123", "SNYK-JS-DOTPROP-543489")
+	assert.True(found)
+	assert.Equal(outcomeCreated, state.Status)
+	assert.NotEqual("", state.JiraKey)
+	assert.True(strings.HasPrefix(state.ContentHash, "sha256:"))
+}
+
+func TestStateCommandFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	filename := filepath.Join(t.TempDir(), "state.db")
+	assert.Equal(exitConfigError, runStateCommand([]string{"export", "--stateFile=" + filename, "--orgID=123"}))
+	assert.Equal(exitConfigError, runStateCommand([]string{"list"}))
+
+	store, _ := openStateStore(filename, "123", false)
+	vuln, _ := jsn.NewJson(map[string]interface{}{"id": "code-1", "attributes": map[string]interface{}{"title": "SQL Injection", "severity": "high"}})
+	store.record("project-a", vuln, outcomeCreated, "FPI-1")
+	store.close()
+
+	rescueStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	exitCode := runStateCommand([]string{"export", "--stateFile=" + filename, "--orgID=123"})
+	w.Close()
+	os.Stdout = rescueStdout
+
+	out := make([]byte, 4096)
+	n, _ := r.Read(out)
+	assert.Equal(exitSuccess, exitCode)
+	assert.Contains(string(out[:n]), `"jiraKey": "FPI-1"`)
+	assert.Contains(string(out[:n]), `"issueType": "code"`)
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Trackers the tickets can be created in, selected with --tracker or per route
+const (
+	TrackerJira   = "jira"
+	TrackerGitHub = "github"
+)
+
+// ticketContent is what the formatters produce for every tracker, the body is markdown
+type ticketContent struct {
+	ProjectID string
+	IssueID   string
+	// vuln, license or code
+	IssueType string
+	Severity  string
+	Title     string
+	Body      string
+}
+
+// trackerRequest is the request creating a ticket, it is kept in the dry run tickets and in the plans
+type trackerRequest struct {
+	IssueID     string
+	Endpoint    string
+	Payload     []byte
+	Summary     string
+	Description string
+}
+
+// tracker is an issue tracker the tickets are created in
+type tracker interface {
+	// name is the value of --tracker
+	name() string
+	// target is the Jira project or the repository of the tickets
+	target() string
+	// existingTickets maps the issue IDs of the project to the keys of their tickets
+	existingTickets(projectID string, customDebug debug) (map[string]string, error)
+	// newRequest renders the content for the tracker, nothing is sent
+	newRequest(content ticketContent, customDebug debug) (trackerRequest, error)
+	// create sends the request and returns the response and the key of the ticket
+	create(request trackerRequest, customDebug debug) ([]byte, *JiraDetailForTicket, error)
+}
+
+// trackerRoute sends the tickets of the matching projects to another tracker or Jira project
+type trackerRoute struct {
+	// project IDs or names, the names can be glob patterns
+	Projects       []string `yaml:"projects"`
+	Tracker        string   `yaml:"tracker"`
+	JiraProjectID  string   `yaml:"jiraProjectID"`
+	JiraProjectKey string   `yaml:"jiraProjectKey"`
+	GitHubRepo     string   `yaml:"githubRepo"`
+}
+
+func isTracker(name string) bool {
+	return name == TrackerJira || name == TrackerGitHub
+}
+
+/*
+**
+function readTrackerRoutes
+input yamlFile []byte, the config file
+return []trackerRoute, the routes section in order
+return error, when the section cannot be read
+**
+*/
+func readTrackerRoutes(yamlFile []byte) ([]trackerRoute, error) {
+
+	var config struct {
+		Routes []trackerRoute `yaml:"routes"`
+	}
+	if err := yaml.Unmarshal(yamlFile, &config); err != nil {
+		return nil, err
+	}
+
+	return config.Routes, nil
+}
+
+/*
+**
+function matches
+input projectID string
+input projectName string
+return bool, true when a pattern is the project ID or matches the project name
+**
+*/
+func (route trackerRoute) matches(projectID string, projectName string) bool {
+
+	for _, pattern := range route.Projects {
+		if pattern == projectID {
+			return true
+		}
+		if matched, _ := path.Match(pattern, projectName); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+**
+function check
+input Mf MandatoryFlags, the target of the route falls back on the global options
+return error, when the route has no project or no target
+**
+*/
+func (route trackerRoute) check(Mf MandatoryFlags) error {
+
+	if len(route.Projects) == 0 {
+		return errors.New("projects is required")
+	}
+
+	tracker := route.tracker(Mf)
+	switch tracker {
+	case TrackerJira:
+		if route.JiraProjectID != "" && route.JiraProjectKey != "" {
+			return errors.New("set jiraProjectID or jiraProjectKey, not both")
+		}
+		if route.JiraProjectID == "" && route.JiraProjectKey == "" && Mf.jiraProjectID == "" && Mf.jiraProjectKey == "" {
+			return errors.New("jiraProjectID or jiraProjectKey is required")
+		}
+	case TrackerGitHub:
+		if route.GitHubRepo == "" && Mf.githubRepo == "" {
+			return errors.New("githubRepo is required")
+		}
+		if Mf.githubToken == "" {
+			return errors.New("githubToken or the GITHUB_TOKEN env var is required")
+		}
+	default:
+		return fmt.Errorf("tracker %s is not supported, use %s or %s", tracker, TrackerJira, TrackerGitHub)
+	}
+
+	return nil
+}
+
+// tracker is the tracker of the route, the global one when not set
+func (route trackerRoute) tracker(Mf MandatoryFlags) string {
+
+	if route.Tracker != "" {
+		return route.Tracker
+	}
+	if Mf.tracker != "" {
+		return Mf.tracker
+	}
+
+	return TrackerJira
+}
+
+/*
+**
+function trackerFor
+input projectID string
+input projectName string
+return tracker, of the first route matching the project, the global one otherwise
+**
+*/
+func (options flags) trackerFor(projectID string, projectName string) tracker {
+
+	for _, route := range options.routes {
+		if route.matches(projectID, projectName) {
+			return options.newTracker(route)
+		}
+	}
+
+	return options.newTracker(trackerRoute{})
+}
+
+/*
+**
+function trackerOf
+input name string, the tracker of a plan item
+input target string, the Jira project or the repository of the plan item
+return tracker
+The endpoint and the payload of the plan already hold the Jira project, the
+repository is needed to list the existing GitHub issues.
+**
+*/
+func (options flags) trackerOf(name string, target string) tracker {
+
+	if name == TrackerGitHub {
+		return options.newTracker(trackerRoute{Tracker: TrackerGitHub, GitHubRepo: target})
+	}
+
+	return options.newTracker(trackerRoute{Tracker: TrackerJira})
+}
+
+// newTracker creates the tracker of the route, the global options are used for what the route doesn't set
+func (options flags) newTracker(route trackerRoute) tracker {
+
+	if route.tracker(options.mandatoryFlags) == TrackerGitHub {
+		repo := route.GitHubRepo
+		if repo == "" {
+			repo = options.mandatoryFlags.githubRepo
+		}
+		return newGitHubTracker(options.mandatoryFlags.githubAPI, repo, options.mandatoryFlags.githubToken, options.optionalFlags.labels)
+	}
+
+	if route.JiraProjectKey != "" {
+		options.mandatoryFlags.jiraProjectKey = route.JiraProjectKey
+		options.mandatoryFlags.jiraProjectID = ""
+	} else if route.JiraProjectID != "" {
+		options.mandatoryFlags.jiraProjectID = route.JiraProjectID
+		options.mandatoryFlags.jiraProjectKey = ""
+	}
+
+	return snykJiraTracker{options: options}
+}
+
+// ticketURL links a Jira ticket in the reports, the GitHub keys (owner/repo#1) are not linked
+func ticketURL(jiraURL string, key string) string {
+
+	if len(key) == 0 || len(jiraURL) == 0 || strings.Contains(key, "#") {
+		return ""
+	}
+
+	return jiraURL + "/browse/" + key
+}
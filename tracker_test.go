@@ -0,0 +1,286 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/michael-go/go-jsn/jsn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrackerRoutesFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	routes, err := readTrackerRoutes([]byte(`
+snyk:
+  orgID: "123"
+jira:
+  jiraProjectKey: FPI
+routes:
+  - projects: ["payments/*"]
+    tracker: github
+    githubRepo: acme/payments
+  - projects: ["0e9373a6-0a6d-4bbc-8c2e-0f7b3b1b9a9b"]
+    jiraProjectKey: LEGACY
+`))
+	assert.Nil(err)
+	assert.Equal(2, len(routes))
+
+	options := flags{routes: routes}
+	options.mandatoryFlags = MandatoryFlags{orgID: "123", jiraProjectKey: "FPI", githubToken: "gh-token"}
+
+	tracker := options.trackerFor("project-a", "payments/api:package.json")
+	assert.Equal(TrackerGitHub, tracker.name())
+	assert.Equal("acme/payments", tracker.target())
+
+	tracker = options.trackerFor("0e9373a6-0a6d-4bbc-8c2e-0f7b3b1b9a9b", "legacy:pom.xml")
+	assert.Equal(TrackerJira, tracker.name())
+	assert.Equal("LEGACY", tracker.target())
+
+	// no route matches, the global options are used
+	tracker = options.trackerFor("project-b", "frontend:package.json")
+	assert.Equal(TrackerJira, tracker.name())
+	assert.Equal("FPI", tracker.target())
+
+	for _, route := range routes {
+		assert.Nil(route.check(options.mandatoryFlags))
+	}
+	assert.NotNil(trackerRoute{}.check(options.mandatoryFlags))
+	assert.NotNil(trackerRoute{Projects: []string{"*"}, Tracker: "gitlab"}.check(options.mandatoryFlags))
+	assert.NotNil(trackerRoute{Projects: []string{"*"}, Tracker: TrackerGitHub}.check(options.mandatoryFlags))
+	assert.NotNil(trackerRoute{Projects: []string{"*"}, JiraProjectID: "1", JiraProjectKey: "A"}.check(options.mandatoryFlags))
+
+	assert.Equal("https://jira.example.com/browse/FPI-1", ticketURL("https://jira.example.com", "FPI-1"))
+	assert.Equal("", ticketURL("https://jira.example.com", "acme/payments#12"))
+}
+
+func TestGitHubTrackerFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	requests := []string{}
+	bodies := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal("Bearer gh-token", r.Header.Get("Authorization"))
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		bodies[r.Method+" "+r.URL.Path] = string(body)
+
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/acme/payments/issues":
+			assert.Equal("all", r.URL.Query().Get("state"))
+			assert.Equal("snyk", r.URL.Query().Get("labels"))
+			w.Write([]byte(`[
+				{"number": 3, "body": "text\n\n<!-- snyk-issue: project-a/SNYK-JS-MINIMIST-559764 -->\n", "state": "closed"},
+				{"number": 4, "body": "<!-- snyk-issue: project-b/SNYK-JS-ACORN-559469 -->"},
+				{"number": 5, "body": "<!-- snyk-issue: project-a/SNYK-JS-PR-1 -->", "pull_request": {}},
+				{"number": 6, "body": "opened by hand"}
+			]`))
+		case r.Method == http.MethodPost && r.URL.Path == "/repos/acme/payments/issues":
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"number": 12, "html_url": "https://github.com/acme/payments/issues/12"}`))
+		default:
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+
+	tracker := newGitHubTracker(server.URL+"/", "acme/payments", "gh-token", "security, snyk")
+	cD := debug{}
+
+	tickets, err := tracker.existingTickets("project-a", cD)
+	assert.Nil(err)
+	assert.Equal(map[string]string{"SNYK-JS-MINIMIST-559764": "acme/payments#3"}, tickets)
+
+	content := ticketContent{ProjectID: "project-a", IssueID: "SNYK-JS-ACORN-559469", Title: "payments - Acorn ReDoS", Body: "**Impacted Paths:**"}
+	request, err := tracker.newRequest(content, cD)
+	assert.Nil(err)
+	assert.Equal(server.URL+"/repos/acme/payments/issues", request.Endpoint)
+
+	_, detail, err := tracker.create(request, cD)
+	assert.Nil(err)
+	assert.Equal("acme/payments#12", detail.JiraIssue.Key)
+	assert.Equal("SNYK-JS-ACORN-559469", detail.IssueId)
+
+	payload := struct {
+		Title  string   `json:"title"`
+		Body   string   `json:"body"`
+		Labels []string `json:"labels"`
+	}{}
+	assert.Nil(json.Unmarshal([]byte(bodies["POST /repos/acme/payments/issues"]), &payload))
+	assert.Equal("payments - Acorn ReDoS", payload.Title)
+	assert.Contains(payload.Body, "**Impacted Paths:**")
+	assert.Contains(payload.Body, "<!-- snyk-issue: project-a/SNYK-JS-ACORN-559469 -->")
+	assert.Equal([]string{"snyk", "security"}, payload.Labels)
+
+	assert.Equal([]string{
+		"GET /repos/acme/payments/issues",
+		"POST /repos/acme/payments/issues",
+	}, requests)
+}
+
+func TestGitHubErrorsFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	cD := debug{}
+	CreateLogFile(cD, ErrorsFilePrefix)
+	defer removeLogFile()
+
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		switch r.URL.Path {
+		case "/repos/acme/limited/issues":
+			// secondary rate limit, then the issue is created
+			if attempts == 1 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"number": 1}`))
+		case "/repos/acme/private/issues":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	defaultRunStatus = &runStatus{}
+	request := trackerRequest{IssueID: "SNYK-JS-1", Endpoint: server.URL + "/repos/acme/limited/issues", Payload: []byte(`{}`)}
+	_, detail, err := newGitHubTracker(server.URL, "acme/limited", "gh-token", "").create(request, cD)
+	assert.Nil(err)
+	assert.Equal("acme/limited#1", detail.JiraIssue.Key)
+	assert.Equal(2, attempts)
+
+	// a 403 without a rate limit is not retried
+	attempts = 0
+	request.Endpoint = server.URL + "/repos/acme/private/issues"
+	_, _, err = newGitHubTracker(server.URL, "acme/private", "gh-token", "").create(request, cD)
+	assert.True(errors.Is(err, ErrForbidden))
+	assert.Equal(1, attempts)
+
+	// a rejected GitHub token is not a Snyk authentication error
+	request.Endpoint = server.URL + "/repos/acme/payments/issues"
+	_, _, err = newGitHubTracker(server.URL, "acme/payments", "bad-token", "").create(request, cD)
+	assert.True(errors.Is(err, ErrUnauthorized))
+	assert.False(defaultRunStatus.hasAuthFailed())
+
+	journal, _ := findLogFile(ErrorsFilePrefix)
+	content, _ := ioutil.ReadFile(journal)
+	assert.Contains(string(content), "githubAPIRequest")
+	assert.NotContains(string(content), "makeSnykAPIRequest")
+}
+
+func TestOpenGitHubTicketDryRunFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	projectInfo, _ := jsn.NewJson(readFixture("./fixtures/project.json"))
+	vulnsForJira := make(map[string]interface{})
+	if err := json.Unmarshal(readFixture("./fixtures/vulnForJiraAggregatedWithPath.json"), &vulnsForJira); err != nil {
+		panic(err)
+	}
+
+	options := flags{}
+	options.mandatoryFlags = MandatoryFlags{orgID: "123", endpointAPI: "https://api.snyk.io", apiToken: "123", tracker: TrackerGitHub, githubRepo: "acme/typescript", githubToken: "gh-token"}
+	options.optionalFlags = optionalFlags{dryRun: true, jiraTicketType: "Bug"}
+
+	_, ticket, err, _ := openJiraTicket(options, projectInfo, vulnsForJira["SNYK-JS-MINIMIST-559764"], debug{})
+	assert.NotNil(err)
+
+	assert.Equal(TrackerGitHub, ticket.Tracker)
+	assert.Equal("acme/typescript", ticket.JiraProject)
+	assert.Equal(GitHubAPI+"/repos/acme/typescript/issues", ticket.Endpoint)
+	// the markdown is kept, the Jira tickets get Confluence wiki markup
+	assert.True(strings.Contains(ticket.Description, "**Impacted Paths:**"))
+	assert.True(strings.Contains(ticket.Description, "<!-- snyk-issue: 12345678-1234-1234-1234-123456789012/SNYK-JS-MINIMIST-559764 -->"))
+
+	options.mandatoryFlags.tracker = ""
+	options.mandatoryFlags.jiraProjectKey = "FPI"
+	_, ticket, _, _ = openJiraTicket(options, projectInfo, vulnsForJira["SNYK-JS-MINIMIST-559764"], debug{})
+	assert.Equal(TrackerJira, ticket.Tracker)
+	assert.Equal("FPI", ticket.JiraProject)
+	assert.False(strings.Contains(ticket.Description, "**Impacted Paths:**"))
+}
+
+func TestGitHubTicketRefusedNotRetriedFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	cD := debug{}
+	CreateLogFile(cD, ErrorsFilePrefix)
+	defer removeLogFile()
+
+	posts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posts++
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(`{"message": "Validation Failed"}`))
+	}))
+	defer server.Close()
+
+	projectInfo, _ := jsn.NewJson(readFixture("./fixtures/project.json"))
+	vulnsForJira := make(map[string]interface{})
+	if err := json.Unmarshal(readFixture("./fixtures/vulnForJiraAggregatedWithPath.json"), &vulnsForJira); err != nil {
+		panic(err)
+	}
+	refused := map[string]interface{}{"SNYK-JS-MINIMIST-559764": vulnsForJira["SNYK-JS-MINIMIST-559764"]}
+
+	options := flags{}
+	options.mandatoryFlags = MandatoryFlags{orgID: "123", endpointAPI: server.URL, apiToken: "123", tracker: TrackerGitHub, githubAPI: server.URL, githubRepo: "acme/typescript", githubToken: "gh-token"}
+	options.optionalFlags = optionalFlags{jiraTicketType: "Bug", priorityIsSeverity: true}
+
+	defaultRunStatus = &runStatus{}
+	created, _, notCreated, _ := openJiraTickets(options, projectInfo, refused, cD)
+
+	// the priority fallback only applies to Jira, the issue is posted once
+	assert.Equal(0, created)
+	assert.Equal(1, posts)
+	assert.Equal(1, defaultRunStatus.ticketsFailed)
+	assert.Contains(notCreated, "SNYK-JS-MINIMIST-559764")
+}
+
+func TestGitHubIssuesListedOncePerRunFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	lists := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lists++
+		w.Write([]byte(`[
+			{"number": 1, "body": "<!-- snyk-issue: project-a/SNYK-JS-1 -->"},
+			{"number": 2, "body": "<!-- snyk-issue: project-b/SNYK-JS-2 -->"},
+			{"number": 3, "body": "<!-- snyk-issue: project-b/SNYK-JS-3 -->", "pull_request": {}}
+		]`))
+	}))
+	defer server.Close()
+
+	defaultGitHubIssues = newGitHubIssueCache()
+	defer func() { defaultGitHubIssues = newGitHubIssueCache() }()
+
+	tracker := newGitHubTracker(server.URL, "acme/shared", "gh-token", "")
+	ticketsA, err := tracker.existingTickets("project-a", debug{})
+	assert.Nil(err)
+	ticketsB, err := tracker.existingTickets("project-b", debug{})
+	assert.Nil(err)
+
+	// the projects sharing the repository get their own issues from one listing
+	assert.Equal(1, lists)
+	assert.Equal(map[string]string{"SNYK-JS-1": "acme/shared#1"}, ticketsA)
+	assert.Equal(map[string]string{"SNYK-JS-2": "acme/shared#2"}, ticketsB)
+
+	// the next run lists the repository again
+	defaultGitHubIssues = newGitHubIssueCache()
+	_, err = tracker.existingTickets("project-a", debug{})
+	assert.Nil(err)
+	assert.Equal(2, lists)
+}
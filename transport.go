@@ -0,0 +1,141 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+/*
+**
+function newTransport
+input Of optionalFlags, proxy, noProxy, caBundle, clientCert, clientKey and minTLSVersion are used
+return *http.Transport, used for every request sent by the tool
+return error, when a file can't be read or a value is not valid
+Without proxy option the HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables are used
+**
+*/
+func newTransport(Of optionalFlags) (*http.Transport, error) {
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	tlsConfig := &tls.Config{}
+
+	if len(Of.proxy) > 0 {
+		proxyURL, err := url.Parse(Of.proxy)
+		if err != nil || len(proxyURL.Host) == 0 {
+			return nil, fmt.Errorf("%s is not a valid proxy URL", Of.proxy)
+		}
+		noProxy := strings.Split(Of.noProxy, ",")
+		transport.Proxy = func(request *http.Request) (*url.URL, error) {
+			if bypassProxy(request.URL.Host, noProxy) {
+				return nil, nil
+			}
+			return proxyURL, nil
+		}
+	}
+
+	if len(Of.caBundle) > 0 {
+		pem, err := ioutil.ReadFile(Of.caBundle)
+		if err != nil {
+			return nil, fmt.Errorf("could not read the CA bundle %s: %s", Of.caBundle, err.Error())
+		}
+
+		// the bundle is added to the system CAs, it does not replace them
+		rootCAs, err := x509.SystemCertPool()
+		if err != nil || rootCAs == nil {
+			rootCAs = x509.NewCertPool()
+		}
+		if !rootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in the CA bundle %s", Of.caBundle)
+		}
+		tlsConfig.RootCAs = rootCAs
+	}
+
+	if len(Of.clientCert) > 0 || len(Of.clientKey) > 0 {
+		if len(Of.clientCert) == 0 || len(Of.clientKey) == 0 {
+			return nil, errors.New("clientCert and clientKey must be set together")
+		}
+		certificate, err := tls.LoadX509KeyPair(Of.clientCert, Of.clientKey)
+		if err != nil {
+			return nil, fmt.Errorf("could not load the client certificate: %s", err.Error())
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	if len(Of.minTLSVersion) > 0 {
+		version, found := tlsVersions[Of.minTLSVersion]
+		if !found {
+			return nil, fmt.Errorf("%s is not a valid minTLSVersion. Must be one of 1.0, 1.1, 1.2, 1.3", Of.minTLSVersion)
+		}
+		tlsConfig.MinVersion = version
+	}
+
+	transport.TLSClientConfig = tlsConfig
+
+	return transport, nil
+}
+
+/*
+**
+function bypassProxy
+input host string, host of the request with an optional port
+input noProxy []string, hosts, domains, IPs or CIDRs that are reached directly, * for all
+return bool, true when the request must not go through the proxy
+A domain matches its sub domains, with or without a leading dot
+**
+*/
+func bypassProxy(host string, noProxy []string) bool {
+
+	hostname := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		hostname = h
+	}
+	hostname = strings.ToLower(hostname)
+	ip := net.ParseIP(hostname)
+
+	for _, entry := range noProxy {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if len(entry) == 0 {
+			continue
+		}
+		if entry == "*" {
+			return true
+		}
+
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+
+		// an entry with a port only matches that port
+		if entryHost, entryPort, err := net.SplitHostPort(entry); err == nil {
+			_, port, _ := net.SplitHostPort(host)
+			if port != entryPort {
+				continue
+			}
+			entry = entryHost
+		}
+
+		domain := strings.TrimPrefix(entry, ".")
+		if hostname == domain || strings.HasSuffix(hostname, "."+domain) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBypassProxyFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	noProxy := []string{"internal.example.com", ".corp.local", "10.0.0.0/8", "jira.example.com:8443", " "}
+
+	assert.True(bypassProxy("internal.example.com", noProxy))
+	assert.True(bypassProxy("api.internal.example.com:443", noProxy))
+	assert.True(bypassProxy("snyk.corp.local", noProxy))
+	assert.True(bypassProxy("10.1.2.3:8080", noProxy))
+	assert.True(bypassProxy("jira.example.com:8443", noProxy))
+	assert.False(bypassProxy("jira.example.com:443", noProxy))
+	assert.False(bypassProxy("api.snyk.io", noProxy))
+	assert.False(bypassProxy("notinternal.example.com", noProxy))
+	assert.True(bypassProxy("api.snyk.io", []string{"*"}))
+}
+
+func TestNewTransportFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	transport, err := newTransport(optionalFlags{minTLSVersion: "1.3"})
+	assert.Nil(err)
+	assert.Equal(uint16(tls.VersionTLS13), transport.TLSClientConfig.MinVersion)
+
+	_, err = newTransport(optionalFlags{minTLSVersion: "1.4"})
+	assert.NotNil(err)
+
+	_, err = newTransport(optionalFlags{proxy: "::not a url"})
+	assert.NotNil(err)
+
+	_, err = newTransport(optionalFlags{clientCert: "./cert.pem"})
+	assert.NotNil(err)
+
+	_, err = newTransport(optionalFlags{caBundle: "./does-not-exist.pem"})
+	assert.NotNil(err)
+}
+
+func TestNewTransportProxyFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	// the proxy receives the absolute url of the target
+	proxied := ""
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		w.Write([]byte(`{}`))
+	}))
+	defer proxy.Close()
+
+	transport, err := newTransport(optionalFlags{proxy: proxy.URL, noProxy: "direct.example.com"})
+	assert.Nil(err)
+
+	client := &http.Client{Transport: transport}
+	response, err := client.Get("http://snyk.example.com/v1/org/123")
+	assert.Nil(err)
+	response.Body.Close()
+	assert.Equal("http://snyk.example.com/v1/org/123", proxied)
+
+	request, _ := http.NewRequest("GET", "http://direct.example.com/", nil)
+	proxyURL, _ := transport.Proxy(request)
+	assert.Nil(proxyURL)
+}
+
+func TestNewTransportCABundleFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	dir, _ := ioutil.TempDir("", "ca-bundle")
+	defer os.RemoveAll(dir)
+
+	bundle := filepath.Join(dir, "ca.pem")
+	ioutil.WriteFile(bundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600)
+
+	// unknown CA without the bundle
+	transport, _ := newTransport(optionalFlags{})
+	_, err := (&http.Client{Transport: transport}).Get(server.URL)
+	assert.NotNil(err)
+
+	transport, err = newTransport(optionalFlags{caBundle: bundle})
+	assert.Nil(err)
+	response, err := (&http.Client{Transport: transport}).Get(server.URL)
+	assert.Nil(err)
+	response.Body.Close()
+}
@@ -5,9 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"os"
 	"reflect"
+	"strconv"
 	"strings"
 	"time"
 
@@ -29,40 +29,6 @@ func (m *debug) getDebug() bool {
 	return m.PrintDebug
 }
 
-/*
-**
-Function Debug
-check if flag is set
-print debug
-**
-*/
-func (m *debug) Debug(args ...interface{}) {
-	if m.PrintDebug {
-		m.Print(args...)
-	}
-}
-
-func (m *debug) Print(args ...interface{}) {
-	log.Print(args...)
-}
-
-/*
-**
-Function Debugf
-check if flag is set
-print debug with formatting directive
-**
-*/
-func (m *debug) Debugf(format string, args ...interface{}) {
-	if m.PrintDebug {
-		m.Printf(format, args...)
-	}
-}
-
-func (m *debug) Printf(format string, args ...interface{}) {
-	log.Printf(format, args...)
-}
-
 /*
 **
 Function setOption
@@ -76,6 +42,21 @@ func (Mf *MandatoryFlags) setMandatoryFlags(apiTokenPtr *string, v viper.Viper)
 	Mf.apiToken = *apiTokenPtr
 	Mf.jiraProjectID = v.GetString("jira.jiraProjectID")
 	Mf.jiraProjectKey = v.GetString("jira.jiraProjectKey")
+	Mf.oauthClientID = v.GetString("snyk.oauthClientID")
+	Mf.oauthClientSecret = v.GetString("snyk.oauthClientSecret")
+	Mf.oauthTokenURL = v.GetString("snyk.oauthTokenURL")
+	Mf.tracker = v.GetString("snyk.tracker")
+	Mf.githubRepo = v.GetString("snyk.githubRepo")
+	Mf.githubToken = v.GetString("snyk.githubToken")
+	Mf.githubAPI = v.GetString("snyk.githubAPI")
+
+	// keep the secret out of the command line and the config file
+	if len(Mf.oauthClientSecret) == 0 {
+		Mf.oauthClientSecret = os.Getenv("SNYK_OAUTH_CLIENT_SECRET")
+	}
+	if len(Mf.githubToken) == 0 {
+		Mf.githubToken = os.Getenv("GITHUB_TOKEN")
+	}
 
 	// Checking flag exist
 	// pflag required function does not work with viper
@@ -109,6 +90,42 @@ func (Of *optionalFlags) setOptionalFlags(debugPtr bool, dryRunPtr bool, v viper
 	Of.cveInTitle = v.GetBool("jira.cveInTitle")
 	Of.ifUpgradeAvailableOnly = v.GetBool("snyk.ifUpgradeAvailableOnly")
 	Of.ifAutoFixableOnly = v.GetBool("snyk.ifAutoFixableOnly")
+	Of.skipInactiveProjects = v.GetBool("snyk.skipInactiveProjects")
+	Of.maxProjectAge = v.GetString("snyk.maxProjectAge")
+	Of.incremental = v.GetBool("snyk.incremental")
+	Of.fullSyncEvery = v.GetString("snyk.fullSyncEvery")
+	Of.introducedSince = v.GetString("snyk.introducedSince")
+	Of.concurrency = v.GetInt("snyk.concurrency")
+	Of.issueConcurrency = v.GetInt("snyk.issueConcurrency")
+	Of.requestsPerMinute = v.GetInt("snyk.requestsPerMinute")
+	Of.cacheDir = v.GetString("snyk.cacheDir")
+	Of.cacheTTL = v.GetString("snyk.cacheTTL")
+	Of.projectCacheTTL = v.GetString("snyk.projectCacheTTL")
+	Of.proxy = v.GetString("snyk.proxy")
+	Of.noProxy = v.GetString("snyk.noProxy")
+	Of.caBundle = v.GetString("snyk.caBundle")
+	Of.clientCert = v.GetString("snyk.clientCert")
+	Of.clientKey = v.GetString("snyk.clientKey")
+	Of.minTLSVersion = v.GetString("snyk.minTLSVersion")
+	Of.logFormat = v.GetString("snyk.logFormat")
+	Of.logLevel = v.GetString("snyk.logLevel")
+	Of.reportFile = v.GetString("snyk.reportFile")
+	Of.reportFormat = v.GetString("snyk.reportFormat")
+	Of.jiraURL = v.GetString("jira.jiraURL")
+	Of.outputDir = v.GetString("snyk.outputDir")
+	Of.failOn = v.GetString("snyk.failOn")
+	Of.metricsFile = v.GetString("snyk.metricsFile")
+	Of.pushgatewayURL = v.GetString("snyk.pushgatewayURL")
+	Of.notifySlackWebhook = v.GetString("snyk.notifySlackWebhook")
+	Of.notifyTeamsWebhook = v.GetString("snyk.notifyTeamsWebhook")
+	Of.notifyWebhook = v.GetString("snyk.notifyWebhook")
+	Of.notifyTemplate = v.GetString("snyk.notifyTemplate")
+	Of.notifyMinSeverity = v.GetString("snyk.notifyMinSeverity")
+	Of.eventsFile = v.GetString("snyk.eventsFile")
+	Of.eventsURL = v.GetString("snyk.eventsURL")
+	Of.planFile = v.GetString("snyk.planFile")
+	Of.stateFile = v.GetString("snyk.stateFile")
+	Of.resume = v.GetBool("snyk.resume")
 }
 
 /*
@@ -149,9 +166,16 @@ func (opt *flags) setOption(args []string) {
 	fs.String("projectID", "", "Optional. Your Project ID. Will sync all projects Of your organization if not provided")
 	fs.String("api", "https://api.snyk.io", "Optional. Your API endpoint for onprem deployments (https://yourdeploymenthostname/api)")
 	apiTokenPtr = fs.String("token", "", "Your API token")
+	fs.String("oauthClientID", "", "Optional. OAuth client ID of a Snyk service account, used instead of the token")
+	fs.String("oauthClientSecret", "", "Optional. OAuth client secret, can also be set with the SNYK_OAUTH_CLIENT_SECRET env var")
+	fs.String("oauthTokenURL", "", "Optional. OAuth token endpoint. Defaults to <api>/oauth2/token")
 	fs.String("jiraProjectID", "", "Your JIRA projectID (jiraProjectID or jiraProjectKey is required)")
 	fs.String("jiraProjectKey", "", "Your JIRA projectKey (jiraProjectID or jiraProjectKey is required)")
 	fs.String("jiraTicketType", "Bug", "Optional. Chosen JIRA ticket type")
+	fs.String("tracker", "", "Optional. Tracker the tickets are created in (jira|github), jira when not set. The routes of the config file can select another one per project")
+	fs.String("githubRepo", "", "Optional. GitHub repository (owner/repo) of the issues, required with the github tracker")
+	fs.String("githubToken", "", "Optional. GitHub token allowed to read and write the issues, can also be set with the GITHUB_TOKEN env var")
+	fs.String("githubAPI", "", "Optional. GitHub REST API URL for GitHub Enterprise Server (https://yourhost/api/v3), "+GitHubAPI+" when not set")
 	fs.String("projectCriticality", "", "Optional. Include only projects whose criticality attribute contains one or more of the specified values.")
 	fs.String("projectEnvironment", "", "Optional. Include only projects whose environment attribute contains one or more of the specified values.")
 	fs.String("projectLifecycle", "", "Optional. Include only projects whose lifecycle attribute contains one or more of the specified values.")
@@ -169,11 +193,49 @@ func (opt *flags) setOption(args []string) {
 	fs.Bool("cveInTitle", false, "Optional. Boolean. Adds the CVEs to the jira ticket title")
 	fs.Bool("ifUpgradeAvailableOnly", false, "Optional. Boolean. Opens tickets only for upgradable issues")
 	fs.Bool("ifAutoFixableOnly", false, "Optional. Boolean. Opens tickets for issues that are fixable (no effect when using ifUpgradeAvailableOnly)")
+	fs.Bool("skipInactiveProjects", false, "Optional. Boolean. Skip projects that are deactivated in Snyk")
+	fs.String("maxProjectAge", "", "Optional. Skip projects not tested within this duration (e.g. 90d, 2w, 720h)")
+	fs.Bool("incremental", false, "Optional. Boolean. Only process the projects tested since the last successful run")
+	fs.String("fullSyncEvery", "", "Optional. With incremental, process every project when the last full sync is older than this duration (e.g. 7d)")
+	fs.String("introducedSince", "", "Optional. Only open tickets for issues introduced after this date (YYYY-MM-DD), duration (e.g. 30d) or lastRun")
+	fs.Int("concurrency", 1, "Optional. Number of projects processed in parallel")
+	fs.Int("issueConcurrency", 1, "Optional. Number of issue paths or code issue details fetched in parallel for each project")
+	fs.Int("requestsPerMinute", 1500, "Optional. Maximum number of requests sent to the Snyk API per minute, shared by all the workers. 0 disables the limit")
+	fs.String("cacheDir", "", "Optional. Directory used to cache issue paths and code issue details between runs")
+	fs.String("cacheTTL", "24h", "Optional. How long cached paths and code issue details are kept (e.g. 7d, 24h)")
+	fs.String("projectCacheTTL", "1h", "Optional. How long the cached paths and code issue details of a project without a last tested date are kept (e.g. 1h)")
+	fs.String("proxy", "", "Optional. Proxy URL used for all the requests, HTTPS_PROXY and HTTP_PROXY are used when not set")
+	fs.String("noProxy", "", "Optional. Comma separated hosts, domains or CIDRs reached without the proxy")
+	fs.String("caBundle", "", "Optional. PEM file with additional CA certificates to trust")
+	fs.String("clientCert", "", "Optional. PEM client certificate for mutual TLS")
+	fs.String("clientKey", "", "Optional. PEM private key of the client certificate")
+	fs.String("minTLSVersion", "", "Optional. Minimum TLS version (1.0|1.1|1.2|1.3)")
+	fs.String("log-format", "text", "Optional. Format of the log lines (text|json)")
+	fs.String("log-level", "info", "Optional. Least important level logged (error|warn|info|debug|trace), --debug sets at least debug")
+	fs.String("reportFile", "", "Optional. File where the run report listing the outcome of every issue is written")
+	fs.String("reportFormat", "json", "Optional. Format of the run report (json|csv|junit)")
+	fs.String("jiraURL", "", "Optional. Jira base URL (https://yourcompany.atlassian.net), used to link the tickets in the run report")
+	fs.String("output-dir", "", "Optional. Directory of the error journal, the list of tickets, the last run state and relative report files")
+	fs.String("fail-on", failOnAnyError, "Optional. Failures that make the run exit with code 4 (any-error|ticket-failure|project-failure|never)")
+	fs.String("metricsFile", "", "Optional. File where the metrics of the run are written for the Prometheus node exporter textfile collector")
+	fs.String("pushgatewayURL", "", "Optional. Prometheus Pushgateway URL where the metrics of the run are pushed")
+	fs.String("notifySlackWebhook", "", "Optional. Slack incoming webhook URL where the summary of the run is posted")
+	fs.String("notifyTeamsWebhook", "", "Optional. Microsoft Teams incoming webhook URL where the summary of the run is posted")
+	fs.String("notifyWebhook", "", "Optional. URL where the summary of the run is posted as JSON")
+	fs.String("notifyTemplate", "", "Optional. File with the Go template of the notification message")
+	fs.String("notifyMinSeverity", "", "Optional. Notify only when a ticket of at least this severity is created (low|medium|high|critical)")
+	fs.String("eventsFile", "", "Optional. JSON Lines file where a CloudEvent is written for the outcome of every issue")
+	fs.String("eventsURL", "", "Optional. URL where a CloudEvent is posted for the outcome of every issue")
+	fs.Bool("resume", false, "Optional. Boolean. Continue the interrupted run of the org from its checkpoint")
+	fs.String("stateFile", "", "Optional. BoltDB file keeping the issue to ticket mappings between runs, checked with the Snyk ones to avoid duplicates")
+	fs.String("planFile", "", "Optional. File where the plan command writes the tickets to create, plan_<runID>.json in the output directory by default")
+	recordPtr := fs.String("record", "", "Optional. Directory where every request and response is saved, with the secrets redacted")
+	replayPtr := fs.String("replay", "", "Optional. Directory written by --record, the saved responses are used instead of the network")
+	noCachePtr := fs.Bool("no-cache", false, "Optional. Boolean. Do not read or write the cache even if cacheDir is set")
 	configFilePtr = fs.String("configFile", "", "Optional. Config file path. Use config file to set parameters")
 	errParse := fs.Parse(args)
 	if errParse != nil {
-		log.Println("*** ERROR *** Error parsing command line arguments: ", errParse.Error())
-		os.Exit(1)
+		logger.FatalWithCode(exitConfigError, "Error parsing command line arguments", "error", errParse)
 	}
 
 	// Have to set one by one because the name in the config file doesn't correspond to the flag name
@@ -182,6 +244,13 @@ func (opt *flags) setOption(args []string) {
 	v.BindPFlag("snyk.api", fs.Lookup("api"))
 	v.BindPFlag("jira.jiraProjectID", fs.Lookup("jiraProjectID"))
 	v.BindPFlag("jira.jiraProjectKey", fs.Lookup("jiraProjectKey"))
+	v.BindPFlag("snyk.oauthClientID", fs.Lookup("oauthClientID"))
+	v.BindPFlag("snyk.oauthClientSecret", fs.Lookup("oauthClientSecret"))
+	v.BindPFlag("snyk.oauthTokenURL", fs.Lookup("oauthTokenURL"))
+	v.BindPFlag("snyk.tracker", fs.Lookup("tracker"))
+	v.BindPFlag("snyk.githubRepo", fs.Lookup("githubRepo"))
+	v.BindPFlag("snyk.githubToken", fs.Lookup("githubToken"))
+	v.BindPFlag("snyk.githubAPI", fs.Lookup("githubAPI"))
 
 	v.BindPFlag("snyk.projectID", fs.Lookup("projectID"))
 	v.BindPFlag("snyk.projectCriticality", fs.Lookup("projectCriticality"))
@@ -200,6 +269,42 @@ func (opt *flags) setOption(args []string) {
 	v.BindPFlag("snyk.priorityScoreThreshold", fs.Lookup("priorityScoreThreshold"))
 	v.BindPFlag("snyk.ifUpgradeAvailableOnly", fs.Lookup("ifUpgradeAvailableOnly"))
 	v.BindPFlag("snyk.ifAutoFixableOnly", fs.Lookup("ifAutoFixableOnly"))
+	v.BindPFlag("snyk.skipInactiveProjects", fs.Lookup("skipInactiveProjects"))
+	v.BindPFlag("snyk.maxProjectAge", fs.Lookup("maxProjectAge"))
+	v.BindPFlag("snyk.incremental", fs.Lookup("incremental"))
+	v.BindPFlag("snyk.fullSyncEvery", fs.Lookup("fullSyncEvery"))
+	v.BindPFlag("snyk.introducedSince", fs.Lookup("introducedSince"))
+	v.BindPFlag("snyk.concurrency", fs.Lookup("concurrency"))
+	v.BindPFlag("snyk.issueConcurrency", fs.Lookup("issueConcurrency"))
+	v.BindPFlag("snyk.requestsPerMinute", fs.Lookup("requestsPerMinute"))
+	v.BindPFlag("snyk.cacheDir", fs.Lookup("cacheDir"))
+	v.BindPFlag("snyk.cacheTTL", fs.Lookup("cacheTTL"))
+	v.BindPFlag("snyk.projectCacheTTL", fs.Lookup("projectCacheTTL"))
+	v.BindPFlag("snyk.proxy", fs.Lookup("proxy"))
+	v.BindPFlag("snyk.noProxy", fs.Lookup("noProxy"))
+	v.BindPFlag("snyk.caBundle", fs.Lookup("caBundle"))
+	v.BindPFlag("snyk.clientCert", fs.Lookup("clientCert"))
+	v.BindPFlag("snyk.clientKey", fs.Lookup("clientKey"))
+	v.BindPFlag("snyk.minTLSVersion", fs.Lookup("minTLSVersion"))
+	v.BindPFlag("snyk.logFormat", fs.Lookup("log-format"))
+	v.BindPFlag("snyk.logLevel", fs.Lookup("log-level"))
+	v.BindPFlag("snyk.reportFile", fs.Lookup("reportFile"))
+	v.BindPFlag("snyk.reportFormat", fs.Lookup("reportFormat"))
+	v.BindPFlag("jira.jiraURL", fs.Lookup("jiraURL"))
+	v.BindPFlag("snyk.outputDir", fs.Lookup("output-dir"))
+	v.BindPFlag("snyk.failOn", fs.Lookup("fail-on"))
+	v.BindPFlag("snyk.metricsFile", fs.Lookup("metricsFile"))
+	v.BindPFlag("snyk.pushgatewayURL", fs.Lookup("pushgatewayURL"))
+	v.BindPFlag("snyk.notifySlackWebhook", fs.Lookup("notifySlackWebhook"))
+	v.BindPFlag("snyk.notifyTeamsWebhook", fs.Lookup("notifyTeamsWebhook"))
+	v.BindPFlag("snyk.notifyWebhook", fs.Lookup("notifyWebhook"))
+	v.BindPFlag("snyk.notifyTemplate", fs.Lookup("notifyTemplate"))
+	v.BindPFlag("snyk.notifyMinSeverity", fs.Lookup("notifyMinSeverity"))
+	v.BindPFlag("snyk.eventsFile", fs.Lookup("eventsFile"))
+	v.BindPFlag("snyk.eventsURL", fs.Lookup("eventsURL"))
+	v.BindPFlag("snyk.planFile", fs.Lookup("planFile"))
+	v.BindPFlag("snyk.stateFile", fs.Lookup("stateFile"))
+	v.BindPFlag("snyk.resume", fs.Lookup("resume"))
 
 	// Set and parse config file
 	v.SetConfigName("jira") // config file name without extension
@@ -211,22 +316,31 @@ func (opt *flags) setOption(args []string) {
 		v.AddConfigPath(".")
 	}
 
-	configFile, configFileLocation := ReadFile(*configFilePtr, true)
+	configFile, configFileLocation := ReadFile(*configFilePtr)
 
 	if err := v.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
-			fmt.Println("*** WARN *** Config file is not found or maybe empty at location:", configFileLocation)
+			logger.Warn("Config file is not found or maybe empty", "configFile", configFileLocation)
 		} else {
-			fmt.Println("*** ERROR *** ", err)
+			logger.Error("Could not read the config file", "configFile", configFileLocation, "error", err)
 		}
 	}
 
 	customMandatoryJiraFields := CheckConfigFileFormat(configFile)
 	opt.customMandatoryJiraFields = customMandatoryJiraFields
 
+	routes, err := readTrackerRoutes(configFile)
+	if err != nil {
+		logger.FatalWithCode(exitConfigError, "Please check the routes section of the config file", "error", err)
+	}
+	opt.routes = routes
+
 	// Setting the flags structure
 	opt.mandatoryFlags.setMandatoryFlags(apiTokenPtr, *v)
 	opt.optionalFlags.setOptionalFlags(*debugPtr, *dryRunPtr, *v)
+	opt.optionalFlags.noCache = *noCachePtr
+	opt.optionalFlags.record = *recordPtr
+	opt.optionalFlags.replay = *replayPtr
 
 	// check the flags rules
 	opt.checkFlags()
@@ -239,10 +353,21 @@ exit if the mandatory flags are missing
 **
 */
 func (flags *MandatoryFlags) checkMandatoryAreSet() {
-	if len(flags.orgID) == 0 || len(flags.apiToken) == 0 || (len(flags.jiraProjectID) == 0 && len(flags.jiraProjectKey) == 0) {
-		log.Println("*** ERROR *** Missing required flag(s). Please ensure orgID, token, jiraProjectID or jiraProjectKey are set.")
-		os.Exit(1)
+	if len(flags.orgID) == 0 || (len(flags.apiToken) == 0 && len(flags.oauthClientID) == 0) || !flags.hasTicketTarget() {
+		logger.FatalWithCode(exitConfigError, "Missing required flag(s). Please ensure orgID, token (or oauthClientID), jiraProjectID or jiraProjectKey (githubRepo and githubToken with the github tracker) are set.")
+	}
+
+	if len(flags.oauthClientID) > 0 && len(flags.oauthClientSecret) == 0 {
+		logger.FatalWithCode(exitConfigError, "oauthClientID is set without oauthClientSecret. Please set the secret with --oauthClientSecret or the SNYK_OAUTH_CLIENT_SECRET env var.")
+	}
+}
+
+// hasTicketTarget is true when the tickets have somewhere to go, a Jira project or a GitHub repository
+func (flags *MandatoryFlags) hasTicketTarget() bool {
+	if flags.tracker == TrackerGitHub {
+		return len(flags.githubRepo) > 0 && len(flags.githubToken) > 0
 	}
+	return len(flags.jiraProjectID) > 0 || len(flags.jiraProjectKey) > 0
 }
 
 /*
@@ -251,168 +376,394 @@ Function checkFlags
 check flags rules
 To work properly with jira these needs to be respected:
   - set only jiraProjectID or jiraProjectKey, not both
+  - tracker must be jira or github and every route needs projects and a target
   - priorityScoreThreshold must be between 0 and 1000
+  - maxProjectAge and fullSyncEvery must be valid durations
+  - introducedSince must be a date, a duration or lastRun
+  - concurrency and issueConcurrency must be at least 1
+  - requestsPerMinute can't be negative
+  - cacheTTL and projectCacheTTL must be valid durations
+  - record and replay can't be used together
+  - logFormat must be text or json and logLevel a known level
+  - reportFormat must be json, csv or junit
+  - failOn must be any-error, ticket-failure, project-failure or never
+  - severity must be a severity and notifyMinSeverity empty or a severity
 
 **
 */
 func (flags *flags) checkFlags() {
 	if flags.mandatoryFlags.jiraProjectID != "" && flags.mandatoryFlags.jiraProjectKey != "" {
-		log.Fatalf("*** ERROR *** You passed both jiraProjectID and jiraProjectKey in parameters\n Please, Use jiraProjectID OR jiraProjectKey, not both")
+		logger.FatalWithCode(exitConfigError, "You passed both jiraProjectID and jiraProjectKey in parameters. Please, Use jiraProjectID OR jiraProjectKey, not both")
+	}
+
+	if flags.mandatoryFlags.tracker != "" && !isTracker(flags.mandatoryFlags.tracker) {
+		logger.FatalWithCode(exitConfigError, "Not a valid tracker. Must be one of jira, github", "tracker", flags.mandatoryFlags.tracker)
+	}
+
+	for index, route := range flags.routes {
+		if err := route.check(flags.mandatoryFlags); err != nil {
+			logger.FatalWithCode(exitConfigError, "Not a valid route in the config file", "route", index+1, "projects", route.Projects, "error", err)
+		}
 	}
 
 	if flags.optionalFlags.priorityScoreThreshold < 0 || flags.optionalFlags.priorityScoreThreshold > 1000 {
-		log.Fatalf("*** ERROR *** %d is not a valid score. Must be between 0-1000.", flags.optionalFlags.priorityScoreThreshold)
+		logger.FatalWithCode(exitConfigError, "Not a valid score. Must be between 0-1000.", "priorityScoreThreshold", flags.optionalFlags.priorityScoreThreshold)
+	}
+
+	if flags.optionalFlags.maxProjectAge != "" {
+		if _, err := parseDuration(flags.optionalFlags.maxProjectAge); err != nil {
+			logger.FatalWithCode(exitConfigError, "Not a valid maxProjectAge", "maxProjectAge", flags.optionalFlags.maxProjectAge, "error", err)
+		}
+	}
+
+	if flags.optionalFlags.fullSyncEvery != "" {
+		if _, err := parseDuration(flags.optionalFlags.fullSyncEvery); err != nil {
+			logger.FatalWithCode(exitConfigError, "Not a valid fullSyncEvery", "fullSyncEvery", flags.optionalFlags.fullSyncEvery, "error", err)
+		}
+	}
+
+	if flags.optionalFlags.introducedSince != "" && flags.optionalFlags.introducedSince != IntroducedSinceLastRun {
+		if _, err := parseIntroducedSince(flags.optionalFlags.introducedSince, time.Now()); err != nil {
+			logger.FatalWithCode(exitConfigError, "Not a valid introducedSince. Use a date (YYYY-MM-DD), a duration (e.g. 30d) or "+IntroducedSinceLastRun, "introducedSince", flags.optionalFlags.introducedSince)
+		}
+	}
+
+	if flags.optionalFlags.concurrency < 1 || flags.optionalFlags.issueConcurrency < 1 {
+		logger.FatalWithCode(exitConfigError, "concurrency and issueConcurrency must be at least 1", "concurrency", flags.optionalFlags.concurrency, "issueConcurrency", flags.optionalFlags.issueConcurrency)
+	}
+
+	if flags.optionalFlags.requestsPerMinute < 0 {
+		logger.FatalWithCode(exitConfigError, "Not a valid requestsPerMinute. Must be 0 or more.", "requestsPerMinute", flags.optionalFlags.requestsPerMinute)
+	}
+
+	if flags.optionalFlags.record != "" && flags.optionalFlags.replay != "" {
+		logger.FatalWithCode(exitConfigError, "You passed both record and replay in parameters. Please, Use record OR replay, not both")
+	}
+
+	if _, err := parseDuration(flags.optionalFlags.cacheTTL); err != nil {
+		logger.FatalWithCode(exitConfigError, "Not a valid cacheTTL", "cacheTTL", flags.optionalFlags.cacheTTL, "error", err)
+	}
+
+	if _, err := parseDuration(flags.optionalFlags.projectCacheTTL); err != nil {
+		logger.FatalWithCode(exitConfigError, "Not a valid projectCacheTTL", "projectCacheTTL", flags.optionalFlags.projectCacheTTL, "error", err)
+	}
+
+	if err := checkLogOptions(flags.optionalFlags.logFormat, flags.optionalFlags.logLevel); err != nil {
+		logger.FatalWithCode(exitConfigError, "Not valid logging options", "logFormat", flags.optionalFlags.logFormat, "logLevel", flags.optionalFlags.logLevel, "error", err)
+	}
+
+	if !isReportFormat(flags.optionalFlags.reportFormat) {
+		logger.FatalWithCode(exitConfigError, "Not a valid reportFormat. Must be one of json, csv, junit", "reportFormat", flags.optionalFlags.reportFormat)
+	}
+
+	if !isFailOn(flags.optionalFlags.failOn) {
+		logger.FatalWithCode(exitConfigError, "Not a valid failOn. Must be one of any-error, ticket-failure, project-failure, never", "failOn", flags.optionalFlags.failOn)
+	}
+
+	if _, found := severityRank[flags.optionalFlags.severity]; !found {
+		logger.FatalWithCode(exitConfigError, "Not a valid severity. Must be one of low, medium, high, critical", "severity", flags.optionalFlags.severity)
+	}
+
+	if _, found := severityRank[flags.optionalFlags.notifyMinSeverity]; flags.optionalFlags.notifyMinSeverity != "" && !found {
+		logger.FatalWithCode(exitConfigError, "Not a valid notifyMinSeverity. Must be one of low, medium, high, critical", "notifyMinSeverity", flags.optionalFlags.notifyMinSeverity)
 	}
 }
 
 /*
 **
-function CreateLogFile
-return filename: string
-argument: debug
-Check if the file exist if not create it
+function parseIntroducedSince
+input value string, absolute date (YYYY-MM-DD or RFC3339) or duration relative to now
+input now time.Time, reference time for relative durations
+return time.Time, issues introduced before this date are ignored
 **
 */
+func parseIntroducedSince(value string, now time.Time) (time.Time, error) {
 
-func CreateLogFile(customDebug debug, fileType string) string {
-
-	// Get date
-	date := getDate()
+	if date, err := time.Parse(time.RFC3339, value); err == nil {
+		return date, nil
+	}
 
-	// Set filename
-	filename := fileType + date + ".json"
+	if date, err := time.Parse("2006-01-02", value); err == nil {
+		return date, nil
+	}
 
-	// If the file doesn't exist, create it, or append to the file
-	_, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	duration, err := parseDuration(value)
 	if err != nil {
-		// Do not fail the tool if file cannot be created print a warning instead
-		customDebug.Debug("*** ERROR *** Could not create log file")
-		customDebug.Debug(err.Error())
+		return time.Time{}, err
 	}
 
-	return filename
+	return now.Add(-duration), nil
 }
 
 /*
 **
-function getDate
-return date: string
-argument: none
-return a string containing date and time
+function resolveIntroducedSince
+input Of *optionalFlags, introducedSince is read and introducedSinceDate is set
+input orgID string, used to find the last successful run of this org
+input now time.Time, start of the current run
+Turn the introducedSince option in the date used to filter issues.
+When lastRun is used and no run has been recorded yet, the current run start is used
+so the existing backlog is not ticketed.
 **
 */
-func getDate() string {
+func (Of *optionalFlags) resolveIntroducedSince(orgID string, now time.Time) {
 
-	now := time.Now().Round(0)
-	y := fmt.Sprint(now.Year()) + "_"
-	m := fmt.Sprint(int(now.Month())) + "_"
-	d := fmt.Sprint(now.Day()) + "_"
-	h := fmt.Sprint(now.Hour()) + "_"
-	min := fmt.Sprint(now.Minute()) + "_"
-	s := fmt.Sprint(now.Second())
+	if Of.introducedSince == "" {
+		return
+	}
 
-	return y + m + d + h + min + s
+	if Of.introducedSince != IntroducedSinceLastRun {
+		Of.introducedSinceDate, _ = parseIntroducedSince(Of.introducedSince, now)
+		return
+	}
+
+	lastRun, err := readLastSuccessfulRun(orgID)
+	if err != nil {
+		logger.Warn("No previous successful run found, only issues introduced from now on will be ticketed", "org", orgID)
+		Of.introducedSinceDate = now
+		return
+	}
+	Of.introducedSinceDate = lastRun
 }
 
 /*
 **
-function getDate
-return date: string
-argument: none
-return a string containing date and time
+function resolveIncremental
+input Of *optionalFlags, incremental and fullSyncEvery are read and incrementalSince is set
+input orgID string, used to find the last successful run of this org
+input now time.Time, start of the current run
+With incremental, only the projects tested since the last successful run are processed.
+A full sync is run when there is no previous run, when a single project is asked
+or when the last full sync is older than fullSyncEvery.
 **
 */
-func getDateDayOnly() string {
+func (Of *optionalFlags) resolveIncremental(orgID string, now time.Time) {
 
-	now := time.Now().Round(0)
-	y := fmt.Sprint(now.Year()) + "_"
-	m := fmt.Sprint(int(now.Month())) + "_"
-	d := fmt.Sprint(now.Day()) + "_"
+	Of.incrementalSince = time.Time{}
+	if !Of.incremental || Of.projectID != "" {
+		return
+	}
 
-	return y + m + d
+	state, err := readLastRunState(orgID)
+	if err != nil {
+		logger.Info("No previous successful run found, every project is processed", "org", orgID)
+		return
+	}
+
+	if Of.fullSyncEvery != "" {
+		fullSyncEvery, _ := parseDuration(Of.fullSyncEvery)
+		if state.LastFullSync.IsZero() || now.Sub(state.LastFullSync) >= fullSyncEvery {
+			logger.Info("Full sync is due, every project is processed", "org", orgID, "lastFullSync", state.LastFullSync, "fullSyncEvery", Of.fullSyncEvery)
+			return
+		}
+	}
+
+	logger.Info("Incremental sync, only the projects tested since the last successful run are processed", "org", orgID, "lastSuccessfulRun", state.LastSuccessfulRun)
+	Of.incrementalSince = state.LastSuccessfulRun
+}
+
+// lastRunPath is the state file of the org, it is shared by the runs so it has no run ID
+func lastRunPath(orgID string) string {
+	return defaultRunOutput.resolve(LastRunFilePrefix + orgID + ".json")
 }
 
 /*
 **
-function writeLogFile
-return date: string
-input: map[string]interface{} logFile: details of the ticket to be written in the file
-input: string filename: name of the file created in the main function
-input: customDebug debug
-Write the logFile in the file. Details are append to the file per project ID
+function readLastRunState
+input orgID string
+return LastRunState, the last successful run and the last full sync of this org
 **
 */
-func writeLogFile(logFile map[string]map[string]interface{}, filename string, customDebug debug) {
+func readLastRunState(orgID string) (LastRunState, error) {
+
+	var state LastRunState
 
-	// If the file doesn't exist => exit, append to the file otherwise
-	f, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY, 0644)
+	file, err := ioutil.ReadFile(lastRunPath(orgID))
 	if err != nil {
-		customDebug.Debug("*** ERROR *** Could not open file ", filename)
-		return
+		return state, err
 	}
 
-	file, _ := json.MarshalIndent(logFile, "", "")
-
-	if _, err := f.Write(file); err != nil {
-		customDebug.Debug("*** ERROR *** Could write in file")
-		return
+	err = json.Unmarshal(file, &state)
+	if err != nil {
+		return state, err
 	}
 
-	if err := f.Close(); err != nil {
-		customDebug.Debug("*** ERROR ***  Could not close file")
-		return
+	if state.LastSuccessfulRun.IsZero() {
+		return state, errors.New("Failure, last successful run is not set")
 	}
 
-	return
+	return state, nil
 }
 
-func Sprintf2(format string, a ...interface{}) string {
-	a = append(a, "\r")
-	return fmt.Sprintf(format, a...)
+/*
+**
+function readLastSuccessfulRun
+input orgID string
+return time.Time, start time of the last successful run for this org
+**
+*/
+func readLastSuccessfulRun(orgID string) (time.Time, error) {
+
+	state, err := readLastRunState(orgID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return state.LastSuccessfulRun, nil
 }
 
-func writeErrorFile(function string, errorText string, customDebug debug) {
+/*
+**
+function writeLastSuccessfulRun
+input orgID string
+input runStart time.Time, start time of the run that just completed
+input fullSync bool, every project was processed, otherwise the last full sync is kept
+input customDebug debug
+**
+*/
+func writeLastSuccessfulRun(orgID string, runStart time.Time, fullSync bool, customDebug debug) {
 
-	errorsInterface := make(map[string]interface{})
+	state := LastRunState{
+		OrgID:             orgID,
+		LastSuccessfulRun: runStart.UTC(),
+	}
 
-	// Get filePath
-	filename, err := FindFile("ErrorsFile")
-	if err != nil {
-		workingDir, _ := os.Getwd()
-		log.Printf("*** ERROR *** Could not find any ErrorsFiles in the working directory at %s: %v", workingDir, err)
+	if fullSync {
+		state.LastFullSync = runStart.UTC()
+	} else if previous, err := readLastRunState(orgID); err == nil {
+		state.LastFullSync = previous.LastFullSync
 	}
 
-	// Read the file, unMarshallto get a map[]interface{} and append the new error and Marshall to create a json
-	// ReadFile
-	jsonErrofile, _ := ReadFile(filename, false)
-	if jsonErrofile == nil {
-		log.Println("*** ERROR *** No content in ErrorsFile, could not get errors")
+	file, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		customDebug.Error("Could not save the last successful run", "org", orgID, "error", err)
+		return
 	}
 
-	// unMarshall
-	err = json.Unmarshal(jsonErrofile, &errorsInterface)
+	err = writeFileAtomic(lastRunPath(orgID), file, 0644)
 	if err != nil {
-		log.Println("*** ERROR *** Please check the format config file", err)
+		customDebug.Error("Could not save the last successful run", "org", orgID, "error", err)
 	}
+}
 
-	// Add the new error
-	if errorsInterface[function] != nil {
-		errorsInterface[function] = Sprintf2(errorText, errorsInterface[function])
-	} else {
-		errorsInterface[function] = errorText
+/*
+**
+function parseDuration
+input string value, duration such as 90d, 2w or any value understood by time.ParseDuration
+return time.Duration
+Go durations stop at hours, so days (d) and weeks (w) suffixes are added on top
+**
+*/
+func parseDuration(value string) (time.Duration, error) {
+
+	value = strings.TrimSpace(value)
+	if len(value) == 0 {
+		return 0, errors.New("empty duration")
 	}
 
-	NewErrorsList, err := json.Marshal(errorsInterface)
+	unit := time.Duration(0)
+	switch value[len(value)-1] {
+	case 'd':
+		unit = 24 * time.Hour
+	case 'w':
+		unit = 7 * 24 * time.Hour
+	}
+
+	if unit == 0 {
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return 0, err
+		}
+		if duration < 0 {
+			return 0, errors.New("duration must be positive")
+		}
+		return duration, nil
+	}
+
+	number, err := strconv.Atoi(value[:len(value)-1])
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("invalid duration %s, expected format like 90d, 2w or 720h", value)
+	}
+
+	return time.Duration(number) * unit, nil
+}
+
+/*
+**
+function CreateLogFile
+return filename: string
+argument: debug
+Create the file of the current run in the output directory, the run ID makes the name unique
+**
+*/
+
+func CreateLogFile(customDebug debug, fileType string) string {
+
+	extension := ".json"
+	if fileType == ErrorsFilePrefix {
+		extension = ".jsonl"
+	}
+
+	filename := defaultRunOutput.path(fileType, extension)
+
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
-		log.Println("*** ERROR *** Please check the format config file, could not extract 'jira' config", err)
+		// Do not fail the tool if file cannot be created print a warning instead
+		customDebug.Warn("Could not create log file", "file", filename, "error", err)
+		return filename
 	}
+	f.Close()
+
+	return filename
+}
+
+/*
+**
+function writeLogFile
+input: map[string]interface{} logFile: details of the ticket to be written in the file
+input: string filename: name of the file created in the main function
+input: customDebug debug
+Write the logFile in the file, the file is replaced atomically
+**
+*/
+func writeLogFile(logFile map[string]map[string]interface{}, filename string, customDebug debug) {
 
-	err = ioutil.WriteFile(filename, NewErrorsList, 0644)
+	file, err := json.MarshalIndent(logFile, "", "")
 	if err != nil {
-		log.Fatal(err)
+		customDebug.Error("Could not marshal the list of tickets", "file", filename, "error", err)
+		return
 	}
 
-	return
+	if err := writeFileAtomic(filename, file, 0644); err != nil {
+		customDebug.Error("Could not write in file", "file", filename, "error", err)
+	}
+}
+
+/*
+**
+function writeErrorFile
+input function string, function where the error happened
+input errorText string, message, a leading *** ERROR ***, *** WARN *** or *** INFO *** sets the severity
+input customDebug debug, the org, project and issue fields of the logger are journaled with the error
+Append the error to the JSON Lines journal of the run, a journal that cannot be written does not stop the run
+**
+*/
+func writeErrorFile(function string, errorText string, customDebug debug) {
+	writeJournalEntry(newJournalEntry(function, errorText, customDebug), customDebug)
+}
+
+/*
+**
+function writeJournalEntry
+input entry journalEntry
+input customDebug debug
+**
+*/
+func writeJournalEntry(entry journalEntry, customDebug debug) {
+
+	if err := defaultRunOutput.appendJournal(entry); err != nil {
+		customDebug.Error("Could not write the error journal", "file", defaultRunOutput.journalPath(), "function", entry.Function, "message", entry.Message, "error", err)
+	}
 }
 
 /*
@@ -443,19 +794,19 @@ func findCustomJiraMandatoryFlags(yamlFile []byte) map[string]interface{} {
 
 	err := yaml.Unmarshal(yamlFile, &config)
 	if err != nil {
-		log.Println("*** ERROR *** Please check the format config file", err)
+		logger.Error("Please check the format config file", "error", err)
 	}
 
 	// extract jira fields
 	jiraValues := config["jira"]
 	marshalledJiraValues, err := yaml.Marshal(jiraValues)
 	if err != nil {
-		log.Println("*** ERROR *** Please check the format config file, could not extract 'jira' config", err)
+		logger.Error("Please check the format config file, could not extract the jira config", "error", err)
 	}
 
 	err = yaml.Unmarshal(marshalledJiraValues, &unMarshalledJiraValues)
 	if err != nil {
-		log.Println("*** ERROR *** Please check the format config file, could not extract 'jira' config", err)
+		logger.Error("Please check the format config file, could not extract the jira config", "error", err)
 	}
 
 	// extract mandatory fields
@@ -463,12 +814,12 @@ func findCustomJiraMandatoryFlags(yamlFile []byte) map[string]interface{} {
 
 	marshalCustomJiraMandatoryField, err := yaml.Marshal(customJiraMandatoryField_)
 	if err != nil {
-		log.Println("*** ERROR *** Please check the format config file, could not extract 'customMandatoryFields' config", err)
+		logger.Error("Please check the format config file, could not extract the customMandatoryFields config", "error", err)
 	}
 
 	err = yaml.Unmarshal(marshalCustomJiraMandatoryField, &yamlCustomJiraMandatoryField)
 	if err != nil {
-		log.Println("*** ERROR *** Please check the format config file, could not extract 'customMandatoryFields' config", err)
+		logger.Error("Please check the format config file, could not extract the customMandatoryFields config", "error", err)
 	}
 
 	// converting the type, the yaml type is not compatible with the json one
@@ -515,20 +866,20 @@ func CheckConfigFileFormat(yamlFile []byte) map[string]interface{} {
 
 	err := yaml.Unmarshal(yamlFile, &config)
 	if err != nil {
-		log.Println("*** ERROR *** Please check the format config file", err)
+		logger.Error("Please check the format config file", "error", err)
 	}
 
 	// extract and check snyk fields
 	snykValues := config["snyk"]
 	if !checkSnykValue(snykValues) {
-		log.Fatal()
+		logger.FatalWithCode(exitConfigError, "Please check the snyk section of the config file")
 	}
 
 	// extract and check jira fields
 	jiraValues := config["jira"]
 	success, customFields := checkJiraValue(jiraValues)
 	if !success {
-		log.Fatal()
+		logger.FatalWithCode(exitConfigError, "Please check the jira section of the config file")
 	}
 
 	return customFields
@@ -542,12 +893,12 @@ func checkSnykValue(snykValues interface{}) bool {
 
 	marshalledSnykValues, err := yaml.Marshal(snykValues)
 	if err != nil {
-		log.Println("*** ERROR *** Please check the format config file, could not extract 'snyk' config", err)
+		logger.Error("Please check the format config file, could not extract the snyk config", "error", err)
 	}
 
 	err = yaml.Unmarshal(marshalledSnykValues, &unMarshalledSnykValues)
 	if err != nil {
-		log.Println("*** ERROR *** Please check the format config file, could not extract 'snyk' config", err)
+		logger.Error("Please check the format config file, could not extract the snyk config", "error", err)
 	}
 
 	unMarshalledSnykValuesJson := convertYamltoJson(unMarshalledSnykValues)
@@ -560,77 +911,107 @@ func checkSnykValue(snykValues interface{}) bool {
 		case "projectID":
 			valueType := reflect.TypeOf(value).String()
 			if valueType != "string" {
-				log.Printf("*** ERROR *** Please check the format config file, %s is of type %s when it should be a string", key, reflect.TypeOf(value).String())
+				logger.Error("Please check the format config file, wrong value type", "key", key, "type", valueType, "expected", "string")
 				return false
 			}
 		case "api":
 			valueType := reflect.TypeOf(value).String()
 			if valueType != "string" {
-				log.Printf("*** ERROR *** Please check the format config file, %s is of type %s when it should be a string", key, reflect.TypeOf(value).String())
+				logger.Error("Please check the format config file, wrong value type", "key", key, "type", valueType, "expected", "string")
 				return false
 			}
 		case "orgID":
 			valueType := reflect.TypeOf(value).String()
 			if valueType != "string" {
-				log.Printf("*** ERROR *** Please check the format config file, %s is of type %s when it should be a string", key, reflect.TypeOf(value).String())
+				logger.Error("Please check the format config file, wrong value type", "key", key, "type", valueType, "expected", "string")
 				return false
 			}
 		case "projectCriticality":
 			valueType := reflect.TypeOf(value).String()
 			if valueType != "string" {
-				log.Printf("*** ERROR *** Please check the format config file, %s is of type %s when it should be a string", key, reflect.TypeOf(value).String())
+				logger.Error("Please check the format config file, wrong value type", "key", key, "type", valueType, "expected", "string")
 				return false
 			}
 		case "projectLifecycle":
 			valueType := reflect.TypeOf(value).String()
 			if valueType != "string" {
-				log.Printf("*** ERROR *** Please check the format config file, %s is of type %s when it should be a string", key, reflect.TypeOf(value).String())
+				logger.Error("Please check the format config file, wrong value type", "key", key, "type", valueType, "expected", "string")
 				return false
 			}
 		case "projectEnvironment":
 			valueType := reflect.TypeOf(value).String()
 			if valueType != "string" {
-				log.Printf("*** ERROR *** Please check the format config file, %s is of type %s when it should be a string", key, reflect.TypeOf(value).String())
+				logger.Error("Please check the format config file, wrong value type", "key", key, "type", valueType, "expected", "string")
 				return false
 			}
 		case "severity":
 			valueType := reflect.TypeOf(value).String()
 			if valueType != "string" {
-				log.Printf("*** ERROR *** Please check the format config file, %s is of type %s when it should be a string", key, reflect.TypeOf(value).String())
+				logger.Error("Please check the format config file, wrong value type", "key", key, "type", valueType, "expected", "string")
 				return false
 			}
 		case "type":
 			valueType := reflect.TypeOf(value).String()
 			if valueType != "string" {
-				log.Printf("*** ERROR *** Please check the format config file, %s is of type %s when it should be a string", key, reflect.TypeOf(value).String())
+				logger.Error("Please check the format config file, wrong value type", "key", key, "type", valueType, "expected", "string")
 				return false
 			}
 		case "maturityFilter":
 			valueType := reflect.TypeOf(value).String()
 			if valueType != "string" {
-				log.Printf("*** ERROR *** Please check the format config file, %s is of type %s when it should be a string", key, reflect.TypeOf(value).String())
+				logger.Error("Please check the format config file, wrong value type", "key", key, "type", valueType, "expected", "string")
 				return false
 			}
 		case "priorityScoreThreshold":
 			valueType := reflect.TypeOf(value).String()
 			if valueType != "int" {
-				log.Printf("*** ERROR *** Please check the format config file, %s is of type %s when it should be an integer", key, reflect.TypeOf(value).String())
+				logger.Error("Please check the format config file, wrong value type", "key", key, "type", valueType, "expected", "integer")
 				return false
 			}
 		case "ifUpgradeAvailableOnly":
 			valueType := reflect.TypeOf(value).String()
 			if valueType != "bool" {
-				log.Printf("*** ERROR *** Please check the format config file, %s is of type %s when it should be a boolean", key, reflect.TypeOf(value).String())
+				logger.Error("Please check the format config file, wrong value type", "key", key, "type", valueType, "expected", "boolean")
 				return false
 			}
 		case "ifAutoFixableOnly":
 			valueType := reflect.TypeOf(value).String()
 			if valueType != "bool" {
-				log.Printf("*** ERROR *** Please check the format config file, %s is of type %s when it should be a boolean", key, reflect.TypeOf(value).String())
+				logger.Error("Please check the format config file, wrong value type", "key", key, "type", valueType, "expected", "boolean")
+				return false
+			}
+		case "skipInactiveProjects", "resume", "incremental":
+			valueType := reflect.TypeOf(value).String()
+			if valueType != "bool" {
+				logger.Error("Please check the format config file, wrong value type", "key", key, "type", valueType, "expected", "boolean")
+				return false
+			}
+		case "maxProjectAge":
+			valueType := reflect.TypeOf(value).String()
+			if valueType != "string" {
+				logger.Error("Please check the format config file, wrong value type", "key", key, "type", valueType, "expected", "string")
+				return false
+			}
+		case "introducedSince":
+			valueType := reflect.TypeOf(value).String()
+			if valueType != "string" {
+				logger.Error("Please check the format config file, wrong value type", "key", key, "type", valueType, "expected", "string")
+				return false
+			}
+		case "oauthClientID", "oauthClientSecret", "oauthTokenURL", "cacheDir", "cacheTTL", "projectCacheTTL", "proxy", "noProxy", "caBundle", "clientCert", "clientKey", "minTLSVersion", "logFormat", "logLevel", "reportFile", "reportFormat", "outputDir", "failOn", "metricsFile", "pushgatewayURL", "notifySlackWebhook", "notifyTeamsWebhook", "notifyWebhook", "notifyTemplate", "notifyMinSeverity", "eventsFile", "eventsURL", "planFile", "stateFile", "fullSyncEvery", "tracker", "githubRepo", "githubToken", "githubAPI":
+			valueType := reflect.TypeOf(value).String()
+			if valueType != "string" {
+				logger.Error("Please check the format config file, wrong value type", "key", key, "type", valueType, "expected", "string")
+				return false
+			}
+		case "concurrency", "issueConcurrency", "requestsPerMinute":
+			valueType := reflect.TypeOf(value).String()
+			if valueType != "int" {
+				logger.Error("Please check the format config file, wrong value type", "key", key, "type", valueType, "expected", "integer")
 				return false
 			}
 		default:
-			log.Printf("*** ERROR *** Please check the format config file, the snyk key %s is not supported by this tool", key)
+			logger.Error("Please check the format config file, the snyk key is not supported by this tool", "key", key)
 			return false
 		}
 	}
@@ -648,12 +1029,12 @@ func checkJiraValue(JiraValues interface{}) (bool, map[string]interface{}) {
 
 	marshalledJiraValues, err := yaml.Marshal(JiraValues)
 	if err != nil {
-		log.Println("*** ERROR *** Please check the format config file, could not extract 'snyk' config", err)
+		logger.Error("Please check the format config file, could not extract the snyk config", "error", err)
 	}
 
 	err = yaml.Unmarshal(marshalledJiraValues, &unMarshalledJiraValues)
 	if err != nil {
-		log.Println("*** ERROR *** Please check the format config file, could not extract 'snyk' config", err)
+		logger.Error("Please check the format config file, could not extract the snyk config", "error", err)
 	}
 
 	unMarshalledJiraValuesJson := convertYamltoJson(unMarshalledJiraValues)
@@ -666,49 +1047,55 @@ func checkJiraValue(JiraValues interface{}) (bool, map[string]interface{}) {
 		case "jiraProjectID":
 			valueType := reflect.TypeOf(value).String()
 			if valueType != "int" {
-				log.Printf("*** ERROR *** Please check the format config file, %s is of type %s when it should be an integer", key, reflect.TypeOf(value).String())
+				logger.Error("Please check the format config file, wrong value type", "key", key, "type", valueType, "expected", "integer")
 				return false, nil
 			}
 		case "jiraProjectKey":
 			valueType := reflect.TypeOf(value).String()
 			if valueType != "string" {
-				log.Printf("*** ERROR *** Please check the format config file, %s is of type %s when it should be a string", key, reflect.TypeOf(value).String())
+				logger.Error("Please check the format config file, wrong value type", "key", key, "type", valueType, "expected", "string")
 				return false, nil
 			}
 		case "jiraTicketType":
 			valueType := reflect.TypeOf(value).String()
 			if valueType != "string" {
-				log.Printf("*** ERROR *** Please check the format config file, %s is of type %s when it should be a string", key, reflect.TypeOf(value).String())
+				logger.Error("Please check the format config file, wrong value type", "key", key, "type", valueType, "expected", "string")
 				return false, nil
 			}
 		case "assigneeId":
 			valueType := reflect.TypeOf(value).String()
 			if valueType != "string" {
-				log.Printf("*** ERROR *** Please check the format config file, %s is of type %s when it should be an string", key, reflect.TypeOf(value).String())
+				logger.Error("Please check the format config file, wrong value type", "key", key, "type", valueType, "expected", "string")
 				return false, nil
 			}
 		case "labels":
 			valueType := reflect.TypeOf(value).String()
 			if valueType != "string" {
-				log.Printf("*** ERROR *** Please check the format config file, %s is of type %s when it should be a string", key, reflect.TypeOf(value).String())
+				logger.Error("Please check the format config file, wrong value type", "key", key, "type", valueType, "expected", "string")
 				return false, nil
 			}
 		case "dueDate":
 			valueType := reflect.TypeOf(value).String()
 			if valueType != "string" {
-				log.Printf("*** ERROR *** Please check the format config file, %s is of type %s when it should be a string", key, reflect.TypeOf(value).String())
+				logger.Error("Please check the format config file, wrong value type", "key", key, "type", valueType, "expected", "string")
 				return false, nil
 			}
 		case "priorityIsSeverity":
 			valueType := reflect.TypeOf(value).String()
 			if valueType != "bool" {
-				log.Printf("*** ERROR *** Please check the format config file, %s is of type %s when it should be a boolean", key, reflect.TypeOf(value).String())
+				logger.Error("Please check the format config file, wrong value type", "key", key, "type", valueType, "expected", "boolean")
 				return false, nil
 			}
 		case "cveInTitle":
 			valueType := reflect.TypeOf(value).String()
 			if valueType != "bool" {
-				log.Printf("*** ERROR *** Please check the format config file, %s is of type %s when it should be a boolean", key, reflect.TypeOf(value).String())
+				logger.Error("Please check the format config file, wrong value type", "key", key, "type", valueType, "expected", "boolean")
+				return false, nil
+			}
+		case "jiraURL":
+			valueType := reflect.TypeOf(value).String()
+			if valueType != "string" {
+				logger.Error("Please check the format config file, wrong value type", "key", key, "type", valueType, "expected", "string")
 				return false, nil
 			}
 		case "customMandatoryFields":
@@ -718,7 +1105,7 @@ func checkJiraValue(JiraValues interface{}) (bool, map[string]interface{}) {
 			customMandatoryJiraFields = customMandatoryJiraFields_
 
 		default:
-			log.Printf("*** ERROR *** Please check the format config file, the jira key %s is not supported by this tool", key)
+			logger.Error("Please check the format config file, the jira key is not supported by this tool", "key", key)
 			return false, nil
 		}
 	}
@@ -733,12 +1120,12 @@ func checkMandatoryField(customJiraMandatoryField_ interface{}, yamlCustomJiraMa
 
 	marshalCustomJiraMandatoryField, err := yaml.Marshal(customJiraMandatoryField_)
 	if err != nil {
-		log.Println("*** ERROR *** Please check the format config file, could not extract 'customMandatoryFields' config", err)
+		logger.Error("Please check the format config file, could not extract the customMandatoryFields config", "error", err)
 	}
 
 	err = yaml.Unmarshal(marshalCustomJiraMandatoryField, &yamlCustomJiraMandatoryField)
 	if err != nil {
-		log.Println("*** ERROR *** Please check the format config file, could not extract 'customMandatoryFields' config", err)
+		logger.Error("Please check the format config file, could not extract the customMandatoryFields config", "error", err)
 	}
 
 	// converting the type, the yaml type is not compatible with the json one
@@ -753,15 +1140,15 @@ func checkMandatoryField(customJiraMandatoryField_ interface{}, yamlCustomJiraMa
 			v, ok := value["value"].(string)
 			if ok {
 				if strings.HasPrefix(v, JiraPrefix) {
-					s, err = supportJiraFormats(v, debug{PrintDebug: false})
+					s, err = supportJiraFormats(v, logger)
 					if err != nil {
-						log.Printf("*** ERROR *** Error while extracting the mandatory Jira fields configuration\n %s", err)
+						logger.Error("Error while extracting the mandatory Jira fields configuration", "field", i, "error", err)
 						return false, nil
 					}
 				}
 			}
 		} else {
-			log.Println(fmt.Sprintf("*** ERROR *** Expected mandatory Jira fields configuration to be in format map[string]interface{}, received type: %T for field %s ", s, i))
+			logger.Error("Expected mandatory Jira fields configuration to be in format map[string]interface{}", "field", i, "type", fmt.Sprintf("%T", s))
 			return false, nil
 		}
 		fields[i] = s
@@ -777,43 +1164,18 @@ return []byte config file
 Try to read the yaml file. If this fails the config file is not valid yaml
 **
 */
-func ReadFile(path string, config bool) ([]byte, string) {
+func ReadFile(path string) ([]byte, string) {
 
 	if len(path) == 0 {
 		path = "."
 	}
 
-	var err error
 	filePath := path + "/jira.yaml"
-	if !config {
-		filePath, err = FindFile("ErrorsFile")
-	}
 
 	file, err := ioutil.ReadFile(filePath)
 	if err != nil {
-		fmt.Printf("*** ERROR *** Could not read file at location: %s. Please ensure the file exists and is formatted correctly.\nERROR: %s\n", filePath, err.Error())
+		logger.Error("Could not read file. Please ensure the file exists and is formatted correctly.", "file", filePath, "error", err)
 	}
 
 	return file, filePath
 }
-
-func FindFile(fileName string) (string, error) {
-
-	// list all file in the directory
-	fileInfo, err := ioutil.ReadDir(".")
-	if err != nil {
-		log.Fatal()
-	}
-
-	// Look for the one starting with listOfTicketCreated or ErrorsFile
-	for _, file := range fileInfo {
-		if !file.IsDir() {
-			if strings.HasPrefix(file.Name(), fileName) {
-				filePath := "./" + file.Name()
-				return filePath, nil
-			}
-		}
-	}
-	errorMessage := fmt.Sprintf("Failure, Could not find File %s", fileName)
-	return "", errors.New(errorMessage)
-}
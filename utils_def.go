@@ -1,8 +1,13 @@
 package main
 
+import "time"
+
 // structure containing the debug flag to check on
+// debug is the logger passed along the calls, see logger.go
 type debug struct {
 	PrintDebug bool
+	fields     []interface{}
+	buffer     *logBuffer
 }
 
 // Flags
@@ -12,6 +17,8 @@ type flags struct {
 	mandatoryFlags            MandatoryFlags
 	optionalFlags             optionalFlags
 	customMandatoryJiraFields map[string]interface{}
+	// routes of the config file, the first matching a project selects its tracker
+	routes []trackerRoute
 }
 
 type MandatoryFlags struct {
@@ -20,6 +27,15 @@ type MandatoryFlags struct {
 	apiToken       string
 	jiraProjectID  string
 	jiraProjectKey string
+	// OAuth client credentials, used instead of apiToken when set
+	oauthClientID     string
+	oauthClientSecret string
+	oauthTokenURL     string
+	// tracker of the tickets, jira through Snyk or github
+	tracker     string
+	githubRepo  string
+	githubToken string
+	githubAPI   string
 }
 
 type optionalFlags struct {
@@ -42,4 +58,66 @@ type optionalFlags struct {
 	cveInTitle             bool
 	ifUpgradeAvailableOnly bool
 	ifAutoFixableOnly      bool
+	skipInactiveProjects   bool
+	maxProjectAge          string
+	incremental            bool
+	fullSyncEvery          string
+	incrementalSince       time.Time
+	introducedSince        string
+	introducedSinceDate    time.Time
+	concurrency            int
+	issueConcurrency       int
+	requestsPerMinute      int
+	cacheDir               string
+	cacheTTL               string
+	projectCacheTTL        string
+	noCache                bool
+	proxy                  string
+	noProxy                string
+	caBundle               string
+	clientCert             string
+	clientKey              string
+	minTLSVersion          string
+	record                 string
+	replay                 string
+	logFormat              string
+	logLevel               string
+	reportFile             string
+	reportFormat           string
+	jiraURL                string
+	outputDir              string
+	failOn                 string
+	metricsFile            string
+	pushgatewayURL         string
+	notifySlackWebhook     string
+	notifyTeamsWebhook     string
+	notifyWebhook          string
+	notifyTemplate         string
+	notifyMinSeverity      string
+	eventsFile             string
+	eventsURL              string
+	planFile               string
+	stateFile              string
+	resume                 bool
+}
+
+// IntroducedSinceLastRun makes introducedSince use the start of the last successful run
+const IntroducedSinceLastRun = "lastRun"
+
+// LastRunFilePrefix is the prefix of the file keeping the last successful run per org
+const LastRunFilePrefix = "lastSuccessfulRun_"
+
+// LastRunState is the content of the last successful run file
+type LastRunState struct {
+	OrgID             string    `json:"orgID"`
+	LastSuccessfulRun time.Time `json:"lastSuccessfulRun"`
+	LastFullSync      time.Time `json:"lastFullSync,omitempty"`
+}
+
+// projectResult is what a project worker hands back to main
+type projectResult struct {
+	log             *logBuffer
+	summary         string
+	failed          bool
+	projectsTickets map[string]interface{}
 }
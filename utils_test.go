@@ -9,6 +9,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"strings"
+	"time"
 )
 
 type mirroredResponse struct {
@@ -18,6 +19,14 @@ type mirroredResponse struct {
 	Body   []byte `json:"body"`
 }
 
+// keep the retries of the Snyk and GitHub clients fast in the tests
+func init() {
+	defaultSnykClient.baseBackoff = time.Millisecond
+	defaultSnykClient.maxBackoff = 5 * time.Millisecond
+	defaultGitHubClient.baseBackoff = time.Millisecond
+	defaultGitHubClient.maxBackoff = 5 * time.Millisecond
+}
+
 /*
 **
 function removeLogFile
@@ -81,6 +90,16 @@ func removeLogFile() {
 		}
 	}
 
+	path, found = findLogFile(LastRunFilePrefix)
+
+	if found {
+		// Delete the file created for the test
+		e := os.Remove(path)
+		if e != nil {
+			log.Fatal(e)
+		}
+	}
+
 	return
 }
 
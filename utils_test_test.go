@@ -3,6 +3,7 @@ package main
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -44,6 +45,15 @@ func TestSetOptionFunc(t *testing.T) {
 		projectID:              "",
 		severity:               "critical",
 		cveInTitle:             true,
+		concurrency:            1,
+		issueConcurrency:       1,
+		requestsPerMinute:      1500,
+		cacheTTL:               "24h",
+		projectCacheTTL:        "1h",
+		logFormat:              "text",
+		logLevel:               "info",
+		reportFormat:           "json",
+		failOn:                 "any-error",
 	}
 
 	assert.Equal(optionalResult, &options.optionalFlags)
@@ -92,6 +102,15 @@ func TestSetOptionMixFunc(t *testing.T) {
 		projectID:              "",
 		severity:               "critical",
 		cveInTitle:             true,
+		concurrency:            1,
+		issueConcurrency:       1,
+		requestsPerMinute:      1500,
+		cacheTTL:               "24h",
+		projectCacheTTL:        "1h",
+		logFormat:              "text",
+		logLevel:               "info",
+		reportFormat:           "json",
+		failOn:                 "any-error",
 	}
 
 	assert.Equal(optionalResult, &options.optionalFlags)
@@ -137,6 +156,15 @@ func TestSetOption(t *testing.T) {
 		severity:               "critical",
 		ifUpgradeAvailableOnly: true,
 		ifAutoFixableOnly:      true,
+		concurrency:            1,
+		issueConcurrency:       1,
+		requestsPerMinute:      1500,
+		cacheTTL:               "24h",
+		projectCacheTTL:        "1h",
+		logFormat:              "text",
+		logLevel:               "info",
+		reportFormat:           "json",
+		failOn:                 "any-error",
 	}
 
 	customMandatoryJiraFields := map[string]interface{}{"Something": map[string]interface{}{"Value": "This is a summary"}, "transition": map[string]interface{}{"id": 5}}
@@ -184,6 +212,15 @@ func TestSetOptionWithCustomMandatoryField(t *testing.T) {
 		priorityScoreThreshold: 20,
 		projectID:              "",
 		severity:               "critical",
+		concurrency:            1,
+		issueConcurrency:       1,
+		requestsPerMinute:      1500,
+		cacheTTL:               "24h",
+		projectCacheTTL:        "1h",
+		logFormat:              "text",
+		logLevel:               "info",
+		reportFormat:           "json",
+		failOn:                 "any-error",
 	}
 
 	customMandatoryJiraFields := map[string]interface{}{"customfield_10601": "some value to add to the ticket", "customfield_10602": []string{"Value1", "Value2"}, "customfield_10603": []map[string]string{map[string]string{"name": "Value1"}, map[string]string{"name": "Value2"}}}
@@ -192,3 +229,115 @@ func TestSetOptionWithCustomMandatoryField(t *testing.T) {
 	assert.Equal(customMandatoryJiraFields, options.customMandatoryJiraFields)
 
 }
+
+func TestParseDuration(t *testing.T) {
+
+	assert := assert.New(t)
+
+	duration, err := parseDuration("90d")
+	assert.Nil(err)
+	assert.Equal(90*24*time.Hour, duration)
+
+	duration, err = parseDuration("2w")
+	assert.Nil(err)
+	assert.Equal(14*24*time.Hour, duration)
+
+	duration, err = parseDuration("36h")
+	assert.Nil(err)
+	assert.Equal(36*time.Hour, duration)
+
+	_, err = parseDuration("ninety days")
+	assert.NotNil(err)
+
+	_, err = parseDuration("")
+	assert.NotNil(err)
+}
+
+func TestParseIntroducedSince(t *testing.T) {
+
+	assert := assert.New(t)
+
+	now, _ := time.Parse(time.RFC3339, "2024-06-01T12:00:00Z")
+
+	date, err := parseIntroducedSince("2024-01-15", now)
+	assert.Nil(err)
+	assert.Equal("2024-01-15T00:00:00Z", date.Format(time.RFC3339))
+
+	date, err = parseIntroducedSince("2024-01-15T10:30:00Z", now)
+	assert.Nil(err)
+	assert.Equal("2024-01-15T10:30:00Z", date.Format(time.RFC3339))
+
+	date, err = parseIntroducedSince("7d", now)
+	assert.Nil(err)
+	assert.Equal("2024-05-25T12:00:00Z", date.Format(time.RFC3339))
+
+	_, err = parseIntroducedSince("last week", now)
+	assert.NotNil(err)
+}
+
+func TestResolveIntroducedSinceLastRun(t *testing.T) {
+
+	assert := assert.New(t)
+
+	cD := debug{}
+	cD.setDebug(false)
+
+	now, _ := time.Parse(time.RFC3339, "2024-06-01T12:00:00Z")
+	lastRun, _ := time.Parse(time.RFC3339, "2024-05-31T12:00:00Z")
+
+	// no previous run, the current run start is used
+	Of := optionalFlags{introducedSince: IntroducedSinceLastRun}
+	Of.resolveIntroducedSince("lastrun-test-org", now)
+	assert.Equal(now, Of.introducedSinceDate)
+
+	writeLastSuccessfulRun("lastrun-test-org", lastRun, true, cD)
+	defer os.Remove(LastRunFilePrefix + "lastrun-test-org.json")
+
+	Of = optionalFlags{introducedSince: IntroducedSinceLastRun}
+	Of.resolveIntroducedSince("lastrun-test-org", now)
+	assert.True(lastRun.Equal(Of.introducedSinceDate))
+}
+
+func TestResolveIncrementalFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	cD := debug{}
+	cD.setDebug(false)
+
+	now, _ := time.Parse(time.RFC3339, "2024-06-01T12:00:00Z")
+	lastRun, _ := time.Parse(time.RFC3339, "2024-05-31T12:00:00Z")
+	defer os.Remove(LastRunFilePrefix + "incremental-test-org.json")
+
+	// no previous run, every project is processed
+	Of := optionalFlags{incremental: true, fullSyncEvery: "7d"}
+	Of.resolveIncremental("incremental-test-org", now)
+	assert.True(Of.incrementalSince.IsZero())
+
+	// the full sync was 10 days ago, it is due
+	writeLastSuccessfulRun("incremental-test-org", now.Add(-10*24*time.Hour), true, cD)
+	writeLastSuccessfulRun("incremental-test-org", lastRun, false, cD)
+	state, err := readLastRunState("incremental-test-org")
+	assert.Nil(err)
+	assert.True(now.Add(-10 * 24 * time.Hour).Equal(state.LastFullSync))
+	assert.True(lastRun.Equal(state.LastSuccessfulRun))
+
+	Of.resolveIncremental("incremental-test-org", now)
+	assert.True(Of.incrementalSince.IsZero())
+
+	// without full sync period only the projects tested since the last run are processed
+	Of = optionalFlags{incremental: true}
+	Of.resolveIncremental("incremental-test-org", now)
+	assert.True(lastRun.Equal(Of.incrementalSince))
+
+	// the full sync is recent
+	writeLastSuccessfulRun("incremental-test-org", lastRun, true, cD)
+	Of = optionalFlags{incremental: true, fullSyncEvery: "7d"}
+	Of.resolveIncremental("incremental-test-org", now)
+	assert.True(lastRun.Equal(Of.incrementalSince))
+
+	// a single project is always processed
+	Of = optionalFlags{incremental: true, projectID: "123"}
+	Of.resolveIncremental("incremental-test-org", now)
+	assert.True(Of.incrementalSince.IsZero())
+}
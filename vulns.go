@@ -2,8 +2,9 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"time"
 
 	"github.com/michael-go/go-jsn/jsn"
 )
@@ -57,7 +58,11 @@ func getVulnsWithoutTicket(flags flags, projectID string, maturityFilter []strin
 	case "low":
 		body.Filters.Severities = []string{"critical", "high", "medium", "low"}
 	default:
-		log.Fatalln("Unexpected severity threshold")
+		// checked by checkFlags, the projects are processed by workers that can't stop the run
+		message := fmt.Sprintf("*** ERROR *** Unexpected severity threshold %s, skipping project %s", flags.optionalFlags.severity, projectID)
+		writeErrorFile("getVulnsWithoutTicket", message, customDebug)
+		customDebug.Error("Unexpected severity threshold, skipping this project", "severity", flags.optionalFlags.severity, "project", projectID)
+		return nil, "", fmt.Errorf("unexpected severity threshold %s", flags.optionalFlags.severity)
 	}
 	if len(maturityFilter) > 0 {
 		body.Filters.ExploitMaturity = maturityFilter
@@ -74,14 +79,14 @@ func getVulnsWithoutTicket(flags flags, projectID string, maturityFilter []strin
 	if err != nil {
 		message := fmt.Sprintf(" *** ERROR *** IAC projects are not supported by this tool, skipping this project")
 		writeErrorFile("getVulnsWithoutTicket", message, customDebug)
-		customDebug.Debug(" *** ERROR *** IAC projects are not supported by this tool, skipping this project")
+		customDebug.Error("IAC projects are not supported by this tool, skipping this project", "project", projectID)
 	}
 
 	responseAggregatedData, err := makeSnykAPIRequest("POST", flags.mandatoryFlags.endpointAPI+"/v1/org/"+flags.mandatoryFlags.orgID+"/project/"+projectID+"/aggregated-issues", flags.mandatoryFlags.apiToken, marshalledBody, customDebug)
 	if err != nil {
 		message := fmt.Sprintf("*** ERROR *** Could not get aggregated data from %s org %s project %s, skipping this project", flags.mandatoryFlags.endpointAPI, flags.mandatoryFlags.orgID, projectID)
 		writeErrorFile("getVulnsWithoutTicket", message, customDebug)
-		customDebug.Debugf("*** ERROR *** Could not get aggregated data from %s org %s project %s, skipping this project", flags.mandatoryFlags.endpointAPI, flags.mandatoryFlags.orgID, projectID)
+		customDebug.Error("Could not get aggregated data, skipping this project", "endpoint", flags.mandatoryFlags.endpointAPI, "org", flags.mandatoryFlags.orgID, "project", projectID, "error", err)
 		return nil, "", err
 	}
 
@@ -98,7 +103,8 @@ func getVulnsWithoutTicket(flags flags, projectID string, maturityFilter []strin
 	if issueType == "configuration" {
 		message := fmt.Sprintf(" *** WARN *** IAC projects are not supported, skipping project ID %s", projectID)
 		writeErrorFile("getVulnsWithoutTicket", message, customDebug)
-		customDebug.Debug(" *** WARN *** IAC projects are not supported, skipping")
+		customDebug.Warn("IAC projects are not supported, skipping", "project", projectID)
+		recordProject(projectID, projectStatusExcluded, "IAC projects are not supported")
 		return vulnsWithAllPaths, "", err
 	}
 
@@ -136,7 +142,6 @@ Create a list of issue details without tickets.
 */
 func getSnykOpenSourceIssueWithoutTickets(flags flags, projectID string, maturityFilter []string, tickets map[string]string, customDebug debug, responseAggregatedData []byte) (map[string]interface{}, string, error) {
 
-	vulnsPerPath := make(map[string]interface{})
 	vulnsWithAllPaths := make(map[string]interface{})
 
 	j, err := jsn.NewJson(responseAggregatedData)
@@ -148,98 +153,100 @@ func getSnykOpenSourceIssueWithoutTickets(flags flags, projectID string, maturit
 
 	listOfIssues := j.K("issues").Array().Elements()
 
-	issueSkipped := ""
-
-	for _, e := range listOfIssues {
-		if e.K("issueType").String().Value == "vuln" {
-			if len(e.K("id").String().Value) != 0 {
-				if _, found := tickets[e.K("id").String().Value]; !found {
-
-					var issueId = e.K("id").String().Value
-
-					bytes, err := json.Marshal(e)
-					if err != nil {
-						continue
-					}
-					json.Unmarshal(bytes, &vulnsPerPath)
-
-					ProjectIssuePathData, err := makeSnykAPIRequest("GET", flags.mandatoryFlags.endpointAPI+"/v1/org/"+flags.mandatoryFlags.orgID+"/project/"+projectID+"/issue/"+issueId+"/paths", flags.mandatoryFlags.apiToken, nil, customDebug)
-					if err != nil {
-						log.Printf("*** ERROR *** Could not get paths data from %s org %s project %s issue %s", flags.mandatoryFlags.endpointAPI, flags.mandatoryFlags.orgID, projectID, issueId)
-						issueSkipped += "\nissue ID: " + issueId + " from project ID:" + projectID
-						message := fmt.Sprintf("*** ERROR *** Could not get paths data from %s org %s project %s issue %s skipped", flags.mandatoryFlags.endpointAPI, flags.mandatoryFlags.orgID, projectID, issueId)
-						writeErrorFile("getSnykOpenSourceIssueWithoutTickets", message, customDebug)
-						continue
-					}
-					ProjectIssuePathDataJson, er := jsn.NewJson(ProjectIssuePathData)
-					if er != nil {
-						log.Printf("*** ERROR *** Json creation failed\n")
-						issueSkipped += "\nissue ID: " + issueId + " from project ID:" + projectID
-						message := fmt.Sprintf("*** ERROR *** *** ERROR *** Json creation failed \n issue skipped %s org %s project %s issue %s", flags.mandatoryFlags.endpointAPI, flags.mandatoryFlags.orgID, projectID, issueId)
-						writeErrorFile("getSnykOpenSourceIssueWithoutTickets", message, customDebug)
-						continue
-					}
-					vulnsPerPath["from"] = ProjectIssuePathDataJson.K("paths")
-					marshalledvulnsPerPath, err := json.Marshal(vulnsPerPath)
-					vulnsWithAllPaths[issueId], err = jsn.NewJson(marshalledvulnsPerPath)
-					if er != nil {
-						log.Printf("*** ERROR *** vuln per path Json creation failed\n")
-						message := fmt.Sprintf("*** ERROR *** Json creation failed \n issue skipped %s org %s project %s issue %s", flags.mandatoryFlags.endpointAPI, flags.mandatoryFlags.orgID, projectID, issueId)
-						writeErrorFile("getSnykOpenSourceIssueWithoutTickets", message, customDebug)
-						issueSkipped += "\nissue ID: " + issueId + " from project ID:" + projectID
-						continue
-					}
-				}
+	// vulns first then licenses, the paths are then fetched in parallel
+	issuesWithoutTicket := []jsn.Json{}
+	for _, issueType := range []string{"vuln", "license"} {
+		for _, e := range listOfIssues {
+			if e.K("issueType").String().Value != issueType || len(e.K("id").String().Value) == 0 {
+				continue
 			}
+			if jiraKey, found := tickets[e.K("id").String().Value]; found {
+				recordIssue(projectID, e, outcomeAlreadyTicketed, "", jiraKey)
+				continue
+			}
+			if isIntroducedBefore(flags.optionalFlags, e) {
+				customDebug.Debug("Filtering out issue introduced before introducedSince", "issue", e.K("id").String().Value, "introducedSince", flags.optionalFlags.introducedSinceDate.Format(time.RFC3339))
+				recordIssue(projectID, e, outcomeFiltered, "introduced before "+flags.optionalFlags.introducedSinceDate.Format(time.RFC3339), "")
+				continue
+			}
+			issuesWithoutTicket = append(issuesWithoutTicket, e)
 		}
 	}
 
-	for _, e := range listOfIssues {
-		if e.K("issueType").String().Value == "license" {
-			if len(e.K("id").String().Value) != 0 {
-				if _, found := tickets[e.K("id").String().Value]; !found {
-					var issueId = e.K("id").String().Value
-					bytes, err := json.Marshal(e)
-					if err != nil {
-						continue
-					}
-					json.Unmarshal(bytes, &vulnsPerPath)
-
-					ProjectIssuePathData, err := makeSnykAPIRequest("GET", flags.mandatoryFlags.endpointAPI+"/v1/org/"+flags.mandatoryFlags.orgID+"/project/"+projectID+"/issue/"+issueId+"/paths", flags.mandatoryFlags.apiToken, nil, customDebug)
-					if err != nil {
-						log.Printf("*** ERROR *** Could not get aggregated data from %s org %s project %s issue %s", flags.mandatoryFlags.endpointAPI, flags.mandatoryFlags.orgID, projectID, issueId)
-						issueSkipped += "\nissue ID: " + issueId + " from project ID:" + projectID
-						message := fmt.Sprintf("*** ERROR *** Could not get aggregated data from %s org %s project %s issue %s skipped", flags.mandatoryFlags.endpointAPI, flags.mandatoryFlags.orgID, projectID, issueId)
-						writeErrorFile("getSnykOpenSourceIssueWithoutTickets", message, customDebug)
-						continue
-					}
-
-					ProjectIssuePathDataJson, er := jsn.NewJson(ProjectIssuePathData)
-					if er != nil {
-						log.Printf("*** ERROR *** Json creation failed\n")
-						issueSkipped += "\nissue ID: " + issueId + " from project ID:" + projectID
-						message := fmt.Sprintf("*** ERROR *** Json creation failed \n issue skipped %s org %s project %s issue %s", flags.mandatoryFlags.endpointAPI, flags.mandatoryFlags.orgID, projectID, issueId)
-						writeErrorFile("getSnykOpenSourceIssueWithoutTickets", message, customDebug)
-						continue
-					}
-
-					vulnsPerPath["from"] = ProjectIssuePathDataJson.K("paths")
-					marshalledvulnsPerPath, err := json.Marshal(vulnsPerPath)
-					vulnsWithAllPaths[issueId], err = jsn.NewJson(marshalledvulnsPerPath)
-					if er != nil {
-						log.Printf("*** ERROR *** license per path Json creation failed\n")
-						issueSkipped += "\nissue ID: " + issueId + " from project ID:" + projectID
-						message := fmt.Sprintf("*** ERROR *** Json creation failed \n issue skipped %s org %s project %s issue %s", flags.mandatoryFlags.endpointAPI, flags.mandatoryFlags.orgID, projectID, issueId)
-						writeErrorFile("getSnykOpenSourceIssueWithoutTickets", message, customDebug)
-						continue
-					}
-				}
-			}
+	issuesWithPaths := make([]interface{}, len(issuesWithoutTicket))
+	forEachParallel(flags.optionalFlags.issueConcurrency, len(issuesWithoutTicket), func(index int) {
+		issuesWithPaths[index] = getOpenSourceIssueWithPaths(flags, projectID, issuesWithoutTicket[index], customDebug)
+	})
+
+	// merged in the input order so the skipped list is the same on every run
+	issueSkipped := ""
+	for index, e := range issuesWithoutTicket {
+		issueId := e.K("id").String().Value
+		if issuesWithPaths[index] == nil {
+			issueSkipped += "\nissue ID: " + issueId + " from project ID:" + projectID
+			recordIssue(projectID, e, outcomeSkipped, "could not retrieve the paths from Snyk", "")
+			continue
 		}
+		vulnsWithAllPaths[issueId] = issuesWithPaths[index]
 	}
+
 	return vulnsWithAllPaths, issueSkipped, nil
 }
 
+/*
+**
+function getOpenSourceIssueWithPaths
+input flags mandatory and optionnal flags
+input projectID string, the ID of the project the issue belongs to
+input issue jsn.Json, issue from the aggregated issues
+input debug customDebug
+return interface{}, the issue details with the paths in "from", nil if the paths could not be retrieved
+**
+*/
+func getOpenSourceIssueWithPaths(flags flags, projectID string, issue jsn.Json, customDebug debug) interface{} {
+
+	issueId := issue.K("id").String().Value
+	vulnPerPath := make(map[string]interface{})
+
+	bytes, err := json.Marshal(issue)
+	if err != nil {
+		return nil
+	}
+	json.Unmarshal(bytes, &vulnPerPath)
+
+	ProjectIssuePathData, err := makeCachedSnykAPIRequest(flags.mandatoryFlags.endpointAPI+"/v1/org/"+flags.mandatoryFlags.orgID+"/project/"+projectID+"/issue/"+issueId+"/paths", flags.mandatoryFlags.apiToken, projectID, customDebug)
+	if err != nil {
+		customDebug.Error("Could not get paths data, issue skipped", "endpoint", flags.mandatoryFlags.endpointAPI, "org", flags.mandatoryFlags.orgID, "project", projectID, "issue", issueId, "error", err)
+		message := fmt.Sprintf("*** ERROR *** Could not get paths data from %s org %s project %s issue %s skipped", flags.mandatoryFlags.endpointAPI, flags.mandatoryFlags.orgID, projectID, issueId)
+		writeErrorFile("getSnykOpenSourceIssueWithoutTickets", message, customDebug)
+		return nil
+	}
+
+	ProjectIssuePathDataJson, err := jsn.NewJson(ProjectIssuePathData)
+	if err != nil {
+		customDebug.Error("Json creation failed, issue skipped", "project", projectID, "issue", issueId, "error", err)
+		message := fmt.Sprintf("*** ERROR *** Json creation failed \n issue skipped %s org %s project %s issue %s", flags.mandatoryFlags.endpointAPI, flags.mandatoryFlags.orgID, projectID, issueId)
+		writeErrorFile("getSnykOpenSourceIssueWithoutTickets", message, customDebug)
+		return nil
+	}
+
+	vulnPerPath["from"] = ProjectIssuePathDataJson.K("paths")
+	marshalledVulnPerPath, err := json.Marshal(vulnPerPath)
+	if err != nil {
+		return nil
+	}
+
+	issueWithPaths, err := jsn.NewJson(marshalledVulnPerPath)
+	if err != nil {
+		customDebug.Error("Issue per path Json creation failed, issue skipped", "project", projectID, "issue", issueId, "error", err)
+		message := fmt.Sprintf("*** ERROR *** Json creation failed \n issue skipped %s org %s project %s issue %s", flags.mandatoryFlags.endpointAPI, flags.mandatoryFlags.orgID, projectID, issueId)
+		writeErrorFile("getSnykOpenSourceIssueWithoutTickets", message, customDebug)
+		return nil
+	}
+
+	return issueWithPaths
+}
+
 func createMaturityFilter(filtersArray []string) []string {
 
 	var MaturityFilter []string
@@ -256,7 +263,7 @@ func createMaturityFilter(filtersArray []string) []string {
 			MaturityFilter = append(MaturityFilter, filter)
 		case "":
 		default:
-			log.Fatalf("*** ERROR ***: %s is not a valid maturity level. Levels are Must be one of [no-data,no-known-exploit,proof-of-concept,mature]", filter)
+			logger.FatalWithCode(exitConfigError, "Not a valid maturity level. Must be one of [no-data,no-known-exploit,proof-of-concept,mature]", "maturityFilter", filter)
 		}
 	}
 	return MaturityFilter
@@ -300,7 +307,8 @@ func getSnykCodeIssueWithoutTickets(flags flags, projectID string, tickets map[s
 	default:
 		message := fmt.Sprintf("*** ERROR *** Unexpected severity threshold ")
 		writeErrorFile("getSnykCodeIssueWithoutTickets", message, customDebug)
-		log.Fatalln("Unexpected severity threshold")
+		customDebug.Error("Unexpected severity threshold", "severity", flags.optionalFlags.severity, "project", projectID)
+		return nil, fmt.Errorf("unexpected severity threshold %s", flags.optionalFlags.severity)
 	}
 
 	fullCodeIssueDetail := make(map[string]interface{})
@@ -324,8 +332,8 @@ func getSnykCodeIssueWithoutTickets(flags flags, projectID string, tickets map[s
 			responseData, err := makeSnykAPIRequest("GET", url, flags.mandatoryFlags.apiToken, nil, customDebug)
 
 			if err != nil {
-				if (err.Error() != "Not found, Request failed") && (err.Error() != "Request failed with 50x") {
-					log.Printf("*** ERROR ***** Could not get code issues list from %s org %s project %s", flags.mandatoryFlags.endpointAPI, flags.mandatoryFlags.orgID, projectID)
+				if !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrServer) {
+					customDebug.Error("Could not get code issues list", "endpoint", flags.mandatoryFlags.endpointAPI, "org", flags.mandatoryFlags.orgID, "project", projectID, "error", err)
 					errorMessage = err
 					message := fmt.Sprintf("*** ERROR ***** Could not get code issues list from %s org %s project %s", flags.mandatoryFlags.endpointAPI, flags.mandatoryFlags.orgID, projectID)
 					writeErrorFile("getSnykCodeIssueWithoutTickets", message, customDebug)
@@ -336,64 +344,41 @@ func getSnykCodeIssueWithoutTickets(flags flags, projectID string, tickets map[s
 			// loop through the issues and get the details
 			jsonData, err := jsn.NewJson(responseData)
 
-			issueDetail := make(map[string]interface{})
-
+			// the details are fetched in parallel, the list keeps the order of the page
+			issuesWithoutTicket := []jsn.Json{}
 			for _, e := range jsonData.K("data").Array().Elements() {
 
-				if len(e.K("id").String().Value) != 0 {
-					if _, found := tickets[e.K("id").String().Value]; !found {
-
-						// checking if the issue is ignored
-						if e.K("attributes").K("ignored").Bool().Value == true {
-							continue
-						}
-
-						id := e.K("id").String().Value
-
-						url := endpointAPI + "/rest/orgs/" + flags.mandatoryFlags.orgID + "/issues/detail/code/" + id + "?project_id=" + projectID + "&version=2022-04-06~experimental"
-
-						// get the details of this code issue id
-						responseIssueDetail, err := makeSnykAPIRequest("GET", url, flags.mandatoryFlags.apiToken, nil, customDebug)
-						if err != nil {
-							log.Printf("*** ERROR *** Could not get code issues list from %s org %s project %s", flags.mandatoryFlags.endpointAPI, flags.mandatoryFlags.orgID, projectID)
-							message := fmt.Sprintf("*** ERROR *** Could not get code issues list from %s org %s project %s", flags.mandatoryFlags.endpointAPI, flags.mandatoryFlags.orgID, projectID)
-							writeErrorFile("getSnykCodeIssueWithoutTickets", message, customDebug)
-							continue
-						}
-
-						jsonIssueDetail, er := jsn.NewJson(responseIssueDetail)
-						if er != nil {
-							log.Printf("*** ERROR *** Json creation failed\n")
-							message := fmt.Sprintf("*** ERROR *** Json creation failed\n")
-							writeErrorFile("getSnykCodeIssueWithoutTickets", message, customDebug)
-							continue
-						}
-
-						bytes, err := json.Marshal(jsonIssueDetail)
-						if err != nil {
-							continue
-						}
-						json.Unmarshal(bytes, &issueDetail)
-
-						if flags.optionalFlags.priorityScoreThreshold > 0 {
-							if flags.optionalFlags.priorityScoreThreshold > jsonIssueDetail.K("data").K("attributes").K("priorityScore").Int().Value {
-								customDebug.Debug(fmt.Sprintf("*** INFO *** Filtering out issue based on priority score priorityScoreThreshold=%d, issue priorityScore=%d", flags.optionalFlags.priorityScoreThreshold, jsonIssueDetail.K("data").K("attributes").K("priorityScore").Int().Value))
-								continue
-							}
-						}
-
-						issueDetail["title"] = e.K("attributes").K("title").String().Value
-
-						marshalledjsonIssueDetail, err := json.Marshal(issueDetail)
-						fullCodeIssueDetail[id], err = jsn.NewJson(marshalledjsonIssueDetail)
-						if er != nil {
-							log.Printf("*** ERROR *** Json creation failed\n")
-							message := fmt.Sprintf("*** ERROR *** Json creation failed\n")
-							writeErrorFile("getSnykCodeIssueWithoutTickets", message, customDebug)
-							log.Fatalln(er)
-						}
-					}
+				if len(e.K("id").String().Value) == 0 {
+					continue
+				}
+				if jiraKey, found := tickets[e.K("id").String().Value]; found {
+					recordIssue(projectID, e, outcomeAlreadyTicketed, "", jiraKey)
+					continue
+				}
+
+				// checking if the issue is ignored
+				if e.K("attributes").K("ignored").Bool().Value == true {
+					recordIssue(projectID, e, outcomeFiltered, "ignored in Snyk", "")
+					continue
+				}
+
+				if isIntroducedBefore(flags.optionalFlags, e) {
+					customDebug.Debug("Filtering out issue introduced before introducedSince", "issue", e.K("id").String().Value, "introducedSince", flags.optionalFlags.introducedSinceDate.Format(time.RFC3339))
+					recordIssue(projectID, e, outcomeFiltered, "introduced before "+flags.optionalFlags.introducedSinceDate.Format(time.RFC3339), "")
+					continue
+				}
+
+				issuesWithoutTicket = append(issuesWithoutTicket, e)
+			}
+
+			issueDetails := make([]interface{}, len(issuesWithoutTicket))
+			forEachParallel(flags.optionalFlags.issueConcurrency, len(issuesWithoutTicket), func(index int) {
+				issueDetails[index] = getCodeIssueDetail(flags, endpointAPI, projectID, issuesWithoutTicket[index], customDebug)
+			})
 
+			for index, e := range issuesWithoutTicket {
+				if issueDetails[index] != nil {
+					fullCodeIssueDetail[e.K("id").String().Value] = issueDetails[index]
 				}
 			}
 
@@ -407,3 +392,112 @@ func getSnykCodeIssueWithoutTickets(flags flags, projectID string, tickets map[s
 
 	return fullCodeIssueDetail, errorMessage
 }
+
+/*
+**
+function getCodeIssueDetail
+input flags mandatory and optionnal flags
+input endpointAPI string, projectID string
+input issue jsn.Json, the code issue from the issues list
+input debug customDebug
+return interface{}, the details of the issue with its title, nil if the issue is skipped
+**
+*/
+func getCodeIssueDetail(flags flags, endpointAPI string, projectID string, issue jsn.Json, customDebug debug) interface{} {
+
+	id := issue.K("id").String().Value
+	issueDetail := make(map[string]interface{})
+
+	url := endpointAPI + "/rest/orgs/" + flags.mandatoryFlags.orgID + "/issues/detail/code/" + id + "?project_id=" + projectID + "&version=2022-04-06~experimental"
+
+	// get the details of this code issue id
+	responseIssueDetail, err := makeCachedSnykAPIRequest(url, flags.mandatoryFlags.apiToken, projectID, customDebug)
+	if err != nil {
+		customDebug.Error("Could not get code issue detail, issue skipped", "endpoint", flags.mandatoryFlags.endpointAPI, "org", flags.mandatoryFlags.orgID, "project", projectID, "issue", id, "error", err)
+		message := fmt.Sprintf("*** ERROR *** Could not get code issues list from %s org %s project %s", flags.mandatoryFlags.endpointAPI, flags.mandatoryFlags.orgID, projectID)
+		writeErrorFile("getSnykCodeIssueWithoutTickets", message, customDebug)
+		recordIssue(projectID, issue, outcomeSkipped, "could not retrieve the details from Snyk", "")
+		return nil
+	}
+
+	jsonIssueDetail, er := jsn.NewJson(responseIssueDetail)
+	if er != nil {
+		customDebug.Error("Json creation failed, issue skipped", "project", projectID, "issue", id, "error", er)
+		message := fmt.Sprintf("*** ERROR *** Json creation failed\n")
+		writeErrorFile("getSnykCodeIssueWithoutTickets", message, customDebug)
+		recordIssue(projectID, issue, outcomeSkipped, "could not read the details from Snyk", "")
+		return nil
+	}
+
+	// the listing does not always carry the creation date, check the details too
+	if isIntroducedBefore(flags.optionalFlags, jsonIssueDetail.K("data")) {
+		customDebug.Debug("Filtering out issue introduced before introducedSince", "issue", id, "introducedSince", flags.optionalFlags.introducedSinceDate.Format(time.RFC3339))
+		recordIssue(projectID, issue, outcomeFiltered, "introduced before "+flags.optionalFlags.introducedSinceDate.Format(time.RFC3339), "")
+		return nil
+	}
+
+	bytes, err := json.Marshal(jsonIssueDetail)
+	if err != nil {
+		return nil
+	}
+	json.Unmarshal(bytes, &issueDetail)
+
+	if flags.optionalFlags.priorityScoreThreshold > 0 {
+		if flags.optionalFlags.priorityScoreThreshold > jsonIssueDetail.K("data").K("attributes").K("priorityScore").Int().Value {
+			customDebug.Debug("Filtering out issue based on priority score", "issue", id, "priorityScoreThreshold", flags.optionalFlags.priorityScoreThreshold, "priorityScore", jsonIssueDetail.K("data").K("attributes").K("priorityScore").Int().Value)
+			recordIssue(projectID, issue, outcomeFiltered, fmt.Sprintf("priority score %d below %d", jsonIssueDetail.K("data").K("attributes").K("priorityScore").Int().Value, flags.optionalFlags.priorityScoreThreshold), "")
+			return nil
+		}
+	}
+
+	issueDetail["title"] = issue.K("attributes").K("title").String().Value
+
+	marshalledjsonIssueDetail, err := json.Marshal(issueDetail)
+	if err != nil {
+		return nil
+	}
+
+	fullIssueDetail, er := jsn.NewJson(marshalledjsonIssueDetail)
+	if er != nil {
+		customDebug.Error("Json creation failed, issue skipped", "project", projectID, "issue", id, "error", er)
+		message := fmt.Sprintf("*** ERROR *** Json creation failed\n")
+		writeErrorFile("getSnykCodeIssueWithoutTickets", message, customDebug)
+		return nil
+	}
+
+	return fullIssueDetail
+}
+
+/*
+**
+function isIntroducedBefore
+input Of optionalFlags, introducedSinceDate is the threshold
+input issue jsn.Json, open source issue from aggregated-issues or code issue from the REST API
+return bool, true if the issue was introduced before the threshold and should be filtered out
+Issues without an introduction date are kept
+**
+*/
+func isIntroducedBefore(Of optionalFlags, issue jsn.Json) bool {
+
+	if Of.introducedSinceDate.IsZero() {
+		return false
+	}
+
+	introduced := issue.K("introducedDate").String().Value
+	if len(introduced) == 0 {
+		introduced = issue.K("attributes").K("created_at").String().Value
+	}
+	if len(introduced) == 0 {
+		introduced = issue.K("attributes").K("createdAt").String().Value
+	}
+	if len(introduced) == 0 {
+		return false
+	}
+
+	introducedDate, err := time.Parse(time.RFC3339, introduced)
+	if err != nil {
+		return false
+	}
+
+	return introducedDate.Before(Of.introducedSinceDate)
+}
@@ -2,7 +2,9 @@ package main
 
 import (
 	"testing"
+	"time"
 
+	"github.com/michael-go/go-jsn/jsn"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -226,7 +228,33 @@ func TestGetLicenseWithoutTicketFunc(t *testing.T) {
 	assert.Equal(0, len(skippedIssues))
 	assert.Equal(1, len(response))
 
+	// an unknown severity skips the project instead of stopping the run
+	flags.optionalFlags.severity = "hihg"
+	_, _, err := getVulnsWithoutTicket(flags, "456", maturityLevels, nil, cD)
+	assert.NotNil(err)
+	_, err = getSnykCodeIssueWithoutTickets(flags, "456", nil, cD)
+	assert.NotNil(err)
+
 	removeLogFile()
 
 	return
 }
+
+func TestIsIntroducedBefore(t *testing.T) {
+
+	assert := assert.New(t)
+
+	oldIssue, _ := jsn.NewJson([]byte(`{"id": "SNYK-JS-OLD-1", "introducedDate": "2020-01-01T00:00:00.000Z"}`))
+	newIssue, _ := jsn.NewJson([]byte(`{"id": "SNYK-JS-NEW-1", "introducedDate": "2024-03-01T00:00:00.000Z"}`))
+	oldCodeIssue, _ := jsn.NewJson([]byte(`{"id": "abc", "attributes": {"created_at": "2020-01-01T00:00:00Z"}}`))
+	noDateIssue, _ := jsn.NewJson([]byte(`{"id": "SNYK-JS-NODATE-1"}`))
+
+	Of := optionalFlags{}
+	assert.False(isIntroducedBefore(Of, oldIssue))
+
+	Of.introducedSinceDate, _ = time.Parse("2006-01-02", "2024-01-01")
+	assert.True(isIntroducedBefore(Of, oldIssue))
+	assert.False(isIntroducedBefore(Of, newIssue))
+	assert.True(isIntroducedBefore(Of, oldCodeIssue))
+	assert.False(isIntroducedBefore(Of, noDateIssue))
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+/*
+**
+function forEachParallel
+input concurrency int, maximum number of items processed at the same time
+input count int, number of items to process
+input work func(index int), called once for every index between 0 and count
+Run work on a bounded pool of goroutines and return when all the items are done.
+The callers store the results by index so the order of the input is kept
+whatever the completion order is.
+**
+*/
+func forEachParallel(concurrency int, count int, work func(index int)) {
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if concurrency > count {
+		concurrency = count
+	}
+
+	// no need for goroutines when running sequentially
+	if concurrency <= 1 {
+		for index := 0; index < count; index++ {
+			work(index)
+		}
+		return
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+
+	for worker := 0; worker < concurrency; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				work(index)
+			}
+		}()
+	}
+
+	for index := 0; index < count; index++ {
+		indexes <- index
+	}
+	close(indexes)
+
+	wg.Wait()
+}
+
+// rateLimiter is a token bucket shared by all the requests sent to Snyk
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	burst    float64
+	tokens   float64
+	last     time.Time
+}
+
+/*
+**
+function newRateLimiter
+input requestsPerMinute int, 0 or less disables the limiter
+return *rateLimiter, nil when disabled
+Up to one second worth of requests can be sent in a burst
+**
+*/
+func newRateLimiter(requestsPerMinute int) *rateLimiter {
+
+	if requestsPerMinute <= 0 {
+		return nil
+	}
+
+	burst := float64(requestsPerMinute / 60)
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &rateLimiter{
+		interval: time.Minute / time.Duration(requestsPerMinute),
+		burst:    burst,
+		tokens:   burst,
+		last:     time.Now(),
+	}
+}
+
+/*
+**
+function Wait
+Block until the request can be sent.
+The token is reserved before sleeping so concurrent callers queue up
+instead of all waking up at the same time.
+**
+*/
+func (r *rateLimiter) Wait() {
+
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	now := time.Now()
+	r.tokens += float64(now.Sub(r.last)) / float64(r.interval)
+	if r.tokens > r.burst {
+		r.tokens = r.burst
+	}
+	r.last = now
+	r.tokens--
+	delay := time.Duration(-r.tokens * float64(r.interval))
+	r.mu.Unlock()
+
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForEachParallelFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	var mu sync.Mutex
+	running := 0
+	maxRunning := 0
+
+	results := make([]int, 20)
+	forEachParallel(4, len(results), func(index int) {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+
+		// finish in the reverse order to check the results keep the input order
+		time.Sleep(time.Duration(len(results)-index) * time.Millisecond)
+		results[index] = index * 2
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+	})
+
+	assert.LessOrEqual(maxRunning, 4)
+	assert.Greater(maxRunning, 1)
+	for index, value := range results {
+		assert.Equal(index*2, value)
+	}
+
+	// nothing to do should not block
+	forEachParallel(4, 0, func(index int) {
+		t.Fail()
+	})
+}
+
+func TestRateLimiterFunc(t *testing.T) {
+
+	assert := assert.New(t)
+
+	assert.Nil(newRateLimiter(0))
+	// a nil limiter does not block
+	newRateLimiter(0).Wait()
+
+	// 600 per minute is one request every 100ms with a burst of 10
+	limiter := newRateLimiter(600)
+	assert.Equal(100*time.Millisecond, limiter.interval)
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 12; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			limiter.Wait()
+		}()
+	}
+	wg.Wait()
+
+	// the burst goes straight through, the 2 extra requests wait for new tokens
+	elapsed := time.Since(start)
+	assert.GreaterOrEqual(elapsed, 150*time.Millisecond)
+	assert.Less(elapsed, time.Second)
+}